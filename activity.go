@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+const (
+	// bucketActivity holds the activity tracked from message events per channel ID
+	bucketActivity = "activity"
+	// bucketMeta holds state about auto-archiver itself
+	bucketMeta = "meta"
+
+	// keyActivityTrackingSince is when the daemon last started tracking activity
+	keyActivityTrackingSince = "activity_tracking_since"
+)
+
+// trackedActivity is the activity of a channel seen in message events
+type trackedActivity struct {
+	// TrackedSince is when messages in the channel started being tracked
+	TrackedSince time.Time `json:"tracked_since"`
+	LastMessage  time.Time `json:"last_message,omitempty"`
+	LastActivity time.Time `json:"last_activity,omitempty"`
+}
+
+// activityTTL is how long the tracked activity of a channel is kept after its last write: the inactivity threshold,
+// after which a channel without new messages is archived, and a grace period
+func activityTTL(threshold int) time.Duration {
+	return time.Duration(threshold)*24*time.Hour + stateGracePeriod
+}
+
+// getActivityTrackingSince will return when the daemon last started tracking activity, or the zero time if it never has
+func getActivityTrackingSince(ctx context.Context, store Store) (time.Time, error) {
+	var since time.Time
+	if err := getJSON(ctx, store, bucketMeta, keyActivityTrackingSince, &since); err != nil && !errors.Is(err, errNotFound) {
+		return time.Time{}, err
+	}
+
+	return since, nil
+}
+
+// startActivityTracking will record that activity is tracked from now on. Messages posted while the daemon was not
+// running were missed, so tracked activity is only trusted once the daemon has been running for a whole threshold.
+func (d *daemon) startActivityTracking(ctx context.Context) error {
+	return putJSON(ctx, d.store, bucketMeta, keyActivityTrackingSince, time.Now(), 0)
+}
+
+// trackMessage will record a message event as the latest message, and latest activity if it counts as activity,
+// of its channel
+func (d *daemon) trackMessage(ctx context.Context, ev *slackevents.MessageEvent) {
+	logger := d.logger.V(1).WithValues("channel", ev.Channel)
+
+	ts, err := parseSlackTimestamp(ev.TimeStamp)
+	if err != nil {
+		logger.Info("can not track message", "error", err.Error())
+		return
+	}
+
+	var t trackedActivity
+	if err := getJSON(ctx, d.store, bucketActivity, ev.Channel, &t); err != nil && !errors.Is(err, errNotFound) {
+		logger.Error(err, "failed to get tracked activity")
+		return
+	}
+	if t.TrackedSince.IsZero() {
+		t.TrackedSince = time.Now()
+	}
+
+	m := slack.Message{Msg: slack.Msg{User: ev.User, BotID: ev.BotID, SubType: ev.SubType}}
+	if ts.After(t.LastMessage) {
+		t.LastMessage = ts
+	}
+	if isActivity(m, d.activityAuthors) && ts.After(t.LastActivity) {
+		t.LastActivity = ts
+	}
+
+	if err := putJSON(ctx, d.store, bucketActivity, ev.Channel, t, activityTTL(d.cfg.archiveThreshold)); err != nil {
+		logger.Error(err, "failed to track activity")
+	}
+}
+
+// getTrackedActivity will return the last activity of a channel and whether any messages were posted within the
+// threshold from the activity tracked by the daemon. It reports false when tracking does not cover the whole
+// threshold, for example because the daemon restarted or the channel was joined recently, and history has to be
+// read instead.
+func (a *ArchiveSlacker) getTrackedActivity(ctx context.Context, c slack.Channel, now time.Time, threshold int) (time.Time, bool, bool, error) {
+	// Reactions are not tracked, and bounded windows look at history rather than the channel's current state
+	if a.activityTrackingSince.IsZero() || a.reactionWeight > 0 || !a.since.IsZero() || !a.until.IsZero() {
+		return time.Time{}, false, false, nil
+	}
+
+	var t trackedActivity
+	if err := getJSON(ctx, a.store, bucketActivity, c.ID, &t); err != nil {
+		if !errors.Is(err, errNotFound) {
+			return time.Time{}, false, false, err
+		}
+
+		// Channels without tracked activity are tracked from now on, there may just not have been any messages
+		t.TrackedSince = now
+		if err := putJSON(ctx, a.store, bucketActivity, c.ID, t, activityTTL(threshold)); err != nil {
+			return time.Time{}, false, false, err
+		}
+	}
+
+	windowStart := a.windowStart(now, threshold)
+	if windowStart.Before(a.activityTrackingSince) || windowStart.Before(t.TrackedSince) {
+		return time.Time{}, false, false, nil
+	}
+
+	lastActivity := time.Time{}
+	if t.LastActivity.After(windowStart) {
+		lastActivity = t.LastActivity
+	}
+
+	return lastActivity, t.LastMessage.After(windowStart), true, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Which token a channel is archived with, logged with every archive
+const (
+	archivePathBot   = "bot"
+	archivePathAdmin = "admin"
+	archivePathUser  = "user"
+)
+
+// joinRestricted will report whether the bot could not join a public channel because the channel does not let it,
+// such as channels only admins can add members to or post in, rather than because joining failed
+func joinRestricted(err error) bool {
+	var slackErr slack.SlackErrorResponse
+	if !errors.As(err, &slackErr) {
+		return false
+	}
+
+	switch slackErr.Err {
+	case "restricted_action", "restricted_action_read_only_channel", "restricted_action_thread_only_channel",
+		"restricted_action_non_threadable_channel", "method_not_supported_for_channel_type":
+		return true
+	}
+	return false
+}
+
+// adminConversation is a channel as admin.conversations.search returns it
+type adminConversation struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// LastActivityTS is when anything was last posted in the channel, in milliseconds since the epoch
+	LastActivityTS int64 `json:"last_activity_ts"`
+}
+
+// getAdminLastActivity will return when anything was last posted in a public channel, or the zero time if nothing
+// ever was, searching for it by name. It needs an org admin's user token with the admin.conversations:read scope.
+func (r *rawSlackClient) getAdminLastActivity(ctx context.Context, c slack.Channel) (time.Time, error) {
+	values := url.Values{"query": {c.Name}, "search_channel_types": {"public"}, "limit": {"20"}}
+	for {
+		var page struct {
+			Conversations []adminConversation `json:"conversations"`
+			NextCursor    string              `json:"next_cursor"`
+		}
+		if err := r.call(ctx, "admin.conversations.search", values, &page); err != nil {
+			return time.Time{}, err
+		}
+
+		for _, conversation := range page.Conversations {
+			if conversation.ID != c.ID {
+				continue
+			}
+			if conversation.LastActivityTS == 0 {
+				return time.Time{}, nil
+			}
+			return time.UnixMilli(conversation.LastActivityTS), nil
+		}
+
+		if page.NextCursor == "" {
+			return time.Time{}, fmt.Errorf("admin.conversations.search did not find channel %s", c.ID)
+		}
+		values.Set("cursor", page.NextCursor)
+	}
+}
+
+// adminArchive will archive a channel without the bot being a member of it. It needs an org admin's user token
+// with the admin.conversations:write scope.
+func (r *rawSlackClient) adminArchive(ctx context.Context, channelID string) error {
+	return r.call(ctx, "admin.conversations.archive", url.Values{"channel_id": {channelID}}, nil)
+}
+
+// archivePath will return which token a channel is archived with
+func (a *ArchiveSlacker) archivePath(channelID string) string {
+	if a.adminChannels[channelID] {
+		return archivePathAdmin
+	}
+	if a.archiveUser != nil {
+		return archivePathUser
+	}
+
+	return archivePathBot
+}
+
+// getAdminLastActivity will return the last activity of a channel the bot could not join from the admin API, or the
+// zero time if it is before the window, and whether anything was posted within the window. The admin API only
+// knows when anything was last posted, so messages of bots and joins count as activity.
+func (a *ArchiveSlacker) getAdminLastActivity(ctx context.Context, c slack.Channel, windowStart time.Time) (time.Time, bool, error) {
+	lastActivity, err := a.admin.getAdminLastActivity(ctx, c)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("could not get last activity from the admin API: %w", err)
+	}
+	if !lastActivity.After(windowStart) {
+		return time.Time{}, false, nil
+	}
+
+	return lastActivity, true, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// bucketApprovals holds the requests to approve archiving channels, by channel ID
+const bucketApprovals = "approvals"
+
+// approveArchiveActionID is the action ID of the button in approval requests that approves archiving the channel
+const approveArchiveActionID = "approve_archive"
+
+// archiveApproval is a request to approve archiving a channel and who approved it so far
+type archiveApproval struct {
+	ChannelID   string `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	// Required is how many distinct users have to approve archiving the channel
+	Required    int       `json:"required"`
+	RequestedAt time.Time `json:"requested_at"`
+	// Approvers are when each user approved archiving the channel, by user ID
+	Approvers map[string]time.Time `json:"approvers"`
+}
+
+// approvalRequestData is the data available to the approval request template
+type approvalRequestData struct {
+	channelMessageData
+	// Required is how many distinct users have to approve archiving the channel
+	Required int
+}
+
+// approved will report whether enough distinct users approved archiving the channel
+func (a *archiveApproval) approved() bool {
+	return len(a.Approvers) >= a.Required
+}
+
+// approverIDs will return the IDs of the users who approved archiving the channel, in the order they approved
+func (a *archiveApproval) approverIDs() []string {
+	ids := make([]string, 0, len(a.Approvers))
+	for id := range a.Approvers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return a.Approvers[ids[i]].Before(a.Approvers[ids[j]]) })
+
+	return ids
+}
+
+// listApprovals will return every stored approval request by channel ID
+func listApprovals(ctx context.Context, store Store) (map[string]*archiveApproval, error) {
+	values, err := store.List(ctx, bucketApprovals)
+	if err != nil {
+		return nil, err
+	}
+
+	approvals := map[string]*archiveApproval{}
+	for key, value := range values {
+		a := &archiveApproval{}
+		if err := json.Unmarshal(value, a); err != nil {
+			return nil, fmt.Errorf("can not decode %s/%s: %w", bucketApprovals, key, err)
+		}
+		approvals[key] = a
+	}
+
+	return approvals, nil
+}
+
+// requiredApprovals will return how many distinct users have to approve archiving a channel. Channels with at
+// least the two-person member count, or older than the two-person age, need two.
+func (a *ArchiveSlacker) requiredApprovals(c slack.Channel, now time.Time) int {
+	if a.twoPersonApprovalMembers > 0 && c.NumMembers >= a.twoPersonApprovalMembers {
+		return 2
+	}
+	if a.twoPersonApprovalAgeDays > 0 && !now.Before(c.Created.Time().AddDate(0, 0, a.twoPersonApprovalAgeDays)) {
+		return 2
+	}
+
+	return 1
+}
+
+// requestApproval will ask the admin channel to approve archiving a channel and record the request
+func (a *ArchiveSlacker) requestApproval(ctx context.Context, c inactiveChannel, now time.Time) (*archiveApproval, error) {
+	approval := &archiveApproval{
+		ChannelID:   c.channel.ID,
+		ChannelName: c.channel.Name,
+		Required:    a.requiredApprovals(c.channel, now),
+		RequestedAt: now,
+		Approvers:   map[string]time.Time{},
+	}
+
+	text, err := render(a.templates.approvalRequest, approvalRequestData{channelMessageData: a.messageData(c), Required: approval.Required})
+	if err != nil {
+		return nil, err
+	}
+	button := slack.NewButtonBlockElement(approveArchiveActionID, c.channel.ID,
+		slack.NewTextBlockObject(slack.PlainTextType, "Approve archiving", false, false))
+	_, _, err = a.client.PostMessageContext(ctx, a.adminChannel, slack.MsgOptionText(text, false), slack.MsgOptionBlocks(
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+		slack.NewActionBlock("", button),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("can not post approval request: %w", err)
+	}
+
+	if err := putJSON(ctx, a.store, bucketApprovals, c.channel.ID, approval, 0); err != nil {
+		return nil, fmt.Errorf("can not record approval request: %w", err)
+	}
+
+	return approval, nil
+}
+
+// approveArchive will record the approval of the authorized user who clicked an approval request's button, if
+// they did not approve it already. The request is updated with who approved it so far.
+func (d *daemon) approveArchive(ctx context.Context, callback slack.InteractionCallback, action *slack.BlockAction) {
+	channelID := action.Value
+	logger := d.logger.WithValues("channel", channelID, "user", callback.User.ID)
+
+	tell := func(text string) {
+		if _, err := d.api.PostEphemeralContext(ctx, callback.Channel.ID, callback.User.ID, slack.MsgOptionText(text, false)); err != nil {
+			logger.Error(err, "failed to answer approval")
+		}
+	}
+
+	var approval archiveApproval
+	if err := getJSON(ctx, d.store, bucketApprovals, channelID, &approval); err != nil {
+		if errors.Is(err, errNotFound) {
+			tell("This channel no longer needs approving.")
+			return
+		}
+		logger.Error(err, "failed to get approval request")
+		tell("Something went wrong, please try again.")
+		return
+	}
+	if _, ok := approval.Approvers[callback.User.ID]; ok {
+		tell(fmt.Sprintf("You already approved archiving #%s, it needs another approver.", approval.ChannelName))
+		return
+	}
+
+	if approval.Approvers == nil {
+		approval.Approvers = map[string]time.Time{}
+	}
+	approval.Approvers[callback.User.ID] = time.Now()
+	if err := putJSON(ctx, d.store, bucketApprovals, channelID, approval, 0); err != nil {
+		logger.Error(err, "failed to record approval")
+		tell("Something went wrong, please try again.")
+		return
+	}
+	logger.Info("approved archiving channel", "approvals", len(approval.Approvers), "required", approval.Required)
+
+	mentions := []string{}
+	for _, id := range approval.approverIDs() {
+		mentions = append(mentions, fmt.Sprintf("<@%s>", id))
+	}
+	status := fmt.Sprintf("Approved by %s (%d of %d).", strings.Join(mentions, ", "), len(approval.Approvers), approval.Required)
+	blocks := []slack.Block{}
+	for _, block := range callback.Message.Blocks.BlockSet {
+		if section, ok := block.(*slack.SectionBlock); ok {
+			blocks = append(blocks, section)
+		}
+	}
+	blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, status, false, false)))
+	// The button stays until enough users approved
+	if !approval.approved() {
+		blocks = append(blocks, slack.NewActionBlock("", slack.NewButtonBlockElement(approveArchiveActionID, channelID,
+			slack.NewTextBlockObject(slack.PlainTextType, "Approve archiving", false, false))))
+	}
+
+	_, _, _, err := d.api.UpdateMessageContext(ctx, callback.Channel.ID, callback.Message.Timestamp,
+		slack.MsgOptionText(callback.Message.Text, false), slack.MsgOptionBlocks(blocks...))
+	if err != nil {
+		logger.Error(err, "failed to update approval request")
+	}
+}
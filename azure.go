@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// azureStorageVersion is the Blob service API version, which must be at least 2017-11-09 for bearer tokens
+	azureStorageVersion = "2021-08-06"
+	azureIMDSTokenURL   = "http://169.254.169.254/metadata/identity/oauth2/token"
+	azureStorageScope   = "https://storage.azure.com/"
+)
+
+// azureTarget writes channel backups to an Azure Blob Storage container, authenticating with
+// a SAS token when one is configured and with the managed identity of the host otherwise
+type azureTarget struct {
+	client       *http.Client
+	containerURL string
+	prefix       string
+	sasToken     string
+	identity     *azureManagedIdentity
+}
+
+func newAzureTarget(containerURL, prefix, sasToken, clientID string) *azureTarget {
+	t := &azureTarget{
+		client:       &http.Client{Timeout: 5 * time.Minute},
+		containerURL: strings.TrimSuffix(containerURL, "/"),
+		prefix:       strings.Trim(prefix, "/"),
+		sasToken:     strings.TrimPrefix(sasToken, "?"),
+	}
+
+	if t.sasToken == "" {
+		t.identity = &azureManagedIdentity{client: t.client, clientID: clientID}
+	}
+
+	return t
+}
+
+// Write will upload data as a block blob with a single Put Blob request
+func (t *azureTarget) Write(ctx context.Context, name string, data []byte, metadata map[string]string) (string, error) {
+	if t.prefix != "" {
+		name = t.prefix + "/" + name
+	}
+	blobURL := t.containerURL + "/" + escapeBlobName(name)
+
+	reqURL := blobURL
+	if t.sasToken != "" {
+		reqURL += "?" + t.sasToken
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, reqURL, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("x-ms-version", azureStorageVersion)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("Content-Type", contentType(name))
+
+	// Metadata names must be valid C# identifiers, so hyphens are not allowed
+	for k, v := range metadata {
+		req.Header.Set("x-ms-meta-"+strings.ReplaceAll(k, "-", "_"), v)
+	}
+
+	if t.identity != nil {
+		token, err := t.identity.token(ctx)
+		if err != nil {
+			return "", fmt.Errorf("can not get managed identity token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("can not upload %s: %s: %s", blobURL, resp.Status, msg)
+	}
+
+	return blobURL, nil
+}
+
+// azureManagedIdentity fetches and caches storage access tokens from the instance metadata service
+type azureManagedIdentity struct {
+	client   *http.Client
+	clientID string
+
+	mu          sync.Mutex
+	accessToken string
+	expiresOn   time.Time
+}
+
+// token will return a cached access token, fetching a new one when it is about to expire
+func (m *azureManagedIdentity) token(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.accessToken != "" && time.Now().Add(5*time.Minute).Before(m.expiresOn) {
+		return m.accessToken, nil
+	}
+
+	query := url.Values{"api-version": {"2018-02-01"}, "resource": {azureStorageScope}}
+	if m.clientID != "" {
+		query.Set("client_id", m.clientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, azureIMDSTokenURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata", "true")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+
+	expiresOn, err := strconv.ParseInt(body.ExpiresOn, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("can not parse token expiry %q: %w", body.ExpiresOn, err)
+	}
+
+	m.accessToken = body.AccessToken
+	m.expiresOn = time.Unix(expiresOn, 0)
+
+	return m.accessToken, nil
+}
+
+// escapeBlobName will escape each segment of a blob name while keeping its slashes as virtual directories
+func escapeBlobName(name string) string {
+	segments := strings.Split(name, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// exportChannel is a channel of a Slack workspace export with its messages
+type exportChannel struct {
+	channel slack.Channel
+	// messages are the channel's top level messages, newest first
+	messages []slack.Message
+	// times are the times of messages
+	times []time.Time
+}
+
+// slackExport is the channels, messages and users of a Slack workspace export
+type slackExport struct {
+	channels []*exportChannel
+	// deactivated are the IDs of the deactivated users
+	deactivated map[string]bool
+	// users are the users of the export by ID, for weighing their messages
+	users map[string]*slack.User
+	// oldest and newest are the times of the oldest and newest message
+	oldest time.Time
+	newest time.Time
+}
+
+// backtestStep is the outcome of one simulated run
+type backtestStep struct {
+	Date     string `json:"date"`
+	Channels int    `json:"channels"`
+	Warned   int    `json:"warned"`
+	Archived int    `json:"archived"`
+}
+
+// backtestArchive is a channel the simulation archived
+type backtestArchive struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// WarnedOn is when the channel was first warned before being archived, empty if it never was
+	WarnedOn   string        `json:"warned_on,omitempty"`
+	ArchivedOn string        `json:"archived_on"`
+	Reason     archiveReason `json:"reason"`
+	// ArchivedInExport is whether the channel was already archived when the workspace was exported
+	ArchivedInExport bool `json:"archived_in_export"`
+}
+
+// backtestResult is the outcome of simulating the archive policy against a Slack export
+type backtestResult struct {
+	Steps    []backtestStep    `json:"steps"`
+	Archived []backtestArchive `json:"archived"`
+}
+
+// runBacktestCommand will run "auto-archiver backtest" and return the exit code. It simulates the configured
+// archive policy against a Slack workspace export, without connecting to Slack.
+func runBacktestCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: auto-archiver backtest [flags] <export.zip>")
+		flags.PrintDefaults()
+	}
+	output := flags.String("output", "text", `format of the result, "text" or "json"`)
+	every := flags.Int("every", 7, "number of days between simulated runs")
+	configFile, profile := addProfileFlags(flags)
+	var from, to time.Time
+	flags.Func("from", "time of the first simulated run, defaults to the archive threshold after the oldest message", timeFlag(&from))
+	flags.Func("to", "time of the last simulated run, defaults to the newest message", timeFlag(&to))
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(stderr, "unknown output format %q\n", *output)
+		return exitUsage
+	}
+	if *every < 1 {
+		fmt.Fprintln(stderr, "--every must be at least 1")
+		return exitUsage
+	}
+
+	cfg, err := loadProfileConfig(*configFile, *profile)
+	if err != nil {
+		fmt.Fprintf(stderr, "can not load configuration: %v\n", err)
+		return exitConfig
+	}
+
+	export, err := readSlackExport(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "can not read slack export: %v\n", err)
+		return exitRunFailed
+	}
+	if export.newest.IsZero() {
+		fmt.Fprintln(stderr, "slack export has no messages")
+		return exitRunFailed
+	}
+
+	if from.IsZero() {
+		from = export.oldest.AddDate(0, 0, cfg.archiveThreshold)
+	}
+	if to.IsZero() {
+		to = export.newest
+	}
+	if from.After(to) {
+		fmt.Fprintln(stderr, "--from must not be after --to")
+		return exitUsage
+	}
+
+	a := NewArchiveSlacker(newLogger(stderr), nil, cfg, nil, nil, newRunResult(time.Now()))
+	a.users = export.users
+	result := a.backtest(export, from, to, *every)
+
+	if *output == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			fmt.Fprintf(stderr, "can not write result: %v\n", err)
+			return exitRunFailed
+		}
+		return exitOK
+	}
+
+	if err := result.writeText(stdout); err != nil {
+		fmt.Fprintf(stderr, "can not write result: %v\n", err)
+		return exitRunFailed
+	}
+
+	return exitOK
+}
+
+// backtest will evaluate every channel of the export as of each day from from to to, every days apart,
+// the same way a run would, archiving channels as it goes
+func (a *ArchiveSlacker) backtest(export *slackExport, from, to time.Time, every int) *backtestResult {
+	result := &backtestResult{Steps: []backtestStep{}, Archived: []backtestArchive{}}
+
+	archived := map[string]bool{}
+	warnedOn := map[string]time.Time{}
+
+	for now := from; !now.After(to); now = now.AddDate(0, 0, every) {
+		step := backtestStep{Date: now.Format(time.DateOnly)}
+
+		for _, c := range export.channels {
+			if archived[c.channel.ID] || c.channel.Created.Time().After(now) {
+				continue
+			}
+			step.Channels++
+
+			logger := a.logger.V(1).WithValues("channel", c.channel.Name, "date", step.Date)
+
+			threshold := a.channelThreshold(c.channel)
+			creatorDeactivated := a.deactivatedCreatorThreshold != nil && export.deactivated[c.channel.Creator]
+			if creatorDeactivated {
+				threshold = *a.deactivatedCreatorThreshold
+			}
+
+			// Parse errors are impossible as only messages with valid timestamps are read from the export, and users
+			// are only looked up in the export
+			lastActivity, sawMessages, _ := a.lastActivityIn(context.Background(), logger, c.window(a.windowStart(now, threshold), now), a.minActivityMessages)
+
+			if lastActivity.IsZero() {
+				reason := reasonNoHumanMessages
+				if !sawMessages {
+					reason = reasonEmptyChannel
+				}
+				if creatorDeactivated {
+					reason = reasonCreatorDeactivated
+				}
+
+				archive := backtestArchive{
+					ID:               c.channel.ID,
+					Name:             c.channel.Name,
+					ArchivedOn:       step.Date,
+					Reason:           reason,
+					ArchivedInExport: c.channel.IsArchived,
+				}
+				if w, ok := warnedOn[c.channel.ID]; ok {
+					archive.WarnedOn = w.Format(time.DateOnly)
+				}
+
+				archived[c.channel.ID] = true
+				result.Archived = append(result.Archived, archive)
+				step.Archived++
+				continue
+			}
+
+			if !a.needsAttention(lastActivity, now, threshold, a.channelWarningDays(c.channel)) {
+				delete(warnedOn, c.channel.ID)
+				continue
+			}
+
+			if _, ok := warnedOn[c.channel.ID]; !ok {
+				warnedOn[c.channel.ID] = now
+			}
+			step.Warned++
+		}
+
+		result.Steps = append(result.Steps, step)
+	}
+
+	return result
+}
+
+// writeText will write the result as tables of the simulated runs and the archived channels
+func (r *backtestResult) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "DATE\tCHANNELS\tWARNED\tARCHIVED")
+	for _, s := range r.Steps {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", s.Date, s.Channels, s.Warned, s.Archived)
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "CHANNEL\tWARNED\tARCHIVED\tREASON\tARCHIVED IN EXPORT")
+	for _, c := range r.Archived {
+		warned := c.WarnedOn
+		if warned == "" {
+			warned = "-"
+		}
+		fmt.Fprintf(tw, "#%s\t%s\t%s\t%s\t%t\n", c.Name, warned, c.ArchivedOn, c.Reason, c.ArchivedInExport)
+	}
+
+	return tw.Flush()
+}
+
+// window will return the channel's messages posted after oldest and no later than latest, newest first
+func (c *exportChannel) window(oldest, latest time.Time) []slack.Message {
+	start := sort.Search(len(c.times), func(i int) bool { return !c.times[i].After(latest) })
+	end := sort.Search(len(c.times), func(i int) bool { return !c.times[i].After(oldest) })
+
+	return c.messages[start:end]
+}
+
+// readSlackExport will read the public and private channels of a Slack workspace export ZIP.
+// Channel history is kept in a directory per channel name with a JSON file of messages per day.
+func readSlackExport(name string) (*slackExport, error) {
+	r, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	channels := []slack.Channel{}
+	for _, file := range []string{"channels.json", "groups.json"} {
+		var cs []slack.Channel
+		if err := readExportFile(&r.Reader, file, &cs); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		channels = append(channels, cs...)
+	}
+	if len(channels) == 0 {
+		return nil, errors.New("no channels.json or groups.json in export")
+	}
+
+	var users []slack.User
+	if err := readExportFile(&r.Reader, "users.json", &users); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	export := &slackExport{deactivated: map[string]bool{}, users: map[string]*slack.User{}}
+	for i, u := range users {
+		if u.Deleted {
+			export.deactivated[u.ID] = true
+		}
+		export.users[u.ID] = &users[i]
+	}
+
+	byName := map[string]*exportChannel{}
+	for _, c := range channels {
+		ec := &exportChannel{channel: c}
+		export.channels = append(export.channels, ec)
+		byName[c.Name] = ec
+	}
+
+	for _, f := range r.File {
+		dir, file := path.Split(f.Name)
+		c, ok := byName[strings.TrimSuffix(dir, "/")]
+		if !ok || path.Ext(file) != ".json" {
+			continue
+		}
+
+		var messages []slack.Message
+		if err := readExportFile(&r.Reader, f.Name, &messages); err != nil {
+			return nil, err
+		}
+
+		for _, m := range messages {
+			// Thread replies are not part of the channel history a run reads
+			if m.ThreadTimestamp != "" && m.ThreadTimestamp != m.Timestamp {
+				continue
+			}
+
+			t, err := parseSlackTimestamp(m.Timestamp)
+			if err != nil {
+				return nil, fmt.Errorf("can not parse timestamp of message in %s: %w", f.Name, err)
+			}
+
+			c.messages = append(c.messages, m)
+			c.times = append(c.times, t)
+
+			if export.oldest.IsZero() || t.Before(export.oldest) {
+				export.oldest = t
+			}
+			if t.After(export.newest) {
+				export.newest = t
+			}
+		}
+	}
+
+	for _, c := range export.channels {
+		sort.Sort(newestFirst{c})
+	}
+
+	return export, nil
+}
+
+// readExportFile will decode the JSON file called name in a Slack export into v
+func readExportFile(r *zip.Reader, name string, v any) error {
+	f, err := r.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("can not decode %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// newestFirst sorts the messages of an export channel newest first
+type newestFirst struct {
+	c *exportChannel
+}
+
+func (s newestFirst) Len() int           { return len(s.c.messages) }
+func (s newestFirst) Less(i, j int) bool { return s.c.times[i].After(s.c.times[j]) }
+func (s newestFirst) Swap(i, j int) {
+	s.c.messages[i], s.c.messages[j] = s.c.messages[j], s.c.messages[i]
+	s.c.times[i], s.c.times[j] = s.c.times[j], s.c.times[i]
+}
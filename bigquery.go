@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	bigQueryInsertAllURL = "https://bigquery.googleapis.com/bigquery/v2/projects/%s/datasets/%s/tables/%s/insertAll"
+	bigQueryScope        = "https://www.googleapis.com/auth/bigquery.insertdata"
+	// bigQueryBatchSize is how many rows are streamed per request, well below the insertAll limits
+	bigQueryBatchSize = 500
+)
+
+// Kinds of rows streamed into BigQuery
+const (
+	bigQueryRunRow     = "run"
+	bigQueryChannelRow = "channel"
+)
+
+// bigQueryTable is the table run results are streamed into
+type bigQueryTable struct {
+	project string
+	dataset string
+	table   string
+}
+
+// parseBigQueryTable will parse a table in BigQuery's <project>.<dataset>.<table> notation
+func parseBigQueryTable(v string) (*bigQueryTable, error) {
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("BigQuery table %q must be <project>.<dataset>.<table>", v)
+	}
+
+	return &bigQueryTable{project: parts[0], dataset: parts[1], table: parts[2]}, nil
+}
+
+// bigQueryRow is a row of the results table. Every run streams one run row with its counts and one channel row
+// per channel with its decision, the columns of the other kind being null.
+type bigQueryRow struct {
+	Kind      string    `json:"kind"`
+	RunID     string    `json:"run_id"`
+	StartedAt time.Time `json:"started_at"`
+	DryRun    bool      `json:"dry_run"`
+
+	DurationMS *int64 `json:"duration_ms,omitempty"`
+	Scanned    *int   `json:"scanned,omitempty"`
+	Kept       *int   `json:"kept,omitempty"`
+	Warned     *int   `json:"warned,omitempty"`
+	Archived   *int   `json:"archived,omitempty"`
+	Exempt     *int   `json:"exempt,omitempty"`
+	Failed     *int   `json:"failed,omitempty"`
+	Errors     *int   `json:"errors,omitempty"`
+
+	ChannelID    string        `json:"channel_id,omitempty"`
+	ChannelName  string        `json:"channel_name,omitempty"`
+	Decision     decision      `json:"decision,omitempty"`
+	Reason       archiveReason `json:"reason,omitempty"`
+	DaysInactive *int          `json:"days_inactive,omitempty"`
+	AgeDays      *int          `json:"age_days,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	ErrorClass   string        `json:"error_class,omitempty"`
+	ReportOnly   *bool         `json:"report_only,omitempty"`
+}
+
+// bigQueryRows will return the rows of a finished run, keyed by insert ID so retried requests are deduplicated
+func bigQueryRows(r *runResult, dryRun bool) ([]string, []bigQueryRow) {
+	run := bigQueryRow{Kind: bigQueryRunRow, RunID: r.RunID, StartedAt: r.StartedAt, DryRun: dryRun}
+	errs := r.Counts.Failed + len(r.Errors)
+	run.DurationMS = &r.DurationMS
+	run.Scanned, run.Kept, run.Warned = &r.Counts.Scanned, &r.Counts.Kept, &r.Counts.Warned
+	run.Archived, run.Exempt, run.Failed, run.Errors = &r.Counts.Archived, &r.Counts.Exempt, &r.Counts.Failed, &errs
+
+	ids := []string{r.RunID}
+	rows := []bigQueryRow{run}
+	for i := range r.Channels {
+		c := &r.Channels[i]
+		ids = append(ids, r.RunID+"/"+c.ID)
+		rows = append(rows, bigQueryRow{
+			Kind:         bigQueryChannelRow,
+			RunID:        r.RunID,
+			StartedAt:    r.StartedAt,
+			DryRun:       dryRun,
+			ChannelID:    c.ID,
+			ChannelName:  c.Name,
+			Decision:     c.Decision,
+			Reason:       c.Reason,
+			DaysInactive: &c.DaysInactive,
+			AgeDays:      &c.AgeDays,
+			Error:        c.Error,
+			ErrorClass:   c.ErrorClass,
+			ReportOnly:   &c.ReportOnly,
+		})
+	}
+
+	return ids, rows
+}
+
+// bigQueryInsertRequest is the body of a tabledata.insertAll request
+type bigQueryInsertRequest struct {
+	Rows []bigQueryInsertRow `json:"rows"`
+}
+
+type bigQueryInsertRow struct {
+	InsertID string      `json:"insertId"`
+	JSON     bigQueryRow `json:"json"`
+}
+
+// bigQueryInsertResponse lists the rows of a tabledata.insertAll request that were not inserted
+type bigQueryInsertResponse struct {
+	InsertErrors []struct {
+		Index  int `json:"index"`
+		Errors []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"insertErrors"`
+}
+
+// exportToBigQuery will stream the run and its channel decisions into a BigQuery table, authenticating with
+// Application Default Credentials. The table must exist with columns for every field of bigQueryRow.
+func exportToBigQuery(ctx context.Context, table *bigQueryTable, r *runResult, dryRun bool) error {
+	client, err := google.DefaultClient(ctx, bigQueryScope)
+	if err != nil {
+		return fmt.Errorf("can not find application default credentials: %w", err)
+	}
+
+	ids, rows := bigQueryRows(r, dryRun)
+	for start := 0; start < len(rows); start += bigQueryBatchSize {
+		end := min(start+bigQueryBatchSize, len(rows))
+		body := bigQueryInsertRequest{Rows: make([]bigQueryInsertRow, 0, end-start)}
+		for i := start; i < end; i++ {
+			body.Rows = append(body.Rows, bigQueryInsertRow{InsertID: ids[i], JSON: rows[i]})
+		}
+		if err := table.insert(ctx, client, body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insert will send a single tabledata.insertAll request, failing if any of its rows was not inserted
+func (t *bigQueryTable) insert(ctx context.Context, client *http.Client, body bigQueryInsertRequest) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf(bigQueryInsertAllURL, url.PathEscape(t.project), url.PathEscape(t.dataset), url.PathEscape(t.table))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("can not insert into %s.%s.%s: %s: %s", t.project, t.dataset, t.table, resp.Status, msg)
+	}
+
+	var result bigQueryInsertResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("can not decode insert response: %w", err)
+	}
+	if len(result.InsertErrors) > 0 {
+		e := result.InsertErrors[0]
+		msg := "unknown error"
+		if len(e.Errors) > 0 {
+			msg = e.Errors[0].Reason + ": " + e.Errors[0].Message
+		}
+		return fmt.Errorf("can not insert %d rows into %s.%s.%s, the first failing with %s", len(result.InsertErrors),
+			t.project, t.dataset, t.table, msg)
+	}
+
+	return nil
+}
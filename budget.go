@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// slackRateLimits are the calls per minute Slack allows of the methods auto-archiver calls, by their rate limit
+// tier. chat.postMessage is limited per channel, so its workspace wide limit is an approximation.
+var slackRateLimits = map[string]int{
+	"conversations.list":    20,
+	"conversations.archive": 20,
+	"pins.list":             20,
+	"conversations.members": 100,
+	"users.info":            100,
+	"chat.postMessage":      60,
+}
+
+// defaultRateLimit is the calls per minute of methods missing from slackRateLimits, Slack's tier 3
+const defaultRateLimit = 50
+
+// apiCallCounter counts the Slack API calls made, by method
+type apiCallCounter struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newAPICallCounter() *apiCallCounter {
+	return &apiCallCounter{calls: map[string]int{}}
+}
+
+// counts will return a copy of the calls made so far, by method
+func (c *apiCallCounter) counts() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := map[string]int{}
+	for method, n := range c.calls {
+		counts[method] = n
+	}
+
+	return counts
+}
+
+// countingTransport counts the Slack API calls made through it
+type countingTransport struct {
+	next    http.RoundTripper
+	counter *apiCallCounter
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.counter.mu.Lock()
+	t.counter.calls[path.Base(req.URL.Path)]++
+	t.counter.mu.Unlock()
+
+	return t.next.RoundTrip(req)
+}
+
+// apiBudget is an estimate of the Slack API calls a real run makes and how long Slack's rate limits make it take
+type apiBudget struct {
+	// Calls are the estimated calls by method
+	Calls map[string]int
+	Total int
+	// Duration is how long the calls take at least, spread over shards and paced as configured
+	Duration time.Duration
+}
+
+// estimateAPIBudget will estimate the API calls a real run makes from the calls a dry run made to read channels,
+// which the real run makes as well, and the messages and archives it would add for the decisions in r.
+// Joining channels and exporting their history are not included.
+func estimateAPIBudget(reads map[string]int, r *runResult, cfg *config) apiBudget {
+	b := apiBudget{Calls: map[string]int{}}
+	for method, n := range reads {
+		b.Calls[method] = n
+	}
+
+	// Every warning and archive notice is a message, and every archive a call of its own
+	b.Calls["chat.postMessage"] += r.Counts.Warned + r.Counts.Archived
+	b.Calls["conversations.archive"] += r.Counts.Archived
+	if cfg.notifyCreator {
+		b.Calls["conversations.open"] += r.Counts.Archived
+		b.Calls["chat.postMessage"] += r.Counts.Archived
+	}
+	for method, n := range b.Calls {
+		if n == 0 {
+			delete(b.Calls, method)
+		}
+		b.Total += n
+	}
+
+	// Each bot shard is its own app with its own rate limits, and the slowest method sets the pace
+	shards := 1 + len(cfg.extraBotTokens)
+	var minutes float64
+	for method, n := range b.Calls {
+		limit, ok := slackRateLimits[method]
+		if !ok {
+			limit = defaultRateLimit
+		}
+		minutes = max(minutes, float64(n)/float64(limit*shards))
+	}
+	if cfg.archivesPerMinute > 0 {
+		minutes = max(minutes, float64(r.Counts.Archived)/float64(cfg.archivesPerMinute))
+	}
+	b.Duration = time.Duration(math.Ceil(minutes*60)) * time.Second
+
+	return b
+}
+
+// write will write the estimate below a plan, the methods called most first
+func (b apiBudget) write(w io.Writer) error {
+	methods := make([]string, 0, len(b.Calls))
+	for method := range b.Calls {
+		methods = append(methods, method)
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		if b.Calls[methods[i]] != b.Calls[methods[j]] {
+			return b.Calls[methods[i]] > b.Calls[methods[j]]
+		}
+		return methods[i] < methods[j]
+	})
+
+	if _, err := fmt.Fprintf(w, "\nEstimated %d Slack API calls, taking at least %s at Slack's rate limits:\n", b.Total, b.Duration); err != nil {
+		return err
+	}
+	for _, method := range methods {
+		if _, err := fmt.Fprintf(w, "  %-24s %d\n", method, b.Calls[method]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
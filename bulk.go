@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+const (
+	// bulkArchiveBatchSize is how many channels one admin.conversations.bulkArchive call archives
+	bulkArchiveBatchSize = 10
+	// archivePathBulk is the archive path of channels archived with admin.conversations.bulkArchive
+	archivePathBulk = "bulk"
+)
+
+// bulkArchive will start archiving channels in the background, returning the ID of the bulk action. It needs an org
+// admin's user token with the admin.conversations:write scope.
+func (r *rawSlackClient) bulkArchive(ctx context.Context, channelIDs []string) (string, error) {
+	var resp struct {
+		BulkActionID string `json:"bulk_action_id"`
+	}
+	if err := r.call(ctx, "admin.conversations.bulkArchive", url.Values{"channel_ids": {strings.Join(channelIDs, ",")}}, &resp); err != nil {
+		return "", err
+	}
+	// Without the ID of a bulk action, nothing was started
+	if resp.BulkActionID == "" {
+		return "", errors.New("admin.conversations.bulkArchive did not return a bulk action ID")
+	}
+
+	return resp.BulkActionID, nil
+}
+
+// bulkArchiver collects the channels a run archives into batches archived with one admin.conversations.bulkArchive
+// call each, instead of a call per channel
+type bulkArchiver struct {
+	client  *rawSlackClient
+	pending []inactiveChannel
+}
+
+// add will add a channel to the batch, reporting whether the batch is full
+func (b *bulkArchiver) add(c inactiveChannel) bool {
+	b.pending = append(b.pending, c)
+
+	return len(b.pending) >= bulkArchiveBatchSize
+}
+
+// archive will archive the batch, returning its channels and the ID of the bulk action, and start a new batch
+func (b *bulkArchiver) archive(ctx context.Context) ([]inactiveChannel, string, error) {
+	channels := b.pending
+	b.pending = nil
+
+	ids := make([]string, len(channels))
+	for i, c := range channels {
+		ids[i] = c.channel.ID
+	}
+	id, err := b.client.bulkArchive(ctx, ids)
+
+	return channels, id, err
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// bucketArchived holds the channels auto-archiver archived within the recreation window, or until they are
+// unarchived when unarchived channels are exempted, by channel ID
+const bucketArchived = "archived"
+
+// archivedChannel is a channel auto-archiver archived
+type archivedChannel struct {
+	ChannelID   string        `json:"channel_id"`
+	ChannelName string        `json:"channel_name"`
+	Reason      archiveReason `json:"reason,omitempty"`
+	ArchivedAt  time.Time     `json:"archived_at"`
+}
+
+// channelRecreation is a channel created soon after auto-archiver archived a channel with the same or a similar
+// name, a sign the threshold is too aggressive for channels like it
+type channelRecreation struct {
+	Archived  archivedChannel `json:"archived"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Creator   string          `json:"creator"`
+	Created   time.Time       `json:"created"`
+	DaysLater int             `json:"days_later"`
+}
+
+// recordArchived will remember that a channel was archived for as long as a new channel with its name counts as
+// recreating it, or until it is unarchived when unarchived channels are exempted
+func (a *ArchiveSlacker) recordArchived(ctx context.Context, c inactiveChannel, now time.Time) error {
+	ttl := time.Duration(a.recreationWindowDays) * 24 * time.Hour
+	if a.unarchiveExemptionDays > 0 {
+		ttl = 0
+	}
+
+	return putJSON(ctx, a.store, bucketArchived, c.channel.ID, archivedChannel{
+		ChannelID:   c.channel.ID,
+		ChannelName: c.channel.Name,
+		Reason:      c.reason,
+		ArchivedAt:  now,
+	}, ttl)
+}
+
+// findRecreatedChannels will find the channels created within windowDays after auto-archiver archived a channel
+// with the same or a similar name
+func findRecreatedChannels(ctx context.Context, store Store, channels []slack.Channel, windowDays int) ([]channelRecreation, error) {
+	values, err := store.List(ctx, bucketArchived)
+	if err != nil {
+		return nil, err
+	}
+
+	archived := []archivedChannel{}
+	for key, value := range values {
+		var a archivedChannel
+		if err := json.Unmarshal(value, &a); err != nil {
+			return nil, fmt.Errorf("can not decode %s/%s: %w", bucketArchived, key, err)
+		}
+		archived = append(archived, a)
+	}
+
+	recreations := []channelRecreation{}
+	for _, c := range channels {
+		created := c.Created.Time()
+		name := normalizeChannelName(c.Name)
+		for _, a := range archived {
+			if c.ID == a.ChannelID || created.Before(a.ArchivedAt) || created.After(a.ArchivedAt.AddDate(0, 0, windowDays)) {
+				continue
+			}
+			if !similarChannelNames(name, normalizeChannelName(a.ChannelName)) {
+				continue
+			}
+
+			recreations = append(recreations, channelRecreation{
+				Archived:  a,
+				ID:        c.ID,
+				Name:      c.Name,
+				Creator:   c.Creator,
+				Created:   created,
+				DaysLater: int(created.Sub(a.ArchivedAt).Hours() / 24),
+			})
+			break
+		}
+	}
+	sort.Slice(recreations, func(i, j int) bool { return recreations[i].Name < recreations[j].Name })
+
+	return recreations, nil
+}
+
+// recreationsByPrefix will count the recreated channels by the prefix of the archived channel's name
+func recreationsByPrefix(recreations []channelRecreation) map[string]int {
+	counts := map[string]int{}
+	for _, r := range recreations {
+		counts[namePrefix(r.Archived.ChannelName)]++
+	}
+
+	return counts
+}
+
+// namePrefix will return the part of a channel name before the first dash, which is usually the channel's class
+func namePrefix(name string) string {
+	prefix, _, _ := strings.Cut(name, "-")
+	return prefix
+}
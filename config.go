@@ -0,0 +1,1129 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	"golang.org/x/crypto/ssh"
+)
+
+// config holds all auto-archiver settings
+type config struct {
+	appToken  string
+	botToken  string
+	verbosity int
+	// slackDebug makes the Slack SDK log every API request and response, which include message content
+	slackDebug bool
+	// extraBotTokens are the tokens of further installs of the app, channels are spread over them and the bot token
+	extraBotTokens []string
+	// slackAPIURL is the base URL of the Slack Web API, ending in a slash
+	slackAPIURL string
+	// httpTimeout bounds connecting to Slack and waiting for each response to start
+	httpTimeout time.Duration
+	// apiCallTimeout bounds each Slack API call including reading its response, 0 is unbounded
+	apiCallTimeout time.Duration
+	// runTimeout bounds a whole run, 0 is unbounded
+	runTimeout time.Duration
+	// httpProxy is the proxy Slack is reached through, nil to use the proxy environment variables
+	httpProxy *url.URL
+	// caCerts are the certificate authorities trusted for connections to Slack, nil to trust the system's
+	caCerts *x509.CertPool
+
+	archiveThreshold int
+	warningDays      int
+	// deactivatedCreatorThreshold is the archive threshold for channels whose creator is deactivated, nil when
+	// they use the archive threshold
+	deactivatedCreatorThreshold *int
+	// privateArchiveThreshold and privateWarningDays replace the archive threshold and warning days for private
+	// channels, nil when private channels use the same
+	privateArchiveThreshold *int
+	privateWarningDays      *int
+
+	// archiveWithoutHumanMembers archives channels whose only members are bots regardless of the threshold
+	archiveWithoutHumanMembers bool
+	// archiveMembersDeactivated archives channels whose human members are all deactivated regardless of the threshold
+	archiveMembersDeactivated bool
+	// singleMemberThreshold is the archive threshold for channels with one human member, nil when disabled
+	singleMemberThreshold *int
+
+	// autoJoin makes auto-archiver join every public channel, otherwise it only acts on channels it was invited to
+	autoJoin bool
+	// maxJoinsPerRun is the most public channels joined in a run, 0 is unlimited
+	maxJoinsPerRun int
+	joinDelay      time.Duration
+	// trackActivity makes the daemon track activity from message events instead of reading channel history
+	trackActivity bool
+	// leaveExemptChannels makes auto-archiver leave channels that are permanently exempt
+	leaveExemptChannels bool
+
+	// activityBots are the bot and bot user IDs whose messages count as activity, all bots count when empty
+	activityBots []string
+	// activityUsers are the users whose messages always count as activity, ignoredUsers those whose never do
+	activityUsers []string
+	ignoredUsers  []string
+	// countReminders and countWorkflows count reminders and Workflow Builder posts as activity
+	countReminders bool
+	countWorkflows bool
+	// reactionWeight is how much each reaction counts towards one message of activity, 0 ignores reactions
+	reactionWeight float64
+	canvasActivity bool
+	// channelTags reads channel settings from pinned tag messages and topics
+	channelTags bool
+	// historyPageSize and historyMaxPages bound how much of a channel's history is read looking for activity,
+	// historyMaxPages 0 reads the whole threshold
+	historyPageSize int
+	historyMaxPages int
+	// historyInclusive counts messages posted exactly at the start of the threshold
+	historyInclusive bool
+	// minActivityMessages is how many messages of activity within the threshold keep a channel active
+	minActivityMessages int
+	// guestWeight and externalWeight are how much messages of guests and of users from other organizations count
+	// towards one message of activity, 0 ignores them
+	guestWeight    float64
+	externalWeight float64
+
+	// retentionToken is an org admin's user token for reading channels' custom retention policies, empty when
+	// they are not checked
+	retentionToken  string
+	retentionAction string
+	// staleWarnings is whether the warnings of channels that became active again are kept, deleted or updated
+	staleWarnings string
+	// countdownWarnings updates a channel's warning with the days left every run instead of posting a new one
+	countdownWarnings bool
+	// nudgeMembers is how many recently active members are privately asked about a channel instead of warning it
+	nudgeMembers int
+	// warningMention is whether warnings mention nobody, @here or @channel, and warningMentionMaxMembers the
+	// member count above which channels are not mentioned, 0 for no limit
+	warningMention           string
+	warningMentionMaxMembers int
+	// adminToken is an org admin's user token for admin.conversations methods, and adminFallback whether public
+	// channels the bot can not join are evaluated and archived with it
+	adminToken    string
+	adminFallback bool
+	// bulkArchiveMinChannels is how many channels a run has to archive for them to be archived in bulk with
+	// adminToken, 0 never archives in bulk
+	bulkArchiveMinChannels int
+	// archiveUserToken is the user token channels are archived with instead of the bot token, in workspaces that
+	// only let admins archive channels, empty to archive with the bot token
+	archiveUserToken string
+	// archiveWindow is the time of day channels may be archived in, nil to archive at any time
+	archiveWindow *archiveWindow
+	// privateChannels is whether private channels are ignored, reported on or archived
+	privateChannels string
+	// workspaces are the team IDs of the Enterprise Grid workspaces an org-wide install runs in, nil to run in the
+	// token's workspace
+	workspaces []string
+	// orgAdminChannel is where the rollup of every workspace is posted after each run of an org-wide install
+	orgAdminChannel string
+	// teamID is the workspace of the current run of an org-wide install, empty otherwise
+	teamID string
+
+	// incidents is the lifecycle policy for incident channels, nil when disabled
+	incidents *incidentPolicy
+
+	decisionWebhookURL     string
+	decisionWebhookToken   string
+	decisionWebhookTimeout time.Duration
+
+	// exemptionServiceURL is the base URL of the governance service asked whether candidates are exempt
+	exemptionServiceURL     string
+	exemptionServiceToken   string
+	exemptionServiceTimeout time.Duration
+	// exemptionServiceCacheTTL is how long the service's answers are cached, 0 asks it every run
+	exemptionServiceCacheTTL time.Duration
+
+	// hookPreWarn, hookPreArchive and hookPostArchive are the commands run at each stage, with their arguments
+	hookPreWarn     string
+	hookPreArchive  string
+	hookPostArchive string
+	hookTimeout     time.Duration
+
+	// directorySCIMURL is the base URL of the SCIM directory managers are resolved from
+	directorySCIMURL   string
+	directorySCIMToken string
+
+	// ldapURL is the LDAP or Active Directory server the groups of channel owners are looked up in, nil when none is
+	ldapURL          *url.URL
+	ldapBindDN       string
+	ldapBindPassword string
+	ldapBaseDN       string
+	// ldapUserAttribute is the attribute owners are found by their email with
+	ldapUserAttribute string
+	ldapTimeout       time.Duration
+	// exemptGroups are the directory groups, by common name or DN, whose members' channels are exempt
+	exemptGroups []string
+
+	adminChannel string
+	// escalationSteps are the steps taken before archiving an inactive channel, nil when channels are warned every run
+	escalationSteps []escalationStep
+	// archiveApproval makes archiving each channel wait for approval from the admin channel
+	archiveApproval bool
+	// twoPersonApprovalMembers is the member count from which channels need two approvers, 0 disables it
+	twoPersonApprovalMembers int
+	// twoPersonApprovalAgeDays is the age in days from which channels need two approvers, 0 disables it
+	twoPersonApprovalAgeDays int
+	// exemptionReminderDays is how long before an exemption ends the user who made it is reminded, 0 disables reminders
+	exemptionReminderDays int
+	// archivesPerMinute is the most channels archived a minute, 0 is unlimited
+	archivesPerMinute int
+	// maxArchivesPerRun is the most channels archived in a run, 0 is unlimited
+	maxArchivesPerRun int
+	// archiveRetryAttempts is how many runs retry archiving a channel that failed transiently, 0 disables retries
+	archiveRetryAttempts int
+	// pinnedExemptions reads exemptions from the messages pinned in the admin channel each run
+	pinnedExemptions bool
+	// adminDigestUsers are sent the run summary as a direct message
+	adminDigestUsers []string
+	notifyCreator    bool
+	// respectDND schedules direct messages to users with Do Not Disturb on for when it ends
+	respectDND bool
+	// ownersSource is the file or URL of the channel ownership CSV
+	ownersSource string
+
+	// recreationWindowDays is how long after auto-archiver archived a channel a new channel with a similar name
+	// counts as recreating it
+	recreationWindowDays int
+	// unarchiveExemptionDays is how long channels unarchived after auto-archiver archived them are exempt, 0 when
+	// they are not
+	unarchiveExemptionDays int
+	// maxScanIntervalDays is the longest a kept channel goes without being scanned, 0 to scan every channel every run
+	maxScanIntervalDays int
+	// channelPageSize is how many channels are listed, evaluated and acted on at a time, 0 to list every channel first
+	channelPageSize int
+
+	// namingConventions are the classes of channel names the report checks channels against
+	namingConventions []namingConvention
+	// reportGroupDMs includes the stale multi-person DMs the bot is in in the report
+	reportGroupDMs bool
+
+	// bigQueryTable is the table run results are streamed into, nil when they are not
+	bigQueryTable *bigQueryTable
+	// resultsURL is the gs:// or s3:// prefix run results are written under as resultsFormat files
+	resultsURL    string
+	resultsFormat string
+	// datadogAPIKey sends run metrics and events to the Datadog API of datadogSite when set
+	datadogAPIKey string
+	datadogSite   string
+	// cloudWatchEMF writes run metrics as CloudWatch Embedded Metric Format log lines in cloudWatchNamespace
+	cloudWatchEMF       bool
+	cloudWatchNamespace string
+
+	// detectDuplicates suggests merging likely duplicate channels in the run summary
+	detectDuplicates bool
+	// duplicateMemberOverlap is the share of members two channels must have in common to be duplicates
+	duplicateMemberOverlap float64
+
+	// policyRepo is the URL of the Git repository the policy is read from each run, empty when disabled
+	policyRepo  string
+	policyRef   string
+	policyPath  string
+	policyToken string
+
+	// notifiers are the names of the targets told about warnings, archives, run summaries and errors
+	notifiers           []string
+	notifyWebhookURL    string
+	notifyWebhookToken  string
+	notifyEmailSMTPAddr string
+	notifyEmailFrom     string
+	notifyEmailTo       []string
+	notifyEmailUsername string
+	notifyEmailPassword string
+
+	exportDir             string
+	exportGCSBucket       string
+	exportGCSPrefix       string
+	exportGCSStorageClass string
+	// exportOptional are the export targets a channel is still archived without a backup in when writing fails
+	exportOptional []string
+
+	exportAzureContainerURL string
+	exportAzurePrefix       string
+	exportAzureSASToken     string
+	exportAzureClientID     string
+
+	exportFiles         bool
+	exportFilesMaxBytes int
+
+	templates *messageTemplates
+
+	stateFile        string
+	stateBoltFile    string
+	statePostgresURL string
+	stateRedisURL    string
+	// stateRedisPrefix starts the name of every Redis key, so several deployments can share a Redis
+	stateRedisPrefix   string
+	stateDynamoDBTable string
+	stateObjectURL     string
+
+	// daemonInterval is how often the daemon runs the archive pass
+	daemonInterval time.Duration
+	// triggerAddr is the address the daemon accepts run triggers on, empty when triggers are disabled
+	triggerAddr  string
+	triggerToken string
+	// authorizedUsers may manage auto-archiver from Slack in addition to workspace admins and owners
+	authorizedUsers []string
+	// setupAdmin is the user sent the setup wizard, empty when auto-archiver is configured from the environment
+	setupAdmin string
+
+	// planTrustedKeys are the keys applied plans must be signed by, nil when plans need no signature
+	planTrustedKeys []ssh.PublicKey
+	// planMaxAge is how old an applied plan may be
+	planMaxAge time.Duration
+
+	// dryRun, since, until and daemon are set from command line flags
+	dryRun bool
+	since  time.Time
+	until  time.Time
+	daemon bool
+	// readOnly runs are dry runs that may only call Slack API methods needing read scopes and never write state,
+	// set from the --read-only flag
+	readOnly bool
+	// channelFilter limits a triggered run to the channels with these IDs or names, nil for every channel
+	channelFilter map[string]bool
+	// plan are the decisions of the plan being applied by channel ID, nil when no plan is applied
+	plan map[string]decision
+	// policy is the policy read from Git for this run, nil when there is none
+	policy *channelPolicy
+	// apiCalls counts the Slack API calls of dry runs to estimate those of the real run, nil when they are not counted
+	apiCalls *apiCallCounter
+	// logWriter is where logs, and run metrics as EMF, are written, set by main
+	logWriter io.Writer
+}
+
+// loadConfig reads the auto-archiver settings using getenv to look up each value
+func loadConfig(getenv func(string) string) (*config, error) {
+	var err error
+
+	// TODO(dpe): write package to handle config and secret generation
+	c := &config{
+		appToken:     getenv("AUTO_ARCHIVER_APP_TOKEN"),
+		botToken:     getenv("AUTO_ARCHIVER_BOT_TOKEN"),
+		adminChannel: getenv("AUTO_ARCHIVER_ADMIN_CHANNEL"),
+		exportDir:    getenv("AUTO_ARCHIVER_EXPORT_DIR"),
+		activityBots: listSetting(getenv, "AUTO_ARCHIVER_ACTIVITY_BOTS"),
+		stateFile:    getenv("AUTO_ARCHIVER_STATE_FILE"),
+		ownersSource: getenv("AUTO_ARCHIVER_OWNERS"),
+		slackAPIURL:  getenv("AUTO_ARCHIVER_SLACK_API_URL"),
+
+		stateBoltFile:      getenv("AUTO_ARCHIVER_STATE_BOLT_FILE"),
+		statePostgresURL:   getenv("AUTO_ARCHIVER_STATE_POSTGRES_URL"),
+		stateRedisURL:      getenv("AUTO_ARCHIVER_STATE_REDIS_URL"),
+		stateRedisPrefix:   getenv("AUTO_ARCHIVER_STATE_REDIS_PREFIX"),
+		stateDynamoDBTable: getenv("AUTO_ARCHIVER_STATE_DYNAMODB_TABLE"),
+		stateObjectURL:     getenv("AUTO_ARCHIVER_STATE_OBJECT_URL"),
+
+		extraBotTokens:   listSetting(getenv, "AUTO_ARCHIVER_EXTRA_BOT_TOKENS"),
+		authorizedUsers:  listSetting(getenv, "AUTO_ARCHIVER_AUTHORIZED_USERS"),
+		setupAdmin:       getenv("AUTO_ARCHIVER_SETUP_ADMIN"),
+		adminDigestUsers: listSetting(getenv, "AUTO_ARCHIVER_ADMIN_DIGEST_USERS"),
+
+		decisionWebhookURL:   getenv("AUTO_ARCHIVER_DECISION_WEBHOOK_URL"),
+		decisionWebhookToken: getenv("AUTO_ARCHIVER_DECISION_WEBHOOK_TOKEN"),
+
+		exemptionServiceURL:   getenv("AUTO_ARCHIVER_EXEMPTION_SERVICE_URL"),
+		exemptionServiceToken: getenv("AUTO_ARCHIVER_EXEMPTION_SERVICE_TOKEN"),
+
+		policyRepo:  getenv("AUTO_ARCHIVER_POLICY_REPO"),
+		policyRef:   getenv("AUTO_ARCHIVER_POLICY_REF"),
+		policyPath:  getenv("AUTO_ARCHIVER_POLICY_PATH"),
+		policyToken: getenv("AUTO_ARCHIVER_POLICY_TOKEN"),
+
+		triggerAddr:  getenv("AUTO_ARCHIVER_TRIGGER_ADDR"),
+		triggerToken: getenv("AUTO_ARCHIVER_TRIGGER_TOKEN"),
+
+		hookPreWarn:     getenv("AUTO_ARCHIVER_HOOK_PRE_WARN"),
+		hookPreArchive:  getenv("AUTO_ARCHIVER_HOOK_PRE_ARCHIVE"),
+		hookPostArchive: getenv("AUTO_ARCHIVER_HOOK_POST_ARCHIVE"),
+
+		notifiers:           listSetting(getenv, "AUTO_ARCHIVER_NOTIFIERS"),
+		notifyWebhookURL:    getenv("AUTO_ARCHIVER_NOTIFY_WEBHOOK_URL"),
+		notifyWebhookToken:  getenv("AUTO_ARCHIVER_NOTIFY_WEBHOOK_TOKEN"),
+		notifyEmailSMTPAddr: getenv("AUTO_ARCHIVER_NOTIFY_EMAIL_SMTP_ADDR"),
+		notifyEmailFrom:     getenv("AUTO_ARCHIVER_NOTIFY_EMAIL_FROM"),
+		notifyEmailTo:       listSetting(getenv, "AUTO_ARCHIVER_NOTIFY_EMAIL_TO"),
+		notifyEmailUsername: getenv("AUTO_ARCHIVER_NOTIFY_EMAIL_USERNAME"),
+		notifyEmailPassword: getenv("AUTO_ARCHIVER_NOTIFY_EMAIL_PASSWORD"),
+
+		directorySCIMURL:   strings.TrimSuffix(getenv("AUTO_ARCHIVER_DIRECTORY_SCIM_URL"), "/"),
+		directorySCIMToken: getenv("AUTO_ARCHIVER_DIRECTORY_SCIM_TOKEN"),
+
+		ldapBindDN:       getenv("AUTO_ARCHIVER_LDAP_BIND_DN"),
+		ldapBindPassword: getenv("AUTO_ARCHIVER_LDAP_BIND_PASSWORD"),
+		ldapBaseDN:       getenv("AUTO_ARCHIVER_LDAP_BASE_DN"),
+		exemptGroups:     listSetting(getenv, "AUTO_ARCHIVER_LDAP_EXEMPT_GROUPS"),
+
+		exportOptional: listSetting(getenv, "AUTO_ARCHIVER_EXPORT_OPTIONAL"),
+
+		exportGCSBucket:       getenv("AUTO_ARCHIVER_EXPORT_GCS_BUCKET"),
+		exportGCSPrefix:       getenv("AUTO_ARCHIVER_EXPORT_GCS_PREFIX"),
+		exportGCSStorageClass: getenv("AUTO_ARCHIVER_EXPORT_GCS_STORAGE_CLASS"),
+
+		exportAzureContainerURL: getenv("AUTO_ARCHIVER_EXPORT_AZURE_CONTAINER_URL"),
+		exportAzurePrefix:       getenv("AUTO_ARCHIVER_EXPORT_AZURE_PREFIX"),
+		exportAzureSASToken:     getenv("AUTO_ARCHIVER_EXPORT_AZURE_SAS_TOKEN"),
+		exportAzureClientID:     getenv("AUTO_ARCHIVER_EXPORT_AZURE_CLIENT_ID"),
+	}
+
+	c.verbosity, err = strconv.Atoi(getenv("AUTO_ARCHIVER_VERBOSITY"))
+	if err != nil {
+		return nil, fmt.Errorf("can not parse verbosity into an int: %w", err)
+	}
+
+	c.slackDebug, err = boolSetting(getenv, "AUTO_ARCHIVER_SLACK_DEBUG", false)
+	if err != nil {
+		return nil, err
+	}
+
+	if v := getenv("AUTO_ARCHIVER_PLAN_TRUSTED_KEYS"); v != "" {
+		if c.planTrustedKeys, err = loadTrustedKeys(v); err != nil {
+			return nil, err
+		}
+	}
+
+	c.planMaxAge, err = durationSetting(getenv, "AUTO_ARCHIVER_PLAN_MAX_AGE", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if c.planMaxAge <= 0 {
+		return nil, fmt.Errorf("plan max age must be positive, got %s", c.planMaxAge)
+	}
+
+	// The setup wizard chooses the threshold, so it is only a placeholder until setup is done
+	if v := getenv("AUTO_ARCHIVER_ARCHIVE_THRESHOLD"); v != "" || c.setupAdmin == "" {
+		c.archiveThreshold, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse archive threshold into an int: %w", err)
+		}
+	} else {
+		c.archiveThreshold = defaultSetupThreshold
+	}
+
+	if v := getenv("AUTO_ARCHIVER_DEACTIVATED_CREATOR_THRESHOLD"); v != "" {
+		threshold, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse deactivated creator threshold into an int: %w", err)
+		}
+		if threshold < 0 || threshold > c.archiveThreshold {
+			return nil, fmt.Errorf("deactivated creator threshold must be between 0 and the archive threshold, got %d", threshold)
+		}
+		c.deactivatedCreatorThreshold = &threshold
+	}
+
+	c.escalationSteps, err = loadEscalationSteps(getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	c.respectDND, err = boolSetting(getenv, "AUTO_ARCHIVER_RESPECT_DND", false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.archiveApproval, err = boolSetting(getenv, "AUTO_ARCHIVER_ARCHIVE_APPROVAL", false)
+	if err != nil {
+		return nil, err
+	}
+	if c.archiveApproval && c.adminChannel == "" {
+		return nil, fmt.Errorf("AUTO_ARCHIVER_ADMIN_CHANNEL is required when AUTO_ARCHIVER_ARCHIVE_APPROVAL is set")
+	}
+
+	c.twoPersonApprovalMembers, err = intSetting(getenv, "AUTO_ARCHIVER_TWO_PERSON_APPROVAL_MEMBERS", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.twoPersonApprovalMembers < 0 {
+		return nil, fmt.Errorf("two person approval members can not be negative, got %d", c.twoPersonApprovalMembers)
+	}
+
+	c.twoPersonApprovalAgeDays, err = intSetting(getenv, "AUTO_ARCHIVER_TWO_PERSON_APPROVAL_AGE_DAYS", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.twoPersonApprovalAgeDays < 0 {
+		return nil, fmt.Errorf("two person approval age days can not be negative, got %d", c.twoPersonApprovalAgeDays)
+	}
+
+	c.exemptionReminderDays, err = intSetting(getenv, "AUTO_ARCHIVER_EXEMPTION_REMINDER_DAYS", 7)
+	if err != nil {
+		return nil, err
+	}
+	if c.exemptionReminderDays < 0 {
+		return nil, fmt.Errorf("exemption reminder days can not be negative, got %d", c.exemptionReminderDays)
+	}
+
+	c.recreationWindowDays, err = intSetting(getenv, "AUTO_ARCHIVER_RECREATION_WINDOW_DAYS", 30)
+	if err != nil {
+		return nil, err
+	}
+	if c.recreationWindowDays <= 0 {
+		return nil, fmt.Errorf("recreation window days must be positive, got %d", c.recreationWindowDays)
+	}
+
+	c.unarchiveExemptionDays, err = intSetting(getenv, "AUTO_ARCHIVER_UNARCHIVE_EXEMPTION_DAYS", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.unarchiveExemptionDays < 0 {
+		return nil, fmt.Errorf("unarchive exemption days can not be negative, got %d", c.unarchiveExemptionDays)
+	}
+
+	c.maxScanIntervalDays, err = intSetting(getenv, "AUTO_ARCHIVER_MAX_SCAN_INTERVAL_DAYS", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.maxScanIntervalDays < 0 {
+		return nil, fmt.Errorf("max scan interval days can not be negative, got %d", c.maxScanIntervalDays)
+	}
+
+	c.archivesPerMinute, err = intSetting(getenv, "AUTO_ARCHIVER_ARCHIVES_PER_MINUTE", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.archivesPerMinute < 0 {
+		return nil, fmt.Errorf("archives per minute can not be negative, got %d", c.archivesPerMinute)
+	}
+
+	c.maxArchivesPerRun, err = intSetting(getenv, "AUTO_ARCHIVER_MAX_ARCHIVES_PER_RUN", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.maxArchivesPerRun < 0 {
+		return nil, fmt.Errorf("max archives per run can not be negative, got %d", c.maxArchivesPerRun)
+	}
+
+	c.archiveRetryAttempts, err = intSetting(getenv, "AUTO_ARCHIVER_ARCHIVE_RETRY_ATTEMPTS", 3)
+	if err != nil {
+		return nil, err
+	}
+	if c.archiveRetryAttempts < 0 {
+		return nil, fmt.Errorf("archive retry attempts can not be negative, got %d", c.archiveRetryAttempts)
+	}
+
+	c.pinnedExemptions, err = boolSetting(getenv, "AUTO_ARCHIVER_PINNED_EXEMPTIONS", false)
+	if err != nil {
+		return nil, err
+	}
+	if c.pinnedExemptions && c.adminChannel == "" {
+		return nil, fmt.Errorf("AUTO_ARCHIVER_ADMIN_CHANNEL is required when AUTO_ARCHIVER_PINNED_EXEMPTIONS is set")
+	}
+
+	c.archiveWithoutHumanMembers, err = boolSetting(getenv, "AUTO_ARCHIVER_ARCHIVE_WITHOUT_HUMAN_MEMBERS", false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.archiveMembersDeactivated, err = boolSetting(getenv, "AUTO_ARCHIVER_ARCHIVE_MEMBERS_DEACTIVATED", false)
+	if err != nil {
+		return nil, err
+	}
+
+	if v := getenv("AUTO_ARCHIVER_SINGLE_MEMBER_THRESHOLD"); v != "" {
+		threshold, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse single member threshold into an int: %w", err)
+		}
+		if threshold < 0 || threshold > c.archiveThreshold {
+			return nil, fmt.Errorf("single member threshold must be between 0 and the archive threshold, got %d", threshold)
+		}
+		c.singleMemberThreshold = &threshold
+	}
+
+	c.warningDays, err = intSetting(getenv, "AUTO_ARCHIVER_WARNING_DAYS", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.warningDays < 0 || c.warningDays >= c.archiveThreshold {
+		return nil, fmt.Errorf("warning days must be between 0 and the archive threshold, got %d", c.warningDays)
+	}
+
+	if v := getenv("AUTO_ARCHIVER_PRIVATE_ARCHIVE_THRESHOLD"); v != "" {
+		threshold, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse private archive threshold into an int: %w", err)
+		}
+		c.privateArchiveThreshold = &threshold
+	}
+	if v := getenv("AUTO_ARCHIVER_PRIVATE_WARNING_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse private warning days into an int: %w", err)
+		}
+		c.privateWarningDays = &days
+	}
+	if err := c.validatePrivateThresholds(); err != nil {
+		return nil, err
+	}
+
+	stores := 0
+	for _, v := range []string{c.stateFile, c.stateBoltFile, c.statePostgresURL, c.stateRedisURL, c.stateDynamoDBTable, c.stateObjectURL} {
+		if v != "" {
+			stores++
+		}
+	}
+	if stores > 1 {
+		return nil, fmt.Errorf("only one of AUTO_ARCHIVER_STATE_FILE, AUTO_ARCHIVER_STATE_BOLT_FILE, " +
+			"AUTO_ARCHIVER_STATE_POSTGRES_URL, AUTO_ARCHIVER_STATE_REDIS_URL, AUTO_ARCHIVER_STATE_DYNAMODB_TABLE " +
+			"and AUTO_ARCHIVER_STATE_OBJECT_URL can be set")
+	}
+
+	// Slack API methods are appended to the base URL
+	if c.slackAPIURL == "" {
+		c.slackAPIURL = slack.APIURL
+	} else if !strings.HasSuffix(c.slackAPIURL, "/") {
+		c.slackAPIURL += "/"
+	}
+
+	c.httpTimeout, err = durationSetting(getenv, "AUTO_ARCHIVER_HTTP_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if c.httpTimeout <= 0 {
+		return nil, fmt.Errorf("http timeout must be positive, got %s", c.httpTimeout)
+	}
+
+	c.apiCallTimeout, err = durationSetting(getenv, "AUTO_ARCHIVER_API_CALL_TIMEOUT", 2*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiCallTimeout < 0 {
+		return nil, fmt.Errorf("api call timeout can not be negative, got %s", c.apiCallTimeout)
+	}
+
+	c.runTimeout, err = durationSetting(getenv, "AUTO_ARCHIVER_RUN_TIMEOUT", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.runTimeout < 0 {
+		return nil, fmt.Errorf("run timeout can not be negative, got %s", c.runTimeout)
+	}
+
+	if v := getenv("AUTO_ARCHIVER_HTTP_PROXY"); v != "" {
+		if c.httpProxy, err = parseProxyURL(v); err != nil {
+			return nil, err
+		}
+	}
+
+	if v := getenv("AUTO_ARCHIVER_CA_BUNDLE"); v != "" {
+		if c.caCerts, err = loadCABundle(v); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.policyPath == "" {
+		c.policyPath = "auto-archiver.json"
+	}
+
+	if c.stateRedisPrefix == "" {
+		c.stateRedisPrefix = "auto-archiver"
+	}
+
+	for _, name := range c.exportOptional {
+		switch name {
+		case exportTargetLocal, exportTargetGCS, exportTargetAzure:
+		default:
+			return nil, fmt.Errorf("unknown export target %q in AUTO_ARCHIVER_EXPORT_OPTIONAL", name)
+		}
+	}
+
+	c.autoJoin, err = boolSetting(getenv, "AUTO_ARCHIVER_AUTO_JOIN", true)
+	if err != nil {
+		return nil, err
+	}
+
+	c.trackActivity, err = boolSetting(getenv, "AUTO_ARCHIVER_TRACK_ACTIVITY", false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.leaveExemptChannels, err = boolSetting(getenv, "AUTO_ARCHIVER_LEAVE_EXEMPT_CHANNELS", false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.maxJoinsPerRun, err = intSetting(getenv, "AUTO_ARCHIVER_MAX_JOINS_PER_RUN", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.maxJoinsPerRun < 0 {
+		return nil, fmt.Errorf("max joins per run can not be negative, got %d", c.maxJoinsPerRun)
+	}
+
+	c.joinDelay, err = durationSetting(getenv, "AUTO_ARCHIVER_JOIN_DELAY", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	c.reactionWeight, err = floatSetting(getenv, "AUTO_ARCHIVER_REACTION_WEIGHT", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.reactionWeight < 0 {
+		return nil, fmt.Errorf("reaction weight can not be negative, got %v", c.reactionWeight)
+	}
+
+	c.historyPageSize, err = intSetting(getenv, "AUTO_ARCHIVER_HISTORY_PAGE_SIZE", 100)
+	if err != nil {
+		return nil, err
+	}
+	if c.historyPageSize < 1 || c.historyPageSize > 1000 {
+		return nil, fmt.Errorf("history page size must be between 1 and 1000, got %d", c.historyPageSize)
+	}
+
+	c.historyMaxPages, err = intSetting(getenv, "AUTO_ARCHIVER_HISTORY_MAX_PAGES", 1)
+	if err != nil {
+		return nil, err
+	}
+	if c.historyMaxPages < 0 {
+		return nil, fmt.Errorf("history max pages can not be negative, got %d", c.historyMaxPages)
+	}
+
+	c.historyInclusive, err = boolSetting(getenv, "AUTO_ARCHIVER_HISTORY_INCLUSIVE", false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.activityUsers = listSetting(getenv, "AUTO_ARCHIVER_ACTIVITY_USERS")
+	c.ignoredUsers = listSetting(getenv, "AUTO_ARCHIVER_IGNORED_USERS")
+
+	c.countReminders, err = boolSetting(getenv, "AUTO_ARCHIVER_COUNT_REMINDERS", true)
+	if err != nil {
+		return nil, err
+	}
+
+	c.countWorkflows, err = boolSetting(getenv, "AUTO_ARCHIVER_COUNT_WORKFLOWS", true)
+	if err != nil {
+		return nil, err
+	}
+
+	c.minActivityMessages, err = intSetting(getenv, "AUTO_ARCHIVER_MIN_ACTIVITY_MESSAGES", 1)
+	if err != nil {
+		return nil, err
+	}
+	if c.minActivityMessages < 1 {
+		return nil, fmt.Errorf("min activity messages must be at least 1, got %d", c.minActivityMessages)
+	}
+
+	c.guestWeight, err = floatSetting(getenv, "AUTO_ARCHIVER_GUEST_WEIGHT", 1)
+	if err != nil {
+		return nil, err
+	}
+	if c.guestWeight < 0 || c.guestWeight > 1 {
+		return nil, fmt.Errorf("guest weight must be between 0 and 1, got %v", c.guestWeight)
+	}
+
+	c.externalWeight, err = floatSetting(getenv, "AUTO_ARCHIVER_EXTERNAL_WEIGHT", 1)
+	if err != nil {
+		return nil, err
+	}
+	if c.externalWeight < 0 || c.externalWeight > 1 {
+		return nil, fmt.Errorf("external weight must be between 0 and 1, got %v", c.externalWeight)
+	}
+
+	c.canvasActivity, err = boolSetting(getenv, "AUTO_ARCHIVER_CANVAS_ACTIVITY", false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.channelTags, err = boolSetting(getenv, "AUTO_ARCHIVER_CHANNEL_TAGS", false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.retentionToken = getenv("AUTO_ARCHIVER_RETENTION_TOKEN")
+	c.retentionAction = getenv("AUTO_ARCHIVER_CUSTOM_RETENTION_ACTION")
+	if c.retentionAction == "" {
+		c.retentionAction = retentionExempt
+	}
+	if c.retentionAction != retentionExempt && c.retentionAction != retentionSkipExport {
+		return nil, fmt.Errorf("unknown custom retention action %q", c.retentionAction)
+	}
+
+	c.staleWarnings = getenv("AUTO_ARCHIVER_STALE_WARNINGS")
+	if c.staleWarnings == "" {
+		c.staleWarnings = staleWarningsKeep
+	}
+	if c.staleWarnings != staleWarningsKeep && c.staleWarnings != staleWarningsDelete && c.staleWarnings != staleWarningsUpdate {
+		return nil, fmt.Errorf("unknown stale warnings handling %q", c.staleWarnings)
+	}
+	c.countdownWarnings, err = boolSetting(getenv, "AUTO_ARCHIVER_COUNTDOWN_WARNINGS", false)
+	if err != nil {
+		return nil, err
+	}
+	c.nudgeMembers, err = intSetting(getenv, "AUTO_ARCHIVER_NUDGE_MEMBERS", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.nudgeMembers < 0 {
+		return nil, fmt.Errorf("nudge members can not be negative, got %d", c.nudgeMembers)
+	}
+
+	c.warningMention = getenv("AUTO_ARCHIVER_WARNING_MENTION")
+	if c.warningMention == "" {
+		c.warningMention = mentionNone
+	}
+	if c.warningMention != mentionNone && c.warningMention != mentionHere && c.warningMention != mentionChannel {
+		return nil, fmt.Errorf("unknown warning mention %q", c.warningMention)
+	}
+	c.warningMentionMaxMembers, err = intSetting(getenv, "AUTO_ARCHIVER_WARNING_MENTION_MAX_MEMBERS", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.warningMentionMaxMembers < 0 {
+		return nil, fmt.Errorf("warning mention max members can not be negative, got %d", c.warningMentionMaxMembers)
+	}
+
+	c.adminToken = getenv("AUTO_ARCHIVER_ADMIN_TOKEN")
+	c.adminFallback, err = boolSetting(getenv, "AUTO_ARCHIVER_ADMIN_FALLBACK", false)
+	if err != nil {
+		return nil, err
+	}
+	if c.adminFallback && c.adminToken == "" {
+		return nil, fmt.Errorf("AUTO_ARCHIVER_ADMIN_TOKEN is required when AUTO_ARCHIVER_ADMIN_FALLBACK is set")
+	}
+	c.bulkArchiveMinChannels, err = intSetting(getenv, "AUTO_ARCHIVER_BULK_ARCHIVE_MIN_CHANNELS", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.bulkArchiveMinChannels < 0 {
+		return nil, fmt.Errorf("bulk archive min channels can not be negative, got %d", c.bulkArchiveMinChannels)
+	}
+	if c.bulkArchiveMinChannels > 0 && c.adminToken == "" {
+		return nil, fmt.Errorf("AUTO_ARCHIVER_ADMIN_TOKEN is required when AUTO_ARCHIVER_BULK_ARCHIVE_MIN_CHANNELS is set")
+	}
+	c.archiveUserToken = getenv("AUTO_ARCHIVER_ARCHIVE_USER_TOKEN")
+
+	if window := getenv("AUTO_ARCHIVER_ARCHIVE_WINDOW"); window != "" {
+		location := time.UTC
+		if name := getenv("AUTO_ARCHIVER_ARCHIVE_WINDOW_TIMEZONE"); name != "" {
+			if location, err = time.LoadLocation(name); err != nil {
+				return nil, fmt.Errorf("can not load archive window time zone: %w", err)
+			}
+		}
+		if c.archiveWindow, err = parseArchiveWindow(window, location); err != nil {
+			return nil, err
+		}
+	}
+
+	c.workspaces = listSetting(getenv, "AUTO_ARCHIVER_WORKSPACES")
+	c.orgAdminChannel = getenv("AUTO_ARCHIVER_ORG_ADMIN_CHANNEL")
+	if c.orgAdminChannel != "" && len(c.workspaces) == 0 {
+		return nil, fmt.Errorf("AUTO_ARCHIVER_WORKSPACES is required when AUTO_ARCHIVER_ORG_ADMIN_CHANNEL is set")
+	}
+
+	c.privateChannels = getenv("AUTO_ARCHIVER_PRIVATE_CHANNELS")
+	if c.privateChannels == "" {
+		c.privateChannels = privateIgnore
+	}
+	if c.privateChannels != privateIgnore && c.privateChannels != privateReport && c.privateChannels != privateArchive {
+		return nil, fmt.Errorf("unknown private channels handling %q", c.privateChannels)
+	}
+
+	c.incidents, err = loadIncidentPolicy(getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	c.decisionWebhookTimeout, err = durationSetting(getenv, "AUTO_ARCHIVER_DECISION_WEBHOOK_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	c.exemptionServiceTimeout, err = durationSetting(getenv, "AUTO_ARCHIVER_EXEMPTION_SERVICE_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	c.exemptionServiceCacheTTL, err = durationSetting(getenv, "AUTO_ARCHIVER_EXEMPTION_SERVICE_CACHE_TTL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if c.exemptionServiceCacheTTL < 0 {
+		return nil, fmt.Errorf("exemption service cache TTL can not be negative, got %s", c.exemptionServiceCacheTTL)
+	}
+
+	if v := getenv("AUTO_ARCHIVER_LDAP_URL"); v != "" {
+		c.ldapURL, err = url.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse AUTO_ARCHIVER_LDAP_URL: %w", err)
+		}
+		if c.ldapURL.Scheme != "ldap" && c.ldapURL.Scheme != "ldaps" {
+			return nil, fmt.Errorf("AUTO_ARCHIVER_LDAP_URL must start with ldap:// or ldaps://, got %q", v)
+		}
+		if c.ldapBaseDN == "" {
+			return nil, fmt.Errorf("AUTO_ARCHIVER_LDAP_BASE_DN is required when AUTO_ARCHIVER_LDAP_URL is set")
+		}
+	}
+	if len(c.exemptGroups) > 0 && c.ldapURL == nil {
+		return nil, fmt.Errorf("AUTO_ARCHIVER_LDAP_URL is required when AUTO_ARCHIVER_LDAP_EXEMPT_GROUPS is set")
+	}
+	c.ldapUserAttribute = getenv("AUTO_ARCHIVER_LDAP_USER_ATTRIBUTE")
+	if c.ldapUserAttribute == "" {
+		c.ldapUserAttribute = "mail"
+	}
+	c.ldapTimeout, err = durationSetting(getenv, "AUTO_ARCHIVER_LDAP_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	// Anyone who can reach the trigger could start runs, so it always requires a token
+	if c.triggerAddr != "" && c.triggerToken == "" {
+		return nil, fmt.Errorf("AUTO_ARCHIVER_TRIGGER_TOKEN is required when AUTO_ARCHIVER_TRIGGER_ADDR is set")
+	}
+
+	c.hookTimeout, err = durationSetting(getenv, "AUTO_ARCHIVER_HOOK_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if c.hookTimeout <= 0 {
+		return nil, fmt.Errorf("hook timeout must be positive, got %s", c.hookTimeout)
+	}
+
+	c.daemonInterval, err = durationSetting(getenv, "AUTO_ARCHIVER_DAEMON_INTERVAL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if c.daemonInterval <= 0 {
+		return nil, fmt.Errorf("daemon interval must be positive, got %s", c.daemonInterval)
+	}
+
+	c.exportFiles, err = boolSetting(getenv, "AUTO_ARCHIVER_EXPORT_FILES", false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.exportFilesMaxBytes, err = intSetting(getenv, "AUTO_ARCHIVER_EXPORT_FILES_MAX_BYTES", 100<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	c.notifyCreator, err = boolSetting(getenv, "AUTO_ARCHIVER_NOTIFY_CREATOR", false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Slack keeps notifying as before unless other notifiers are chosen
+	if len(c.notifiers) == 0 {
+		c.notifiers = []string{notifierSlack}
+	}
+	for _, name := range c.notifiers {
+		switch name {
+		case notifierSlack:
+		case notifierWebhook:
+			if c.notifyWebhookURL == "" {
+				return nil, fmt.Errorf("the %s notifier requires AUTO_ARCHIVER_NOTIFY_WEBHOOK_URL", name)
+			}
+		case notifierEmail:
+			if c.notifyEmailSMTPAddr == "" || c.notifyEmailFrom == "" || len(c.notifyEmailTo) == 0 {
+				return nil, fmt.Errorf("the %s notifier requires AUTO_ARCHIVER_NOTIFY_EMAIL_SMTP_ADDR, "+
+					"AUTO_ARCHIVER_NOTIFY_EMAIL_FROM and AUTO_ARCHIVER_NOTIFY_EMAIL_TO", name)
+			}
+		default:
+			return nil, fmt.Errorf("unknown notifier %q", name)
+		}
+	}
+
+	c.namingConventions, err = loadNamingConventions(getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	c.reportGroupDMs, err = boolSetting(getenv, "AUTO_ARCHIVER_REPORT_GROUP_DMS", false)
+	if err != nil {
+		return nil, err
+	}
+
+	if v := getenv("AUTO_ARCHIVER_BIGQUERY_TABLE"); v != "" {
+		c.bigQueryTable, err = parseBigQueryTable(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.resultsURL = getenv("AUTO_ARCHIVER_RESULTS_URL")
+	if c.resultsURL != "" && !strings.HasPrefix(c.resultsURL, "gs://") && !strings.HasPrefix(c.resultsURL, "s3://") {
+		return nil, fmt.Errorf("results URL must be gs://bucket/prefix or s3://bucket/prefix, got %s", c.resultsURL)
+	}
+	c.resultsFormat = getenv("AUTO_ARCHIVER_RESULTS_FORMAT")
+	if c.resultsFormat == "" {
+		c.resultsFormat = resultsCSV
+	}
+	if c.resultsFormat != resultsCSV && c.resultsFormat != resultsParquet {
+		return nil, fmt.Errorf("unknown results format %q", c.resultsFormat)
+	}
+
+	c.datadogAPIKey = getenv("AUTO_ARCHIVER_DATADOG_API_KEY")
+	c.datadogSite = getenv("AUTO_ARCHIVER_DATADOG_SITE")
+	if c.datadogSite == "" {
+		c.datadogSite = "datadoghq.com"
+	}
+
+	c.cloudWatchEMF, err = boolSetting(getenv, "AUTO_ARCHIVER_CLOUDWATCH_EMF", runningInAWS(getenv))
+	if err != nil {
+		return nil, err
+	}
+	c.cloudWatchNamespace = getenv("AUTO_ARCHIVER_CLOUDWATCH_NAMESPACE")
+	if c.cloudWatchNamespace == "" {
+		c.cloudWatchNamespace = "auto-archiver"
+	}
+
+	c.detectDuplicates, err = boolSetting(getenv, "AUTO_ARCHIVER_DETECT_DUPLICATES", false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.duplicateMemberOverlap, err = floatSetting(getenv, "AUTO_ARCHIVER_DUPLICATE_MEMBER_OVERLAP", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	if c.duplicateMemberOverlap <= 0 || c.duplicateMemberOverlap > 1 {
+		return nil, fmt.Errorf("duplicate member overlap must be over 0 and at most 1, got %v", c.duplicateMemberOverlap)
+	}
+
+	c.channelPageSize, err = intSetting(getenv, "AUTO_ARCHIVER_CHANNEL_PAGE_SIZE", 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.channelPageSize < 0 || c.channelPageSize > 1000 {
+		return nil, fmt.Errorf("channel page size must be between 0 and 1000, got %d", c.channelPageSize)
+	}
+	// Assigning channels to shards and finding duplicates both need every channel at once
+	if c.channelPageSize > 0 && len(c.extraBotTokens) > 0 {
+		return nil, fmt.Errorf("AUTO_ARCHIVER_CHANNEL_PAGE_SIZE can not be used with AUTO_ARCHIVER_EXTRA_BOT_TOKENS")
+	}
+	if c.channelPageSize > 0 && c.detectDuplicates {
+		return nil, fmt.Errorf("AUTO_ARCHIVER_CHANNEL_PAGE_SIZE can not be used with AUTO_ARCHIVER_DETECT_DUPLICATES")
+	}
+
+	c.templates, err = newMessageTemplates(getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// loadIncidentPolicy reads the incident channel settings, returning nil when incident handling is disabled
+func loadIncidentPolicy(getenv func(string) string) (*incidentPolicy, error) {
+	days, err := intSetting(getenv, "AUTO_ARCHIVER_INCIDENT_DAYS", 0)
+	if err != nil {
+		return nil, err
+	}
+	if days <= 0 {
+		return nil, nil
+	}
+
+	channelPattern, err := regexpSetting(getenv, "AUTO_ARCHIVER_INCIDENT_CHANNEL_PATTERN", defaultIncidentChannelPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedPattern, err := regexpSetting(getenv, "AUTO_ARCHIVER_INCIDENT_RESOLVED_PATTERN", defaultIncidentResolvedPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	creators := map[string]bool{}
+	for _, id := range listSetting(getenv, "AUTO_ARCHIVER_INCIDENT_CREATORS") {
+		creators[id] = true
+	}
+
+	return &incidentPolicy{
+		days:            days,
+		channelPattern:  channelPattern,
+		creators:        creators,
+		resolvedPattern: resolvedPattern,
+	}, nil
+}
+
+// intSetting parses an optional integer setting, returning def when it is unset
+func intSetting(getenv func(string) string, key string, def int) (int, error) {
+	v := getenv(key)
+	if v == "" {
+		return def, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("can not parse %s into an int: %w", key, err)
+	}
+
+	return i, nil
+}
+
+// floatSetting parses an optional float setting, returning def when it is unset
+func floatSetting(getenv func(string) string, key string, def float64) (float64, error) {
+	v := getenv(key)
+	if v == "" {
+		return def, nil
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("can not parse %s into a float: %w", key, err)
+	}
+
+	return f, nil
+}
+
+// regexpSetting parses an optional regular expression setting, using def when it is unset
+func regexpSetting(getenv func(string) string, key string, def string) (*regexp.Regexp, error) {
+	v := getenv(key)
+	if v == "" {
+		v = def
+	}
+
+	re, err := regexp.Compile(v)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse %s into a regular expression: %w", key, err)
+	}
+
+	return re, nil
+}
+
+// durationSetting parses an optional duration setting such as 30s, returning def when it is unset
+func durationSetting(getenv func(string) string, key string, def time.Duration) (time.Duration, error) {
+	v := getenv(key)
+	if v == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("can not parse %s into a duration: %w", key, err)
+	}
+
+	return d, nil
+}
+
+// boolSetting parses an optional boolean setting, returning def when it is unset
+func boolSetting(getenv func(string) string, key string, def bool) (bool, error) {
+	v := getenv(key)
+	if v == "" {
+		return def, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("can not parse %s into a bool: %w", key, err)
+	}
+
+	return b, nil
+}
+
+// listSetting parses an optional comma separated setting
+func listSetting(getenv func(string) string, key string) []string {
+	values := []string{}
+	for _, v := range strings.Split(getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
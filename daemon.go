@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// daemon runs the archive pass on a schedule and handles Slack interactions over Socket Mode
+type daemon struct {
+	logger logr.Logger
+	cfg    *config
+	api    *slack.Client
+	shards []botShard
+	store  Store
+	socket *socketmode.Client
+
+	// activityAuthors are whose messages count as activity when tracking activity
+	activityAuthors activityAuthors
+	// running is held while a run is in progress, so scheduled and triggered runs never overlap
+	running sync.Mutex
+	// policy is the policy commands are answered with, read again every policyCacheTTL
+	policy cachedPolicy
+}
+
+func newDaemon(logger logr.Logger, cfg *config, shards []botShard, store Store, socketLog *log.Logger) *daemon {
+	// Interactions are handled by the bot token's app, the other shards only share the archive pass
+	api := shards[0].client
+
+	return &daemon{
+		logger: logger,
+		cfg:    cfg,
+		api:    api,
+		shards: shards,
+		store:  store,
+		socket: socketmode.New(api, socketmode.OptionLog(socketLog), socketmode.OptionDialer(newSocketModeDialer(cfg))),
+
+		activityAuthors: newActivityAuthors(cfg),
+	}
+}
+
+// run will run until ctx is done or the Socket Mode connection fails
+func (d *daemon) run(ctx context.Context) error {
+	if d.cfg.trackActivity {
+		if err := d.startActivityTracking(ctx); err != nil {
+			return fmt.Errorf("can not start tracking activity: %w", err)
+		}
+	}
+
+	if d.cfg.setupAdmin != "" {
+		if err := d.promptSetup(ctx); err != nil {
+			return fmt.Errorf("can not send setup wizard: %w", err)
+		}
+	}
+
+	if d.cfg.triggerAddr != "" {
+		go func() {
+			if err := d.serveTrigger(ctx); err != nil {
+				d.logger.Error(err, "failed to serve run triggers", "addr", d.cfg.triggerAddr)
+			}
+		}()
+	}
+
+	go d.schedule(ctx)
+	go d.handleEvents(ctx)
+
+	return d.socket.RunContext(ctx)
+}
+
+// schedule will run the archive pass immediately and then every daemon interval
+func (d *daemon) schedule(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.daemonInterval)
+	defer ticker.Stop()
+
+	for {
+		d.running.Lock()
+		d.runOnce(ctx, d.cfg)
+		d.running.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce will run a single archive pass with cfg, logging and publishing its result
+func (d *daemon) runOnce(ctx context.Context, cfg *config) {
+	result := newRunResult(time.Now())
+	logger := d.logger.WithValues("run_id", result.RunID)
+	if err := run(ctx, logger, cfg, d.shards, d.store, result); err != nil {
+		logger.Error(err, "run failed")
+		result.addError(err)
+	}
+	result.finish(time.Now())
+	result.log(logger)
+	publishRunResult(ctx, logger, cfg, result)
+}
+
+// handleEvents will route each event received over Socket Mode until ctx is done
+func (d *daemon) handleEvents(ctx context.Context) {
+	router := d.newRouter()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt := <-d.socket.Events:
+			router.route(ctx, evt)
+		}
+	}
+}
+
+// newRouter will route auto-archiver's slash commands, shortcuts, buttons and events to their handlers
+func (d *daemon) newRouter() *socketRouter {
+	r := newSocketRouter(d.socket, d.logger)
+	r.use(logSocketEvents(d.logger))
+
+	r.command(statusCommand, d.handleStatusCommand)
+	r.command(tagCommand, d.handleTagCommand, d.requireAuthorized("You are not allowed to tag channels.", func(_ context.Context, e *socketEvent, text string) {
+		e.ack(map[string]any{"response_type": slack.ResponseTypeEphemeral, "text": text})
+	}))
+
+	// Shortcuts are acknowledged first as Slack only waits three seconds
+	r.interaction(exemptShortcutCallbackID, func(ctx context.Context, e *socketEvent) {
+		d.openExemptModal(ctx, e.callback)
+	}, ackFirst)
+	r.interaction(exemptModalCallbackID, func(ctx context.Context, e *socketEvent) {
+		if resp := d.submitExemptModal(ctx, e.callback); resp != nil {
+			e.ack(resp)
+		}
+	}, d.requireAuthorized("You are not allowed to exempt channels.", func(_ context.Context, e *socketEvent, text string) {
+		e.ack(slack.NewErrorsViewSubmissionResponse(map[string]string{exemptReasonBlockID: text}))
+	}))
+	r.interaction(setupActionID, func(ctx context.Context, e *socketEvent) {
+		d.openSetupModal(ctx, e.callback)
+	}, ackFirst, d.requireAuthorized("You are not allowed to set up auto-archiver.", d.answerEphemeral))
+	r.interaction(setupModalCallbackID, func(ctx context.Context, e *socketEvent) {
+		if resp := d.submitSetupModal(ctx, e.callback); resp != nil {
+			e.ack(resp)
+			return
+		}
+		e.ack()
+
+		// Runs wait for setup, so the first one starts as soon as it is done
+		go func() {
+			d.running.Lock()
+			defer d.running.Unlock()
+			d.runOnce(ctx, d.cfg)
+		}()
+	}, d.requireAuthorized("You are not allowed to set up auto-archiver.", func(_ context.Context, e *socketEvent, text string) {
+		e.ack(slack.NewErrorsViewSubmissionResponse(map[string]string{setupThresholdBlockID: text}))
+	}))
+	r.interaction(editSettingsActionID, func(ctx context.Context, e *socketEvent) {
+		d.openSettingsModal(ctx, e.callback)
+	}, ackFirst, d.requireAuthorized("You are not allowed to change auto-archiver's settings.", d.answerDirect))
+	r.interaction(settingsModalCallbackID, func(ctx context.Context, e *socketEvent) {
+		if resp := d.submitSettingsModal(ctx, e.callback); resp != nil {
+			e.ack(resp)
+		}
+	}, d.requireAuthorized("You are not allowed to change auto-archiver's settings.", func(_ context.Context, e *socketEvent, text string) {
+		e.ack(slack.NewErrorsViewSubmissionResponse(map[string]string{settingsThresholdBlockID: text}))
+	}))
+	// Whoever made an exemption may renew it, so renewing checks authorization itself
+	r.interaction(renewExemptionActionID, func(ctx context.Context, e *socketEvent) {
+		d.renewExemption(ctx, e.callback, e.action)
+	}, ackFirst)
+	// Only the members a nudge was sent to may answer it, which answering checks itself
+	r.interaction(nudgeKeepActionID, func(ctx context.Context, e *socketEvent) {
+		d.answerNudge(ctx, e.callback, e.action)
+	}, ackFirst)
+	r.interaction(nudgeArchiveActionID, func(ctx context.Context, e *socketEvent) {
+		d.answerNudge(ctx, e.callback, e.action)
+	}, ackFirst)
+	r.interaction(approveArchiveActionID, func(ctx context.Context, e *socketEvent) {
+		d.approveArchive(ctx, e.callback, e.action)
+	}, ackFirst, d.requireAuthorized("You are not allowed to approve archiving channels.", d.answerEphemeral))
+
+	r.event(slackevents.AppMention, func(ctx context.Context, e *socketEvent) {
+		if ev, ok := e.event.(*slackevents.AppMentionEvent); ok {
+			d.handleMention(ctx, ev, e.teamID)
+		}
+	})
+	r.event(slackevents.AppHomeOpened, func(ctx context.Context, e *socketEvent) {
+		if ev, ok := e.event.(*slackevents.AppHomeOpenedEvent); ok && ev.Tab == "home" {
+			if err := d.publishHome(ctx, ev.User); err != nil {
+				d.logger.Error(err, "failed to publish app home", "user", ev.User)
+			}
+		}
+	})
+	if d.cfg.trackActivity {
+		r.event(slackevents.Message, func(ctx context.Context, e *socketEvent) {
+			if ev, ok := e.event.(*slackevents.MessageEvent); ok {
+				d.trackMessage(ctx, ev)
+			}
+		})
+	}
+
+	return r
+}
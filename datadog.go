@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	datadogSeriesURL = "https://api.%s/api/v2/series"
+	datadogEventsURL = "https://api.%s/api/v1/events"
+	// datadogMetricCount is the intake type of metrics that count what happened in a run
+	datadogMetricCount = 1
+	// datadogMetricGauge is the intake type of metrics that measure a run
+	datadogMetricGauge = 3
+)
+
+// datadogClient sends run metrics and channel events to the Datadog API, for shops without a statsd agent
+type datadogClient struct {
+	client *http.Client
+	site   string
+	apiKey string
+}
+
+func newDatadogClient(site, apiKey string) *datadogClient {
+	return &datadogClient{client: &http.Client{Timeout: 30 * time.Second}, site: site, apiKey: apiKey}
+}
+
+// datadogSeries is a metric of the v2 series intake
+type datadogSeries struct {
+	Metric string         `json:"metric"`
+	Type   int            `json:"type"`
+	Points []datadogPoint `json:"points"`
+	Tags   []string       `json:"tags"`
+}
+
+type datadogPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+// datadogEvent is an event of the v1 events intake
+type datadogEvent struct {
+	Title          string   `json:"title"`
+	Text           string   `json:"text"`
+	DateHappened   int64    `json:"date_happened"`
+	AlertType      string   `json:"alert_type"`
+	SourceTypeName string   `json:"source_type_name"`
+	AggregationKey string   `json:"aggregation_key"`
+	Tags           []string `json:"tags"`
+}
+
+// datadogTags will return the tags of everything sent for a run
+func datadogTags(r *runResult, dryRun bool) []string {
+	return []string{"workspace:" + r.Workspace, "run_id:" + r.RunID, fmt.Sprintf("dry_run:%t", dryRun)}
+}
+
+// sendRun will send the counts and duration of a finished run as metrics, and a "channel archived" event for each
+// channel it archived
+func (d *datadogClient) sendRun(ctx context.Context, r *runResult, dryRun bool) error {
+	tags := datadogTags(r, dryRun)
+	now := time.Now().Unix()
+	metric := func(name string, typ int, value float64) datadogSeries {
+		return datadogSeries{Metric: "auto_archiver." + name, Type: typ, Points: []datadogPoint{{now, value}}, Tags: tags}
+	}
+
+	series := []datadogSeries{
+		metric("run.duration", datadogMetricGauge, float64(r.DurationMS)/1000),
+		metric("run.errors", datadogMetricCount, float64(r.Counts.Failed+len(r.Errors))),
+		metric("channels.scanned", datadogMetricCount, float64(r.Counts.Scanned)),
+		metric("channels.joined", datadogMetricCount, float64(r.Counts.Joined)),
+		metric("channels.kept", datadogMetricCount, float64(r.Counts.Kept)),
+		metric("channels.warned", datadogMetricCount, float64(r.Counts.Warned)),
+		metric("channels.archived", datadogMetricCount, float64(r.Counts.Archived)),
+		metric("channels.exempt", datadogMetricCount, float64(r.Counts.Exempt)),
+		metric("channels.failed", datadogMetricCount, float64(r.Counts.Failed)),
+		metric("channels.report_only", datadogMetricCount, float64(r.Counts.ReportOnly)),
+	}
+	if err := d.post(ctx, datadogSeriesURL, map[string]any{"series": series}); err != nil {
+		return fmt.Errorf("can not send metrics: %w", err)
+	}
+
+	// Dry runs archive nothing, so there are no events to send
+	if dryRun {
+		return nil
+	}
+	for _, c := range r.Channels {
+		if c.Decision != decisionArchive || c.Error != "" || c.ReportOnly {
+			continue
+		}
+
+		text := fmt.Sprintf("auto-archiver archived #%s (%s) after %d days without activity: %s", c.Name, c.ID,
+			c.DaysInactive, c.Reason.Description())
+		event := datadogEvent{
+			Title:          fmt.Sprintf("Channel #%s archived", c.Name),
+			Text:           text,
+			DateHappened:   now,
+			AlertType:      "info",
+			SourceTypeName: "auto-archiver",
+			AggregationKey: r.RunID,
+			Tags:           append(slices.Clip(tags), "channel:"+c.Name, "reason:"+string(c.Reason)),
+		}
+		if err := d.post(ctx, datadogEventsURL, event); err != nil {
+			return fmt.Errorf("can not send event for #%s: %w", c.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// post will send a JSON body to a Datadog intake of the configured site
+func (d *datadogClient) post(ctx context.Context, endpoint string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(endpoint, d.site), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", d.apiKey)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
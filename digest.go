@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// sendAdminDigests will send the run summary to each digest user as a direct message,
+// holding it until their Do Not Disturb ends so it does not notify them while they are away
+func (a *ArchiveSlacker) sendAdminDigests(ctx context.Context, summary summaryMessageData) {
+	if len(a.digestUsers) == 0 {
+		return
+	}
+
+	// Digests always wait for Do Not Disturb to end, whether or not other direct messages do
+	for _, user := range a.digestUsers {
+		if err := a.deliverDirectMessage(ctx, user, a.templates.summary, summary, true); err != nil {
+			a.logger.Error(err, "failed to send admin digest", "user", user)
+		}
+	}
+}
+
+// dndEnd will return when the Do Not Disturb in effect at now ends, or the zero time if none is in effect
+func dndEnd(dnd slack.DNDStatus, now time.Time) time.Time {
+	end := time.Time{}
+
+	if dnd.SnoozeEnabled && dnd.SnoozeEndTime > 0 {
+		if snoozeEnd := time.Unix(int64(dnd.SnoozeEndTime), 0); snoozeEnd.After(now) {
+			end = snoozeEnd
+		}
+	}
+
+	if dnd.Enabled && dnd.NextStartTimestamp > 0 && dnd.NextEndTimestamp > 0 {
+		start := time.Unix(int64(dnd.NextStartTimestamp), 0)
+		scheduledEnd := time.Unix(int64(dnd.NextEndTimestamp), 0)
+		if !now.Before(start) && now.Before(scheduledEnd) && scheduledEnd.After(end) {
+			end = scheduledEnd
+		}
+	}
+
+	return end
+}
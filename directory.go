@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// errNoManager is returned when the directory has no manager for a user
+var errNoManager = errors.New("user has no manager in the directory")
+
+// scimDirectory looks up users' managers in a SCIM 2.0 directory, such as an HR system or identity provider
+type scimDirectory struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+func newSCIMDirectory(baseURL, token string) *scimDirectory {
+	return &scimDirectory{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		baseURL: baseURL,
+		token:   token,
+	}
+}
+
+// scimUser is the part of a SCIM user auto-archiver reads
+type scimUser struct {
+	ID       string `json:"id"`
+	UserName string `json:"userName"`
+	Active   bool   `json:"active"`
+	Emails   []struct {
+		Value   string `json:"value"`
+		Primary bool   `json:"primary"`
+	} `json:"emails"`
+	Enterprise struct {
+		Manager struct {
+			Value string `json:"value"`
+		} `json:"manager"`
+	} `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`
+}
+
+// email will return the user's primary email, falling back to their user name which is usually their email
+func (u scimUser) email() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+
+	return u.UserName
+}
+
+// managerEmail will return the email of the manager of the user with the given email
+func (d *scimDirectory) managerEmail(ctx context.Context, email string) (string, error) {
+	var list struct {
+		Resources []scimUser `json:"Resources"`
+	}
+	filter := fmt.Sprintf("userName eq %q", email)
+	if err := d.get(ctx, "/Users?filter="+url.QueryEscape(filter), &list); err != nil {
+		return "", err
+	}
+	if len(list.Resources) == 0 {
+		return "", fmt.Errorf("no user %s in the directory", email)
+	}
+
+	managerID := list.Resources[0].Enterprise.Manager.Value
+	if managerID == "" {
+		return "", errNoManager
+	}
+
+	var manager scimUser
+	if err := d.get(ctx, "/Users/"+url.PathEscape(managerID), &manager); err != nil {
+		return "", err
+	}
+
+	return manager.email(), nil
+}
+
+// get will GET a path of the SCIM API and decode the response into v
+func (d *scimDirectory) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/scim+json")
+	if d.token != "" {
+		req.Header.Set("Authorization", "Bearer "+d.token)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("directory returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// escalateToManager will send the manager of a deactivated channel creator a direct message about the warning
+func (a *ArchiveSlacker) escalateToManager(ctx context.Context, creatorID string, data channelMessageData) error {
+	creator, err := a.getUser(ctx, creatorID)
+	if err != nil {
+		return err
+	}
+	if !creator.Deleted {
+		return nil
+	}
+	if creator.Profile.Email == "" {
+		return fmt.Errorf("creator has no email, which needs the users:read.email scope")
+	}
+
+	managerEmail, err := a.directory.managerEmail(ctx, creator.Profile.Email)
+	if err != nil {
+		return err
+	}
+
+	manager, err := a.client.GetUserByEmailContext(ctx, managerEmail)
+	if err != nil {
+		return fmt.Errorf("can not find manager %s in slack: %w", managerEmail, err)
+	}
+
+	a.logger.V(1).Info("escalating warning to creator's manager", "channel", data.Channel.Name, "manager", manager.ID)
+	return a.sendDirectMessage(ctx, manager.ID, a.templates.escalation, data)
+}
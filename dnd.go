@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// userDNDEnd will return when a user's Do Not Disturb ends if it is on at now, either snoozed or in their scheduled
+// hours, or the zero time if it is off. It needs the dnd:read scope.
+func (a *ArchiveSlacker) userDNDEnd(ctx context.Context, userID string, now time.Time) (time.Time, error) {
+	status, err := a.client.GetDNDInfoContext(ctx, &userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return dndEnd(*status, now), nil
+}
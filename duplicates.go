@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// duplicateNameSimilarity is how similar two normalized channel names must be to be likely duplicates
+	duplicateNameSimilarity = 0.8
+	// duplicateMinContainedName is the shortest normalized name that counts as a duplicate of a name containing it
+	duplicateMinContainedName = 5
+)
+
+// duplicateChannel is a dormant channel that likely duplicates a more active one
+type duplicateChannel struct {
+	Dormant slack.Channel
+	Active  slack.Channel
+	// Overlap is the percentage of the smaller channel's members that are in both
+	Overlap      int
+	DaysInactive int
+}
+
+// duplicateCandidate is a channel that was kept or warned this run
+type duplicateCandidate struct {
+	channel      slack.Channel
+	slacker      *ArchiveSlacker
+	daysInactive int
+	dormant      bool
+	name         string
+}
+
+// findDuplicateChannels will find channels with similar names and mostly the same members, at least one of which
+// is dormant, so they can be suggested for merging. Channels archived, exempt or failed this run are left out.
+// Members are only compared for channels with similar names, as each channel's members cost an API call.
+func findDuplicateChannels(ctx context.Context, channels []slack.Channel, slackerFor map[string]*ArchiveSlacker,
+	result *runResult, threshold int, minOverlap float64, botUsers map[string]bool) []duplicateChannel {
+	evaluated := map[string]channelResult{}
+	for _, r := range result.Channels {
+		evaluated[r.ID] = r
+	}
+
+	candidates := []duplicateCandidate{}
+	for _, c := range channels {
+		r, ok := evaluated[c.ID]
+		if !ok || r.Error != "" || (r.Decision != decisionKeep && r.Decision != decisionWarn) {
+			continue
+		}
+
+		candidates = append(candidates, duplicateCandidate{
+			channel:      c,
+			slacker:      slackerFor[c.ID],
+			daysInactive: r.DaysInactive,
+			dormant:      r.Decision == decisionWarn || r.DaysInactive >= threshold/2,
+			name:         normalizeChannelName(c.Name),
+		})
+	}
+
+	members := map[string]map[string]bool{}
+	getMembers := func(c duplicateCandidate) (map[string]bool, error) {
+		if m, ok := members[c.channel.ID]; ok {
+			return m, nil
+		}
+
+		m, err := c.slacker.getHumanMembers(ctx, c.channel.ID, botUsers)
+		if err != nil {
+			return nil, err
+		}
+		members[c.channel.ID] = m
+		return m, nil
+	}
+
+	// Each dormant channel is suggested once, for the channel it overlaps most with
+	best := map[string]duplicateChannel{}
+	for i, a := range candidates {
+		for _, b := range candidates[i+1:] {
+			if !a.dormant && !b.dormant {
+				continue
+			}
+			if !similarChannelNames(a.name, b.name) {
+				continue
+			}
+
+			membersA, err := getMembers(a)
+			if err != nil {
+				a.slacker.logger.Error(err, "failed to get channel members", "channel", a.channel.Name)
+				continue
+			}
+			membersB, err := getMembers(b)
+			if err != nil {
+				b.slacker.logger.Error(err, "failed to get channel members", "channel", b.channel.Name)
+				continue
+			}
+
+			overlap := memberOverlap(membersA, membersB)
+			if overlap < minOverlap {
+				continue
+			}
+
+			// The more recently active channel is the one to keep
+			dormant, active := a, b
+			if !a.dormant || (b.dormant && b.daysInactive > a.daysInactive) {
+				dormant, active = b, a
+			}
+
+			d := duplicateChannel{
+				Dormant:      dormant.channel,
+				Active:       active.channel,
+				Overlap:      int(overlap * 100),
+				DaysInactive: dormant.daysInactive,
+			}
+			if existing, ok := best[dormant.channel.ID]; !ok || d.Overlap > existing.Overlap {
+				best[dormant.channel.ID] = d
+			}
+		}
+	}
+
+	duplicates := []duplicateChannel{}
+	for _, d := range best {
+		duplicates = append(duplicates, d)
+	}
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i].Dormant.Name < duplicates[j].Dormant.Name })
+
+	return duplicates
+}
+
+// getHumanMembers will get the members of a channel, leaving out the given bot users
+func (a *ArchiveSlacker) getHumanMembers(ctx context.Context, channelID string, botUsers map[string]bool) (map[string]bool, error) {
+	members := map[string]bool{}
+	err := a.forEachMember(ctx, channelID, func(userID string) (bool, error) {
+		if !botUsers[userID] {
+			members[userID] = true
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// normalizeChannelName will lower case a channel name and drop its separators, so team-design and teamdesign match
+func normalizeChannelName(name string) string {
+	return strings.NewReplacer("-", "", "_", "", ".", "").Replace(strings.ToLower(name))
+}
+
+// similarChannelNames will report whether two normalized channel names are likely names for the same thing:
+// one contains the other or they are only a few edits apart
+func similarChannelNames(a, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(a) >= duplicateMinContainedName && strings.Contains(b, a) {
+		return true
+	}
+
+	// Names whose lengths differ too much can never be similar enough, which skips most pairs cheaply
+	if float64(len(b)-len(a)) > float64(len(b))*(1-duplicateNameSimilarity) {
+		return false
+	}
+
+	return 1-float64(editDistance(a, b))/float64(len(b)) >= duplicateNameSimilarity
+}
+
+// editDistance will return the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+// memberOverlap will return the share of the smaller channel's members that are in both channels
+func memberOverlap(a, b map[string]bool) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(a) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for id := range a {
+		if b[id] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(a))
+}
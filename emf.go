@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// emfMetric is a metric definition of an EMF log line
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// runningInAWS will report whether auto-archiver runs in Lambda or ECS, whose logs CloudWatch can extract EMF
+// metrics from
+func runningInAWS(getenv func(string) string) bool {
+	return getenv("AWS_LAMBDA_FUNCTION_NAME") != "" || getenv("ECS_CONTAINER_METADATA_URI_V4") != ""
+}
+
+// writeEMF will write the metrics of a finished run as a single CloudWatch Embedded Metric Format log line, so they
+// land in CloudWatch Metrics without an agent. The metrics have the workspace as their dimension, and the run ID is
+// a property of the line to find its logs by.
+func writeEMF(w io.Writer, namespace string, r *runResult, dryRun bool) error {
+	values := []struct {
+		name  string
+		unit  string
+		value any
+	}{
+		{"DurationMS", "Milliseconds", r.DurationMS},
+		{"Errors", "Count", r.Counts.Failed + len(r.Errors)},
+		{"Scanned", "Count", r.Counts.Scanned},
+		{"Joined", "Count", r.Counts.Joined},
+		{"Kept", "Count", r.Counts.Kept},
+		{"Warned", "Count", r.Counts.Warned},
+		{"Archived", "Count", r.Counts.Archived},
+		{"Exempt", "Count", r.Counts.Exempt},
+		{"Failed", "Count", r.Counts.Failed},
+		{"ReportOnly", "Count", r.Counts.ReportOnly},
+	}
+
+	line := map[string]any{"Workspace": r.Workspace, "RunID": r.RunID, "DryRun": dryRun}
+	metrics := make([]emfMetric, 0, len(values))
+	for _, v := range values {
+		metrics = append(metrics, emfMetric{Name: v.name, Unit: v.unit})
+		line[v.name] = v.value
+	}
+	line["_aws"] = map[string]any{
+		"Timestamp": r.StartedAt.UnixMilli(),
+		"CloudWatchMetrics": []map[string]any{{
+			"Namespace":  namespace,
+			"Dimensions": [][]string{{"Workspace"}},
+			"Metrics":    metrics,
+		}},
+	}
+
+	return json.NewEncoder(w).Encode(line)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+
+	"github.com/slack-go/slack"
+)
+
+// Classes of errors that are not Slack API errors, which are classified by their Slack error code
+const (
+	errorClassRateLimited = "rate_limited"
+	errorClassTimeout     = "timeout"
+	errorClassNetwork     = "network"
+	errorClassOther       = "other"
+)
+
+// classifyError will return the class of an error for the end of run summary: the error code of Slack API errors
+// such as missing_scope or restricted_action, or one of the errorClass constants
+func classifyError(err error) string {
+	var slackErr slack.SlackErrorResponse
+	var rateLimitErr *slack.RateLimitedError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &slackErr) && slackErr.Err != "":
+		return slackErr.Err
+	case errors.As(err, &rateLimitErr):
+		return errorClassRateLimited
+	case errors.Is(err, context.DeadlineExceeded):
+		return errorClassTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return errorClassTimeout
+	case errors.As(err, &netErr):
+		return errorClassNetwork
+	default:
+		return errorClassOther
+	}
+}
+
+// errorClassCount is how many errors of a class a run had
+type errorClassCount struct {
+	Class string `json:"class"`
+	Count int    `json:"count"`
+}
+
+// sortedErrorClasses will return the counts of each error class, the most frequent first
+func sortedErrorClasses(classes map[string]int) []errorClassCount {
+	counts := make([]errorClassCount, 0, len(classes))
+	for class, count := range classes {
+		counts = append(counts, errorClassCount{Class: class, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Class < counts[j].Class
+	})
+
+	return counts
+}
+
+// transientErrorClasses are the error classes that may succeed when tried again later
+var transientErrorClasses = map[string]bool{
+	errorClassRateLimited: true,
+	errorClassTimeout:     true,
+	errorClassNetwork:     true,
+	"ratelimited":         true,
+	"internal_error":      true,
+	"fatal_error":         true,
+	"service_unavailable": true,
+	"request_timeout":     true,
+}
+
+// isTransientError will report whether err may not happen again when the action is retried
+func isTransientError(err error) bool {
+	return transientErrorClasses[classifyError(err)]
+}
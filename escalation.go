@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bucketEscalations holds how far along the escalation chain each warned channel is, by channel ID
+const bucketEscalations = "escalations"
+
+// Steps of the escalation chain. The channel is always warned first, the other steps are configured with
+// AUTO_ARCHIVER_ESCALATION_STEPS.
+const (
+	escalationChannel = "channel"
+	escalationOwner   = "owner"
+	escalationAdmins  = "admins"
+	// escalationArchive is how long to wait after the last step before archiving
+	escalationArchive = "archive"
+)
+
+// escalationStep is a step of the escalation chain, taken days after the previous step
+type escalationStep struct {
+	name string
+	days int
+}
+
+// escalation is how far along the escalation chain a channel is
+type escalation struct {
+	ChannelID   string `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	// Taken are when each step was taken, by step name
+	Taken map[string]time.Time `json:"taken"`
+}
+
+// loadEscalationSteps will read the escalation chain from AUTO_ARCHIVER_ESCALATION_STEPS as comma separated
+// <step>=<days> entries, days being counted from the previous step. The chain always starts by warning the channel.
+func loadEscalationSteps(getenv func(string) string) ([]escalationStep, error) {
+	entries := listSetting(getenv, "AUTO_ARCHIVER_ESCALATION_STEPS")
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	steps := []escalationStep{{name: escalationChannel}}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		name, days, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("escalation step %q must be <step>=<days>", entry)
+		}
+
+		switch name {
+		case escalationOwner, escalationAdmins, escalationArchive:
+		default:
+			return nil, fmt.Errorf("unknown escalation step %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("escalation step %q is set more than once", name)
+		}
+		if seen[escalationArchive] {
+			return nil, errors.New("the archive escalation step must be the last")
+		}
+		seen[name] = true
+
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse days of escalation step %s into an int: %w", name, err)
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("days of escalation step %s can not be negative, got %d", name, n)
+		}
+
+		steps = append(steps, escalationStep{name: name, days: n})
+	}
+
+	return steps, nil
+}
+
+// next will return the step due at now, if any, and whether every step has been taken and the channel may be
+// archived. Each step is only due once the previous step was taken and its days have passed since.
+func (e *escalation) next(steps []escalationStep, now time.Time) (escalationStep, bool, bool) {
+	var prev time.Time
+	for _, step := range steps {
+		if taken, ok := e.Taken[step.name]; ok {
+			prev = taken
+			continue
+		}
+
+		if !prev.IsZero() && now.Before(prev.AddDate(0, 0, step.days)) {
+			return escalationStep{}, false, false
+		}
+		// Waiting after the last step is not an action of its own
+		if step.name == escalationArchive {
+			return escalationStep{}, false, true
+		}
+
+		return step, true, false
+	}
+
+	return escalationStep{}, false, true
+}
+
+// escalationChainDuration will return how long taking every step of the escalation chain takes at least
+func escalationChainDuration(steps []escalationStep) time.Duration {
+	days := 0
+	for _, step := range steps {
+		days += step.days
+	}
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// listEscalations will return every stored escalation by channel ID
+func listEscalations(ctx context.Context, store Store) (map[string]*escalation, error) {
+	values, err := store.List(ctx, bucketEscalations)
+	if err != nil {
+		return nil, err
+	}
+
+	escalations := map[string]*escalation{}
+	for key, value := range values {
+		e := &escalation{}
+		if err := json.Unmarshal(value, e); err != nil {
+			return nil, fmt.Errorf("can not decode %s/%s: %w", bucketEscalations, key, err)
+		}
+		escalations[key] = e
+	}
+
+	return escalations, nil
+}
+
+// escalate will take the next step of the escalation chain of an inactive channel if one is due, recording it,
+// and report whether every step has been taken so the channel may be archived. In dry runs the step is only logged.
+func (a *ArchiveSlacker) escalate(ctx context.Context, c inactiveChannel, e *escalation, now time.Time) (bool, error) {
+	step, due, done := e.next(a.escalationSteps, now)
+	if !due {
+		return done, nil
+	}
+
+	logger := a.logger.WithValues("channel", c.channel.Name, "step", step.name)
+	if a.dryRun {
+		logger.Info("would escalate channel")
+		return false, nil
+	}
+
+	logger.Info("escalating channel")
+	data := a.messageData(c)
+	switch step.name {
+	case escalationChannel:
+		if err := a.warnChannel(ctx, c); err != nil {
+			return false, err
+		}
+	case escalationOwner:
+		owners, err := a.channelOwners(ctx, c.channel)
+		if err != nil {
+			return false, fmt.Errorf("can not get channel owners: %w", err)
+		}
+		for _, owner := range owners {
+			if err := a.sendDirectMessage(ctx, owner, a.templates.ownerEscalation, data); err != nil {
+				logger.Error(err, "failed to escalate to channel owner", "owner", owner)
+			}
+		}
+	case escalationAdmins:
+		if a.adminChannel != "" {
+			if err := a.postMessage(ctx, a.adminChannel, a.templates.adminEscalation, data); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	e.ChannelID = c.channel.ID
+	e.ChannelName = c.channel.Name
+	if e.Taken == nil {
+		e.Taken = map[string]time.Time{}
+	}
+	e.Taken[step.name] = now
+	// Escalations are kept until a grace period after the whole chain could have been taken from now
+	if err := putJSON(ctx, a.store, bucketEscalations, c.channel.ID, e, escalationChainDuration(a.escalationSteps)+stateGracePeriod); err != nil {
+		return false, fmt.Errorf("can not record escalation: %w", err)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// bucketExemptionService caches the answers of the exemption service per channel ID
+	bucketExemptionService = "exemption_service"
+	// exemptionServiceExemptedBy is who exemptions from the exemption service are recorded as exempted by
+	exemptionServiceExemptedBy = "exemption-service"
+)
+
+// exemptionService asks a company's channel governance service whether each archive candidate is exempt, so
+// exemptions kept there do not have to be synced into auto-archiver
+type exemptionService struct {
+	client *http.Client
+	url    string
+	token  string
+	// cacheTTL is how long answers are cached, in the state store when there is one
+	cacheTTL time.Duration
+	store    Store
+
+	// cache holds the answers of this run when there is no state store
+	cache map[string]exemptionServiceAnswer
+	mu    sync.Mutex
+}
+
+func newExemptionService(baseURL, token string, timeout, cacheTTL time.Duration, store Store) *exemptionService {
+	return &exemptionService{
+		client:   &http.Client{Timeout: timeout},
+		url:      strings.TrimSuffix(baseURL, "/") + "/exempt",
+		token:    token,
+		cacheTTL: cacheTTL,
+		store:    store,
+		cache:    map[string]exemptionServiceAnswer{},
+	}
+}
+
+// exemptionServiceAnswer is the body the exemption service replies with
+type exemptionServiceAnswer struct {
+	Exempt bool   `json:"exempt"`
+	Reason string `json:"reason"`
+	// Until is when the exemption ends, unset for a permanent exemption
+	Until time.Time `json:"until,omitempty"`
+}
+
+// exemption will return the service's exemption of a channel in effect at now, or nil if it has none
+func (s *exemptionService) exemption(ctx context.Context, c slack.Channel, now time.Time) (*exemption, error) {
+	answer, err := s.lookup(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &exemption{ChannelID: c.ID, ChannelName: c.Name, Until: answer.Until, Reason: answer.Reason, ExemptedBy: exemptionServiceExemptedBy}
+	if !answer.Exempt || !e.activeAt(now) {
+		return nil, nil
+	}
+
+	return e, nil
+}
+
+// lookup will return the service's answer for a channel, from the cache when it was asked recently
+func (s *exemptionService) lookup(ctx context.Context, channelID string) (exemptionServiceAnswer, error) {
+	var answer exemptionServiceAnswer
+	if s.cacheTTL > 0 {
+		if s.store != nil {
+			err := getJSON(ctx, s.store, bucketExemptionService, channelID, &answer)
+			if err == nil {
+				return answer, nil
+			}
+			if !errors.Is(err, errNotFound) {
+				return answer, fmt.Errorf("can not get cached exemption service answer: %w", err)
+			}
+		} else {
+			s.mu.Lock()
+			cached, ok := s.cache[channelID]
+			s.mu.Unlock()
+			if ok {
+				return cached, nil
+			}
+		}
+	}
+
+	answer, err := s.ask(ctx, channelID)
+	if err != nil {
+		return answer, err
+	}
+
+	if s.cacheTTL > 0 {
+		if s.store != nil {
+			if err := putJSON(ctx, s.store, bucketExemptionService, channelID, answer, s.cacheTTL); err != nil {
+				return answer, fmt.Errorf("can not cache exemption service answer: %w", err)
+			}
+		} else {
+			s.mu.Lock()
+			s.cache[channelID] = answer
+			s.mu.Unlock()
+		}
+	}
+
+	return answer, nil
+}
+
+// ask will ask the exemption service whether a channel is exempt
+func (s *exemptionService) ask(ctx context.Context, channelID string) (exemptionServiceAnswer, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"?"+url.Values{"channel_id": {channelID}}.Encode(), nil)
+	if err != nil {
+		return exemptionServiceAnswer{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return exemptionServiceAnswer{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return exemptionServiceAnswer{}, fmt.Errorf("exemption service returned %s: %s", resp.Status, msg)
+	}
+
+	var answer exemptionServiceAnswer
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return exemptionServiceAnswer{}, fmt.Errorf("can not decode exemption service response: %w", err)
+	}
+
+	return answer, nil
+}
+
+// checkCandidateExemptions will ask the exemption service and the group directory about a channel that would be
+// warned or archived, returning the evaluation as exempt if either exempts it. Only candidates are looked up, so
+// neither is asked about every channel of the workspace.
+func (a *ArchiveSlacker) checkCandidateExemptions(ctx context.Context, c slack.Channel, e channelEvaluation, now time.Time) (channelEvaluation, error) {
+	if e.decision != decisionWarn && e.decision != decisionArchive {
+		return e, nil
+	}
+
+	if a.exemptionService != nil {
+		exempt, err := a.exemptionService.exemption(ctx, c, now)
+		if err != nil {
+			return channelEvaluation{}, fmt.Errorf("could not check exemption service: %w", err)
+		}
+		if exempt != nil {
+			return channelEvaluation{decision: decisionExempt, exemption: exempt}, nil
+		}
+	}
+
+	if a.groupDirectory != nil {
+		exempt, err := a.ownerGroupExemption(ctx, c)
+		if err != nil {
+			return channelEvaluation{}, fmt.Errorf("could not check owner groups: %w", err)
+		}
+		if exempt != nil {
+			return channelEvaluation{decision: decisionExempt, exemption: exempt}, nil
+		}
+	}
+
+	return e, nil
+}
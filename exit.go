@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/slack-go/slack"
+)
+
+// Exit codes returned by auto-archiver
+const (
+	exitOK = 0
+	// exitPartialFailure means the run finished but acting on or evaluating some channels failed
+	exitPartialFailure = 1
+	exitUsage          = 2
+	exitConfig         = 3
+	exitAuth           = 4
+	// exitRunFailed means the run stopped before it finished
+	exitRunFailed = 5
+)
+
+// authErrors are the Slack API errors caused by a missing, invalid or revoked token
+var authErrors = map[string]bool{
+	"not_authed":       true,
+	"invalid_auth":     true,
+	"account_inactive": true,
+	"token_revoked":    true,
+	"token_expired":    true,
+}
+
+// isAuthError will report whether err was caused by the Slack token
+func isAuthError(err error) bool {
+	var slackErr slack.SlackErrorResponse
+	return errors.As(err, &slackErr) && authErrors[slackErr.Err]
+}
+
+// exitCode will return the exit code for a finished run. In strict mode any failed channel is a failure,
+// otherwise only errors that stopped the run are.
+func exitCode(result *runResult, runErr error, strict bool) int {
+	switch {
+	case runErr != nil && isAuthError(runErr):
+		return exitAuth
+	case runErr != nil:
+		return exitRunFailed
+	case strict && result.Counts.Failed > 0:
+		return exitPartialFailure
+	default:
+		return exitOK
+	}
+}
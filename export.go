@@ -0,0 +1,366 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/slack-go/slack"
+)
+
+// exportDayLayout is the layout of the per-day message files in a Slack export
+const exportDayLayout = "2006-01-02"
+
+// Names of the export targets, used to mark targets optional with AUTO_ARCHIVER_EXPORT_OPTIONAL
+const (
+	exportTargetLocal = "local"
+	exportTargetGCS   = "gcs"
+	exportTargetAzure = "azure"
+)
+
+// Exporter is a destination channel backups are written to
+type Exporter interface {
+	// Write will store data under name, which may contain slashes, tagged with metadata where the
+	// target supports it, and return where it was written to
+	Write(ctx context.Context, name string, data []byte, metadata map[string]string) (string, error)
+}
+
+// exportTarget is a configured Exporter, the name it is configured by and whether a channel may be
+// archived when writing to it fails
+type exportTarget struct {
+	name     string
+	optional bool
+	Exporter
+}
+
+// multiExporter writes every backup to each of its targets
+type multiExporter struct {
+	logger  logr.Logger
+	targets []exportTarget
+}
+
+// Write will write to every target, returning the locations written to. Failing to write to a target
+// fails the write unless the target is optional, so the channel is not archived without its backup.
+func (m *multiExporter) Write(ctx context.Context, name string, data []byte, metadata map[string]string) (string, error) {
+	locations := []string{}
+	for _, t := range m.targets {
+		location, err := t.Write(ctx, name, data, metadata)
+		if err != nil {
+			if !t.optional {
+				return "", fmt.Errorf("can not write to %s: %w", t.name, err)
+			}
+			m.logger.Error(err, "failed to write to optional export target", "target", t.name, "name", name)
+			continue
+		}
+		locations = append(locations, location)
+	}
+
+	return strings.Join(locations, ", "), nil
+}
+
+// channelExporter backs up a channel as a ZIP matching Slack's standard export layout before it is archived
+type channelExporter struct {
+	logger logr.Logger
+	client *slack.Client
+	target Exporter
+
+	// files enables downloading files shared in the channel, skipping any larger than maxFileBytes
+	files        bool
+	maxFileBytes int
+
+	// users is the workspace user list, fetched once on the first export of a run
+	users []slack.User
+	// runID is the ID of the run, added to the name and metadata of exports so they can be traced back to it
+	runID string
+}
+
+// newExporter will create an exporter writing to every configured export target, or return nil if exporting
+// is disabled
+func newExporter(ctx context.Context, logger logr.Logger, cfg *config) (Exporter, error) {
+	optional := map[string]bool{}
+	for _, name := range cfg.exportOptional {
+		optional[name] = true
+	}
+
+	m := &multiExporter{logger: logger}
+	if cfg.exportDir != "" {
+		m.targets = append(m.targets, exportTarget{
+			name:     exportTargetLocal,
+			optional: optional[exportTargetLocal],
+			Exporter: &localTarget{dir: cfg.exportDir},
+		})
+	}
+	if cfg.exportGCSBucket != "" {
+		target, err := newGCSTarget(ctx, cfg.exportGCSBucket, cfg.exportGCSPrefix, cfg.exportGCSStorageClass)
+		if err != nil {
+			return nil, err
+		}
+		m.targets = append(m.targets, exportTarget{name: exportTargetGCS, optional: optional[exportTargetGCS], Exporter: target})
+	}
+	if cfg.exportAzureContainerURL != "" {
+		target := newAzureTarget(cfg.exportAzureContainerURL, cfg.exportAzurePrefix, cfg.exportAzureSASToken, cfg.exportAzureClientID)
+		m.targets = append(m.targets, exportTarget{name: exportTargetAzure, optional: optional[exportTargetAzure], Exporter: target})
+	}
+
+	if len(m.targets) == 0 {
+		return nil, nil
+	}
+
+	return m, nil
+}
+
+func newChannelExporter(logger logr.Logger, client *slack.Client, target Exporter, cfg *config, runID string) *channelExporter {
+	return &channelExporter{
+		logger:       logger,
+		client:       client,
+		target:       target,
+		files:        cfg.exportFiles,
+		maxFileBytes: cfg.exportFilesMaxBytes,
+		runID:        runID,
+	}
+}
+
+// exportChannel will write the full history of a channel to a Slack export ZIP in the export target
+func (e *channelExporter) exportChannel(ctx context.Context, c slack.Channel, now time.Time) (string, error) {
+	messages, err := e.getHistory(ctx, c)
+	if err != nil {
+		return "", fmt.Errorf("can not get channel history: %w", err)
+	}
+
+	if e.users == nil {
+		e.users, err = e.client.GetUsersContext(ctx)
+		if err != nil {
+			return "", fmt.Errorf("can not get users: %w", err)
+		}
+	}
+
+	data, err := buildSlackExport(c, e.users, messages)
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%s-%s", c.Name, now.Format(exportDayLayout), e.runID)
+	metadata := map[string]string{
+		"channel-id":   c.ID,
+		"channel-name": c.Name,
+		"archived-by":  "auto-archiver",
+		"run-id":       e.runID,
+	}
+
+	location, err := e.target.Write(ctx, name+".zip", data, metadata)
+	if err != nil {
+		return "", err
+	}
+
+	// Files are stored next to the transcript because Slack file links stop working once retention removes them
+	if e.files {
+		if err := e.exportFiles(ctx, c, name+"-files", messages, metadata); err != nil {
+			return "", fmt.Errorf("can not export files: %w", err)
+		}
+	}
+
+	return location, nil
+}
+
+// exportFiles will download every file shared in messages up to the size cap and write each one under dir.
+// Files that can no longer be downloaded are logged and skipped.
+func (e *channelExporter) exportFiles(ctx context.Context, c slack.Channel, dir string, messages []slack.Message, metadata map[string]string) error {
+	logger := e.logger.V(1).WithValues("channel", c.Name)
+
+	for _, m := range messages {
+		for _, f := range m.Files {
+			// Deleted and hidden files have no download link
+			if f.URLPrivateDownload == "" {
+				logger.Info("skipping file without download link", "file", f.ID, "mode", f.Mode)
+				continue
+			}
+
+			if e.maxFileBytes > 0 && f.Size > e.maxFileBytes {
+				logger.Info("skipping file larger than size cap", "file", f.ID, "size", f.Size)
+				continue
+			}
+
+			var buf bytes.Buffer
+			if err := e.client.GetFileContext(ctx, f.URLPrivateDownload, &buf); err != nil {
+				e.logger.Error(err, "can not download file", "channel", c.Name, "file", f.ID)
+				continue
+			}
+
+			fileMetadata := map[string]string{"file-id": f.ID}
+			for k, v := range metadata {
+				fileMetadata[k] = v
+			}
+
+			// File names are chosen by users, so slashes are replaced to keep them inside dir
+			name := f.ID + "-" + strings.ReplaceAll(f.Name, "/", "_")
+			location, err := e.target.Write(ctx, dir+"/"+name, buf.Bytes(), fileMetadata)
+			if err != nil {
+				return err
+			}
+			logger.Info("exported file", "file", f.ID, "location", location)
+		}
+	}
+
+	return nil
+}
+
+// getHistory will get every message in a channel and every reply in its threads, following pagination. Replies are
+// listed like any other message, so they end up in the file of the day they were posted on as in Slack exports.
+func (e *channelExporter) getHistory(ctx context.Context, c slack.Channel) ([]slack.Message, error) {
+	messages := []slack.Message{}
+	params := &slack.GetConversationHistoryParameters{ChannelID: c.ID, Limit: 200}
+
+	for {
+		response, err := e.client.GetConversationHistoryContext(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, response.Messages...)
+
+		if !response.HasMore || response.ResponseMetaData.NextCursor == "" {
+			break
+		}
+		params.Cursor = response.ResponseMetaData.NextCursor
+	}
+
+	// Replies also sent to the channel are in the history already
+	seen := map[string]bool{}
+	for _, m := range messages {
+		seen[m.Timestamp] = true
+	}
+
+	for _, m := range messages {
+		if m.ReplyCount == 0 {
+			continue
+		}
+
+		replies, err := e.getReplies(ctx, c, m.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("can not get replies of thread %s: %w", m.Timestamp, err)
+		}
+		for _, r := range replies {
+			if !seen[r.Timestamp] {
+				seen[r.Timestamp] = true
+				messages = append(messages, r)
+			}
+		}
+	}
+
+	return messages, nil
+}
+
+// getReplies will get every message in a thread, following pagination. The parent message is returned too.
+func (e *channelExporter) getReplies(ctx context.Context, c slack.Channel, threadTS string) ([]slack.Message, error) {
+	replies := []slack.Message{}
+	params := &slack.GetConversationRepliesParameters{ChannelID: c.ID, Timestamp: threadTS, Limit: 200}
+
+	for {
+		page, hasMore, cursor, err := e.client.GetConversationRepliesContext(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		replies = append(replies, page...)
+
+		if !hasMore || cursor == "" {
+			return replies, nil
+		}
+		params.Cursor = cursor
+	}
+}
+
+// buildSlackExport will build a ZIP with channels.json, users.json and one file of messages per day
+// under a directory named after the channel, which is the layout Slack uses for workspace exports
+func buildSlackExport(c slack.Channel, users []slack.User, messages []slack.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	if err := writeZipJSON(w, "channels.json", []slack.Channel{c}); err != nil {
+		return nil, err
+	}
+
+	if err := writeZipJSON(w, "users.json", users); err != nil {
+		return nil, err
+	}
+
+	// Group messages by the UTC day they were posted on
+	days := map[string][]slack.Message{}
+	for _, m := range messages {
+		ts, err := parseSlackTimestamp(m.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+
+		day := ts.UTC().Format(exportDayLayout)
+		days[day] = append(days[day], m)
+	}
+
+	for day, dayMessages := range days {
+		// Slack exports list messages oldest first
+		sort.Slice(dayMessages, func(i, j int) bool { return dayMessages[i].Timestamp < dayMessages[j].Timestamp })
+
+		if err := writeZipJSON(w, c.Name+"/"+day+".json", dayMessages); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeZipJSON will write v as indented JSON to a new file in the ZIP
+func writeZipJSON(w *zip.Writer, name string, v any) error {
+	f, err := w.Create(name)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("can not write %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// localTarget writes channel backups to a local directory
+type localTarget struct {
+	dir string
+}
+
+func (t *localTarget) Write(_ context.Context, name string, data []byte, _ map[string]string) (string, error) {
+	path := filepath.Join(t.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// contentType will return the MIME type for an exported object based on its name
+func contentType(name string) string {
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+
+	return "application/octet-stream"
+}
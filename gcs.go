@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path"
+	"time"
+
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	gcsUploadURL = "https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=multipart"
+	gcsScope     = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+// gcsTarget writes channel backups to a Google Cloud Storage bucket, authenticating with
+// Application Default Credentials
+type gcsTarget struct {
+	client       *http.Client
+	bucket       string
+	prefix       string
+	storageClass string
+}
+
+func newGCSTarget(ctx context.Context, bucket, prefix, storageClass string) (*gcsTarget, error) {
+	client, err := google.DefaultClient(ctx, gcsScope)
+	if err != nil {
+		return nil, fmt.Errorf("can not find application default credentials: %w", err)
+	}
+
+	return &gcsTarget{
+		client:       client,
+		bucket:       bucket,
+		prefix:       prefix,
+		storageClass: storageClass,
+	}, nil
+}
+
+// gcsObject is the object resource sent alongside an upload
+type gcsObject struct {
+	Name         string            `json:"name"`
+	ContentType  string            `json:"contentType"`
+	CustomTime   string            `json:"customTime"`
+	StorageClass string            `json:"storageClass,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// Write will upload data as a single multipart request. The object's custom time is set to the upload time
+// so bucket lifecycle rules can use daysSinceCustomTime to expire or transition backups.
+func (t *gcsTarget) Write(ctx context.Context, name string, data []byte, metadata map[string]string) (string, error) {
+	object := gcsObject{
+		Name:         path.Join(t.prefix, name),
+		ContentType:  contentType(name),
+		CustomTime:   time.Now().UTC().Format(time.RFC3339),
+		StorageClass: t.storageClass,
+		Metadata:     metadata,
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	part, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=UTF-8"}})
+	if err != nil {
+		return "", err
+	}
+	if err := json.NewEncoder(part).Encode(object); err != nil {
+		return "", err
+	}
+
+	part, err = w.CreatePart(textproto.MIMEHeader{"Content-Type": {object.ContentType}})
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(gcsUploadURL, url.PathEscape(t.bucket)), &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "multipart/related; boundary="+w.Boundary())
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("can not upload gs://%s/%s: %s: %s", t.bucket, object.Name, resp.Status, msg)
+	}
+
+	return fmt.Sprintf("gs://%s/%s", t.bucket, object.Name), nil
+}
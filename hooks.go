@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// hookStage is when in the handling of a channel a hook command is run
+type hookStage string
+
+const (
+	hookPreWarn     hookStage = "pre_warn"
+	hookPreArchive  hookStage = "pre_archive"
+	hookPostArchive hookStage = "post_archive"
+)
+
+// hookOutputLimit is how much of a hook command's output is logged
+const hookOutputLimit = 1024
+
+// hooks are external commands run at each stage of handling a channel, the simplest way to plug in
+// custom behaviour. A command that exits non-zero before warning or archiving vetoes it.
+type hooks struct {
+	commands map[hookStage][]string
+	timeout  time.Duration
+}
+
+// newHooks will create the hooks configured, or return nil if there are none
+func newHooks(cfg *config) *hooks {
+	h := &hooks{commands: map[hookStage][]string{}, timeout: cfg.hookTimeout}
+	for stage, command := range map[hookStage]string{
+		hookPreWarn:     cfg.hookPreWarn,
+		hookPreArchive:  cfg.hookPreArchive,
+		hookPostArchive: cfg.hookPostArchive,
+	} {
+		if args := strings.Fields(command); len(args) > 0 {
+			h.commands[stage] = args
+		}
+	}
+
+	if len(h.commands) == 0 {
+		return nil
+	}
+
+	return h
+}
+
+// hookInput is the JSON written to a hook command's stdin
+type hookInput struct {
+	Stage        hookStage     `json:"stage"`
+	Channel      slack.Channel `json:"channel"`
+	Reason       archiveReason `json:"reason,omitempty"`
+	DaysInactive int           `json:"days_inactive"`
+	Threshold    int           `json:"threshold"`
+	ArchiveDate  time.Time     `json:"archive_date"`
+	RunID        string        `json:"run_id"`
+}
+
+// runHook will run the hook command of a stage for a channel, reporting whether it exited zero. Stages
+// without a command always allow the action, as do dry runs, since hooks may act on the channels they are run for.
+// It returns an error when the command can not be run at all.
+func (a *ArchiveSlacker) runHook(ctx context.Context, stage hookStage, c inactiveChannel) (bool, error) {
+	if a.hooks == nil || a.hooks.commands[stage] == nil {
+		return true, nil
+	}
+	if a.dryRun {
+		a.logger.V(1).Info("not running hook in dry run", "channel", c.channel.Name, "stage", stage)
+		return true, nil
+	}
+
+	input, err := json.Marshal(hookInput{
+		Stage:        stage,
+		Channel:      c.channel,
+		Reason:       c.reason,
+		DaysInactive: c.daysInactive,
+		Threshold:    c.threshold,
+		ArchiveDate:  c.archiveDate,
+		RunID:        a.result.RunID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.hooks.timeout)
+	defer cancel()
+
+	args := a.hooks.commands[stage]
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err = cmd.Run()
+
+	out := output.String()
+	if len(out) > hookOutputLimit {
+		out = out[:hookOutputLimit]
+	}
+	logger := a.logger.WithValues("channel", c.channel.Name, "stage", stage)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		logger.V(1).Info("ran hook", "output", out)
+		return true, nil
+	case ctx.Err() != nil:
+		return false, fmt.Errorf("%s hook timed out after %s", stage, a.hooks.timeout)
+	case errors.As(err, &exitErr):
+		logger.Info("hook exited non-zero", "exit_code", exitErr.ExitCode(), "output", out)
+		return false, nil
+	default:
+		return false, fmt.Errorf("can not run %s hook: %w", stage, err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// defaultIncidentChannelPattern matches the channel names incident.io (inc-), PagerDuty (incident-)
+	// and FireHydrant (fh-) create by default
+	defaultIncidentChannelPattern = `^(inc|incident|fh)[-_]`
+	// defaultIncidentResolvedPattern matches the status updates incident tooling posts when an incident ends
+	defaultIncidentResolvedPattern = `(?i)\b(resolved|closed)\b`
+)
+
+// incidentPolicy archives channels created by incident tooling a fixed number of days after the incident
+// is resolved, instead of applying the inactivity threshold
+type incidentPolicy struct {
+	days            int
+	channelPattern  *regexp.Regexp
+	creators        map[string]bool
+	resolvedPattern *regexp.Regexp
+}
+
+// matches will report whether a channel was created by incident tooling
+func (p *incidentPolicy) matches(c slack.Channel) bool {
+	return p.channelPattern.MatchString(c.Name) || p.creators[c.Creator]
+}
+
+// getIncidentResolution will return when the incident of a channel was resolved, or the zero time if it is
+// still open. The resolution is the newest status message or topic matching the resolved pattern.
+func (a *ArchiveSlacker) getIncidentResolution(ctx context.Context, c slack.Channel, now time.Time) (time.Time, error) {
+	resolvedAt := time.Time{}
+	if a.incidents.resolvedPattern.MatchString(c.Topic.Value) {
+		resolvedAt = c.Topic.LastSet.Time()
+	}
+
+	// Status updates are posted at the end of an incident, so the newest page of history is enough
+	response, err := a.client.GetConversationHistoryContext(ctx, &slack.GetConversationHistoryParameters{
+		ChannelID: c.ID,
+		Latest:    strconv.Itoa(int(now.Unix())),
+		Limit:     200,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, m := range response.Messages {
+		if !a.incidents.resolvedPattern.MatchString(m.Text) {
+			continue
+		}
+
+		ts, err := parseSlackTimestamp(m.Timestamp)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if ts.After(resolvedAt) {
+			resolvedAt = ts
+		}
+		break
+	}
+
+	return resolvedAt, nil
+}
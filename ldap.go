@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// groupExemptedBy is who exemptions for owners in an exempt directory group are recorded as exempted by
+const groupExemptedBy = "directory"
+
+// LDAP protocol op and BER tags, as numbered in RFC 4511
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berEnumerated  = 0x0a
+	berBoolean     = 0x01
+	berSequence    = 0x30
+	berSet         = 0x31
+
+	ldapBindRequest       = 0x60
+	ldapBindResponse      = 0x61
+	ldapUnbindRequest     = 0x42
+	ldapSearchRequest     = 0x63
+	ldapSearchResultEntry = 0x64
+	ldapSearchResultDone  = 0x65
+	ldapSearchResultRef   = 0x73
+	// ldapSimpleAuth is the [0] context tag of a simple bind's password
+	ldapSimpleAuth = 0x80
+	// ldapEqualityMatch is the [3] context tag of an equality filter
+	ldapEqualityMatch = 0xa3
+
+	ldapScopeWholeSubtree = 2
+	ldapResultSuccess     = 0
+)
+
+// ldapDirectory looks up which groups users are in with an LDAP or Active Directory server, by the memberOf
+// attribute of the user with a matching email. It only speaks the simple bind and search auto-archiver needs, which
+// keeps an LDAP library out of the dependencies.
+type ldapDirectory struct {
+	url      *url.URL
+	bindDN   string
+	password string
+	baseDN   string
+	// userAttribute is the attribute users are found by their email with, such as mail or userPrincipalName
+	userAttribute string
+	timeout       time.Duration
+
+	// groups caches the groups of each email for the run
+	groups map[string][]string
+	mu     sync.Mutex
+}
+
+func newLDAPDirectory(u *url.URL, bindDN, password, baseDN, userAttribute string, timeout time.Duration) *ldapDirectory {
+	return &ldapDirectory{
+		url:           u,
+		bindDN:        bindDN,
+		password:      password,
+		baseDN:        baseDN,
+		userAttribute: userAttribute,
+		timeout:       timeout,
+		groups:        map[string][]string{},
+	}
+}
+
+// memberOf will return the DNs of the groups the user with an email is directly in, nil if there is no such user
+func (d *ldapDirectory) memberOf(ctx context.Context, email string) ([]string, error) {
+	d.mu.Lock()
+	groups, ok := d.groups[email]
+	d.mu.Unlock()
+	if ok {
+		return groups, nil
+	}
+
+	groups, err := d.search(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.groups[email] = groups
+	d.mu.Unlock()
+
+	return groups, nil
+}
+
+// search will bind and search for the memberOf attribute of the user with an email
+func (d *ldapDirectory) search(ctx context.Context, email string) ([]string, error) {
+	host := d.url.Host
+	if d.url.Port() == "" {
+		host = net.JoinHostPort(d.url.Hostname(), map[string]string{"ldap": "389", "ldaps": "636"}[d.url.Scheme])
+	}
+
+	dialer := &net.Dialer{Timeout: d.timeout}
+	var conn net.Conn
+	var err error
+	if d.url.Scheme == "ldaps" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: d.url.Hostname()}}).DialContext(ctx, "tcp", host)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not connect to LDAP server: %w", err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(d.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+
+	bind := berTLV(ldapBindRequest, berInt(berInteger, 3), berString(d.bindDN), berTLV(ldapSimpleAuth, []byte(d.password)))
+	if _, err := conn.Write(ldapMessage(1, bind)); err != nil {
+		return nil, err
+	}
+	tag, op, err := readLDAPMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("can not read LDAP bind response: %w", err)
+	}
+	if tag != ldapBindResponse {
+		return nil, fmt.Errorf("unexpected LDAP response 0x%x to bind", tag)
+	}
+	if err := ldapResultError(op); err != nil {
+		return nil, fmt.Errorf("can not bind to LDAP server: %w", err)
+	}
+
+	search := berTLV(ldapSearchRequest,
+		berString(d.baseDN),
+		berInt(berEnumerated, ldapScopeWholeSubtree),
+		berInt(berEnumerated, 0),
+		berInt(berInteger, 2),
+		berInt(berInteger, int(d.timeout.Seconds())),
+		berTLV(berBoolean, []byte{0}),
+		berTLV(ldapEqualityMatch, berString(d.userAttribute), berString(email)),
+		berTLV(berSequence, berString("memberOf")),
+	)
+	if _, err := conn.Write(ldapMessage(2, search)); err != nil {
+		return nil, err
+	}
+
+	groups := []string{}
+	for {
+		tag, op, err := readLDAPMessage(r)
+		if err != nil {
+			return nil, fmt.Errorf("can not read LDAP search response: %w", err)
+		}
+		switch tag {
+		case ldapSearchResultEntry:
+			values, err := ldapAttributeValues(op, "memberOf")
+			if err != nil {
+				return nil, err
+			}
+			groups = append(groups, values...)
+		case ldapSearchResultRef:
+			// Referrals to other servers are not followed
+		case ldapSearchResultDone:
+			if err := ldapResultError(op); err != nil {
+				return nil, fmt.Errorf("LDAP search failed: %w", err)
+			}
+			// Unbinding is only polite, the connection is closed either way
+			_, _ = conn.Write(ldapMessage(3, []byte{ldapUnbindRequest, 0}))
+			return groups, nil
+		default:
+			return nil, fmt.Errorf("unexpected LDAP response 0x%x to search", tag)
+		}
+	}
+}
+
+// inGroup will report whether any of the group DNs is the group, given as a DN or as its common name
+func inGroup(memberOf []string, group string) bool {
+	for _, dn := range memberOf {
+		if strings.EqualFold(dn, group) {
+			return true
+		}
+		rdn, _, _ := strings.Cut(dn, ",")
+		if attr, value, ok := strings.Cut(rdn, "="); ok && strings.EqualFold(strings.TrimSpace(attr), "cn") &&
+			strings.EqualFold(strings.TrimSpace(value), group) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ownerGroupExemption will return an exemption of a channel one of whose owners is in an exempt directory group, or
+// nil if none is. Owners are matched to directory users by their Slack email.
+func (a *ArchiveSlacker) ownerGroupExemption(ctx context.Context, c slack.Channel) (*exemption, error) {
+	owners, err := a.channelOwners(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("can not get channel owners: %w", err)
+	}
+
+	for _, owner := range owners {
+		user, err := a.getUser(ctx, owner)
+		if err != nil {
+			return nil, err
+		}
+		if user.Profile.Email == "" {
+			continue
+		}
+
+		memberOf, err := a.groupDirectory.memberOf(ctx, user.Profile.Email)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range a.exemptGroups {
+			if inGroup(memberOf, group) {
+				return &exemption{
+					ChannelID:   c.ID,
+					ChannelName: c.Name,
+					Reason:      fmt.Sprintf("owner %s is in directory group %s", user.Name, group),
+					ExemptedBy:  groupExemptedBy,
+				}, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// ldapMessage will wrap a protocol op in an LDAPMessage with an ID
+func ldapMessage(id int, op []byte) []byte {
+	return berTLV(berSequence, berInt(berInteger, id), op)
+}
+
+// readLDAPMessage will read an LDAPMessage, returning the tag and contents of its protocol op
+func readLDAPMessage(r *bufio.Reader) (byte, []byte, error) {
+	tag, msg, err := readBER(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if tag != berSequence {
+		return 0, nil, fmt.Errorf("LDAP message has tag 0x%x", tag)
+	}
+
+	fields, err := splitBER(msg)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(fields) < 2 {
+		return 0, nil, errors.New("LDAP message has no protocol op")
+	}
+
+	return fields[1].tag, fields[1].value, nil
+}
+
+// ldapResultError will return the error of an LDAPResult, nil when it succeeded
+func ldapResultError(op []byte) error {
+	fields, err := splitBER(op)
+	if err != nil {
+		return err
+	}
+	if len(fields) < 3 || fields[0].tag != berEnumerated {
+		return errors.New("malformed LDAP result")
+	}
+
+	code := 0
+	for _, b := range fields[0].value {
+		code = code<<8 | int(b)
+	}
+	if code == ldapResultSuccess {
+		return nil
+	}
+	if msg := string(fields[2].value); msg != "" {
+		return fmt.Errorf("LDAP result code %d: %s", code, msg)
+	}
+
+	return fmt.Errorf("LDAP result code %d", code)
+}
+
+// ldapAttributeValues will return the values of an attribute of a SearchResultEntry
+func ldapAttributeValues(entry []byte, name string) ([]string, error) {
+	fields, err := splitBER(entry)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 2 {
+		return nil, errors.New("malformed LDAP search result entry")
+	}
+
+	attributes, err := splitBER(fields[1].value)
+	if err != nil {
+		return nil, err
+	}
+	values := []string{}
+	for _, attribute := range attributes {
+		parts, err := splitBER(attribute.value)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) < 2 || !strings.EqualFold(string(parts[0].value), name) {
+			continue
+		}
+
+		vals, err := splitBER(parts[1].value)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vals {
+			values = append(values, string(v.value))
+		}
+	}
+
+	return values, nil
+}
+
+// berField is a decoded BER tag, length and value
+type berField struct {
+	tag   byte
+	value []byte
+}
+
+// berTLV will encode a tag with the concatenation of values as its contents
+func berTLV(tag byte, values ...[]byte) []byte {
+	n := 0
+	for _, v := range values {
+		n += len(v)
+	}
+
+	out := []byte{tag}
+	switch {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	case n <= 0xffff:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	default:
+		out = append(out, 0x84, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	for _, v := range values {
+		out = append(out, v...)
+	}
+
+	return out
+}
+
+func berString(s string) []byte {
+	return berTLV(berOctetString, []byte(s))
+}
+
+// berInt will encode a non-negative integer or enumerated value
+func berInt(tag byte, v int) []byte {
+	b := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	// A leading bit would make the value negative
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+
+	return berTLV(tag, b)
+}
+
+// readBER will read one BER element from r
+func readBER(r *bufio.Reader) (byte, []byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length := int(n)
+	if n&0x80 != 0 {
+		size := int(n & 0x7f)
+		if size == 0 || size > 4 {
+			return 0, nil, fmt.Errorf("unsupported BER length of %d bytes", size)
+		}
+		length = 0
+		for i := 0; i < size; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return 0, nil, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return 0, nil, err
+	}
+
+	return tag, value, nil
+}
+
+// splitBER will decode the consecutive BER elements of a constructed value's contents
+func splitBER(data []byte) ([]berField, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+	fields := []berField{}
+	for {
+		tag, value, err := readBER(r)
+		if errors.Is(err, io.EOF) {
+			return fields, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed BER: %w", err)
+		}
+		fields = append(fields, berField{tag: tag, value: value})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/go-logr/logr"
+)
+
+// dayStats summarize a number of days over the channels archived in a run
+type dayStats struct {
+	Min    int     `json:"min"`
+	Median int     `json:"median"`
+	P90    int     `json:"p90"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+}
+
+// lifecycleStats are how old the channels archived in a run were, and how long after their last activity they were
+// archived, so hygiene policy can be tuned from the results of many runs
+type lifecycleStats struct {
+	Archived int `json:"archived"`
+	// AgeDays are the days from creating the channels to archiving them
+	AgeDays dayStats `json:"age_days"`
+	// DaysInactive are the days from the channels' last activity to archiving them
+	DaysInactive dayStats `json:"days_inactive"`
+}
+
+// newDayStats will summarize days, which must not be empty
+func newDayStats(days []int) dayStats {
+	sorted := slices.Clone(days)
+	slices.Sort(sorted)
+
+	sum := 0
+	for _, d := range sorted {
+		sum += d
+	}
+
+	return dayStats{
+		Min:    sorted[0],
+		Median: sorted[len(sorted)/2],
+		P90:    sorted[(len(sorted)*9)/10],
+		Max:    sorted[len(sorted)-1],
+		Mean:   float64(sum) / float64(len(sorted)),
+	}
+}
+
+// archivedLifecycle will return the lifecycle stats of the channels archived in a run, or nil if none were
+func archivedLifecycle(channels []channelResult) *lifecycleStats {
+	ages := []int{}
+	inactive := []int{}
+	for _, c := range channels {
+		if c.Decision != decisionArchive || c.Error != "" || c.ReportOnly {
+			continue
+		}
+		ages = append(ages, c.AgeDays)
+		inactive = append(inactive, c.DaysInactive)
+	}
+	if len(ages) == 0 {
+		return nil
+	}
+
+	return &lifecycleStats{
+		Archived:     len(ages),
+		AgeDays:      newDayStats(ages),
+		DaysInactive: newDayStats(inactive),
+	}
+}
+
+// log will write the lifecycle stats as a single log record
+func (l *lifecycleStats) log(logger logr.Logger) {
+	logger.Info("archived channel lifecycle",
+		"archived", l.Archived,
+		"age_days_median", l.AgeDays.Median,
+		"age_days_p90", l.AgeDays.P90,
+		"age_days_mean", l.AgeDays.Mean,
+		"days_inactive_median", l.DaysInactive.Median,
+		"days_inactive_p90", l.DaysInactive.P90,
+		"days_inactive_mean", l.DaysInactive.Mean,
+	)
+}
@@ -2,9 +2,20 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"math"
 	"os"
+	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -13,135 +24,1663 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "state":
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			code := runStateCommand(ctx, os.Args[2:], os.Stdin, os.Stdout, os.Stderr)
+			cancel()
+			os.Exit(code)
+		case "backtest":
+			os.Exit(runBacktestCommand(os.Args[2:], os.Stdout, os.Stderr))
+		case "report":
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			code := runReportCommand(ctx, os.Args[2:], os.Stdout, os.Stderr)
+			cancel()
+			os.Exit(code)
+		}
+	}
+
+	output := flag.String("output", "text", `format of the run result, "text" only logs it, "json" also writes a JSON document to stdout and "plan" a diff of the channels acted on`)
+	strict := flag.Bool("strict", true, "exit non-zero when evaluating or acting on any channel fails, not only when the run stops")
+	dryRun := flag.Bool("dry-run", false, "evaluate channels without joining, warning or archiving any")
+	readOnly := flag.Bool("read-only", false, "dry run that only calls Slack API methods needing read scopes and never writes state, for evaluating auto-archiver with a read-only token")
+	daemonMode := flag.Bool("daemon", false, "keep running, archiving on a schedule and handling Slack shortcuts over Socket Mode")
+	planOut := flag.String("plan-out", "", "file to write the channels a dry run would warn or archive to, for applying with --apply")
+	apply := flag.String("apply", "", "plan file to apply, only warning and archiving the channels it lists as it planned")
+	configFile, profile := addProfileFlags(flag.CommandLine)
+	var since, until time.Time
+	flag.Func("since", "oldest time to search channel history from instead of the archive threshold, requires --dry-run", timeFlag(&since))
+	flag.Func("until", "evaluate channels as of this time instead of now, requires --dry-run", timeFlag(&until))
+	flag.Parse()
+
+	if *output != "text" && *output != "json" && *output != "plan" {
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n", *output)
+		os.Exit(exitUsage)
+	}
+
+	// Bounded windows evaluate history that does not match the channels' current state, so they must never act
+	if (!since.IsZero() || !until.IsZero()) && !*dryRun {
+		fmt.Fprintln(os.Stderr, "--since and --until require --dry-run")
+		os.Exit(exitUsage)
+	}
+
+	if !since.IsZero() && !until.IsZero() && !since.Before(until) {
+		fmt.Fprintln(os.Stderr, "--since must be before --until")
+		os.Exit(exitUsage)
+	}
+
+	if *daemonMode && (*output != "text" || !until.IsZero()) {
+		fmt.Fprintln(os.Stderr, "--daemon can not be used with --output json, --output plan or --until")
+		os.Exit(exitUsage)
+	}
+
+	if *apply != "" && (*planOut != "" || *daemonMode) {
+		fmt.Fprintln(os.Stderr, "--apply can not be used with --plan-out or --daemon")
+		os.Exit(exitUsage)
+	}
+
+	if *readOnly && (*daemonMode || *apply != "") {
+		fmt.Fprintln(os.Stderr, "--read-only can not be used with --daemon or --apply")
+		os.Exit(exitUsage)
+	}
+
+	// Logs move to stderr when stdout is reserved for the JSON result or the plan
+	logWriter := io.Writer(os.Stdout)
+	if *output != "text" {
+		logWriter = os.Stderr
+	}
+
+	logger := newLogger(logWriter)
+
+	cfg, err := loadProfileConfig(*configFile, *profile)
+	if err != nil {
+		logger.Error(err, "can not load configuration")
+		os.Exit(exitConfig)
+	}
+
+	// Profiles can make every run a dry run, the flags can only add to that
+	cfg.dryRun = cfg.dryRun || *dryRun || *readOnly
+	cfg.readOnly = *readOnly
+	cfg.since = since
+	cfg.until = until
+	cfg.daemon = *daemonMode
+
+	logfmtr.SetVerbosity(cfg.verbosity)
+
+	// A plan is only a plan if the run that makes it can not act
+	if *planOut != "" && !cfg.dryRun {
+		logger.Error(nil, "--plan-out requires a dry run")
+		os.Exit(exitUsage)
+	}
+
+	if *apply != "" {
+		cfg.plan, err = loadPlanFile(*apply, cfg, time.Now())
+		if err != nil {
+			logger.Error(err, "can not load plan")
+			os.Exit(exitConfig)
+		}
+		// Channels the plan does not list are not even scanned
+		cfg.channelFilter = map[string]bool{}
+		for id := range cfg.plan {
+			cfg.channelFilter[id] = true
+		}
+	}
+
+	cfg.logWriter = logWriter
+
+	// The calls a dry run makes show what the real run will cost
+	if cfg.dryRun {
+		cfg.apiCalls = newAPICallCounter()
+	}
+
+	shards := newBotShards(
+		cfg,
+		slack.OptionDebug(cfg.slackDebug),
+		slack.OptionAPIURL(cfg.slackAPIURL),
+		slack.OptionLog(newStdLogger(logger, "slack client")),
+		slack.OptionAppLevelToken(cfg.appToken),
+	)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	store, err := newStore(ctx, cfg)
+	if err != nil {
+		logger.Error(err, "can not open state store")
+		os.Exit(exitConfig)
+	}
+	if store != nil {
+		defer store.Close()
+		if cfg.readOnly {
+			store = readOnlyStore{store}
+		}
+	}
+
+	// Escalations have to be remembered from one run to the next
+	if cfg.escalationSteps != nil && store == nil {
+		logger.Error(nil, "AUTO_ARCHIVER_ESCALATION_STEPS requires a state store")
+		os.Exit(exitConfig)
+	}
+	if cfg.maxScanIntervalDays > 0 && store == nil {
+		logger.Error(nil, "AUTO_ARCHIVER_MAX_SCAN_INTERVAL_DAYS requires a state store")
+		os.Exit(exitConfig)
+	}
+	if cfg.archiveApproval && store == nil {
+		logger.Error(nil, "AUTO_ARCHIVER_ARCHIVE_APPROVAL requires a state store")
+		os.Exit(exitConfig)
+	}
+
+	if cfg.setupAdmin != "" && store == nil {
+		logger.Error(nil, "AUTO_ARCHIVER_SETUP_ADMIN requires a state store")
+		os.Exit(exitConfig)
+	}
+	// Only the daemon receives the clicks of the buttons nudges are sent with
+	if cfg.nudgeMembers > 0 && !*daemonMode {
+		logger.Error(nil, "AUTO_ARCHIVER_NUDGE_MEMBERS requires --daemon")
+		os.Exit(exitConfig)
+	}
+
+	if *daemonMode {
+		// Exemptions made from Slack have to be stored somewhere
+		if store == nil {
+			logger.Error(nil, "--daemon requires a state store")
+			os.Exit(exitConfig)
+		}
+
+		d := newDaemon(logger, cfg, shards, store, newStdLogger(logger, "socket mode"))
+		if err := d.run(ctx); err != nil && ctx.Err() == nil {
+			logger.Error(err, "daemon failed")
+			if isAuthError(err) {
+				os.Exit(exitAuth)
+			}
+			os.Exit(exitRunFailed)
+		}
+		return
+	}
+
+	result := newRunResult(time.Now())
+	logger = logger.WithValues("run_id", result.RunID)
+
+	runErr := run(ctx, logger, cfg, shards, store, result)
+	if runErr != nil {
+		logger.Error(runErr, "run failed")
+		result.addError(runErr)
+	}
+
+	result.finish(time.Now())
+	result.log(logger)
+	publishRunResult(ctx, logger, cfg, result)
+
+	var budget *apiBudget
+	if cfg.apiCalls != nil {
+		b := estimateAPIBudget(cfg.apiCalls.counts(), result, cfg)
+		budget = &b
+		logger.Info("estimated real run", "api_calls", b.Total, "duration", b.Duration)
+	}
+
+	switch *output {
+	case "json":
+		if err := result.writeJSON(os.Stdout); err != nil {
+			logger.Error(err, "can not write run result")
+		}
+	case "plan":
+		if err := result.writePlan(os.Stdout, isTerminal(os.Stdout), budget); err != nil {
+			logger.Error(err, "can not write plan")
+		}
+	}
+
+	if *planOut != "" && runErr == nil {
+		if err := result.writePlanFile(*planOut); err != nil {
+			logger.Error(err, "can not write plan file")
+			os.Exit(exitRunFailed)
+		}
+	}
+
+	os.Exit(exitCode(result, runErr, *strict))
+}
+
+// publishRunResult will send the result of a finished run to the configured metrics and exports. Failing to publish
+// it is only logged, as the run itself is over.
+func publishRunResult(ctx context.Context, logger logr.Logger, cfg *config, result *runResult) {
+	if cfg.cloudWatchEMF {
+		if err := writeEMF(cfg.logWriter, cfg.cloudWatchNamespace, result, cfg.dryRun); err != nil {
+			logger.Error(err, "can not write run metrics as EMF")
+		}
+	}
+
+	if cfg.bigQueryTable != nil {
+		if err := exportToBigQuery(ctx, cfg.bigQueryTable, result, cfg.dryRun); err != nil {
+			logger.Error(err, "can not export run result to BigQuery")
+		}
+	}
+	if cfg.resultsURL != "" {
+		if err := writeResults(ctx, logger, cfg, result); err != nil {
+			logger.Error(err, "can not write run result to object storage")
+		}
+	}
+	if cfg.datadogAPIKey != "" {
+		if err := newDatadogClient(cfg.datadogSite, cfg.datadogAPIKey).sendRun(ctx, result, cfg.dryRun); err != nil {
+			logger.Error(err, "can not send run result to Datadog")
+		}
+	}
+}
+
+// run will warn and archive inactive channels, recording what happened in result. Channels are spread
+// over the bot shards, which each scan their channels concurrently.
+func run(ctx context.Context, logger logr.Logger, cfg *config, shards []botShard, store Store, result *runResult) error {
+	// A stuck run is stopped so it does not hold up the runs after it
+	if cfg.runTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.runTimeout)
+		defer cancel()
+	}
+
+	// Checking the tokens first means a bad token is reported as an auth error rather than a failed API call
+	botUsers := map[string]bool{}
+	auths := make([]*slack.AuthTestResponse, len(shards))
+	for i, shard := range shards {
+		auth, err := shard.client.AuthTestContext(ctx)
+		if err != nil {
+			return fmt.Errorf("can not authenticate with slack: %w", err)
+		}
+		botUsers[auth.UserID] = true
+		auths[i] = auth
+	}
+	result.Workspace = auths[0].Team
+
+	// Settings from the setup wizard and the App Home are read again each run, so changing them applies from the
+	// next run
+	cfg, pending, err := applyStoredSettings(ctx, cfg, store)
+	if err != nil {
+		return err
+	}
+	if pending {
+		logger.Info("waiting for setup, nothing is archived until the setup wizard is done", "admin", cfg.setupAdmin)
+		return nil
+	}
+
+	// The policy is read again each run, so merged changes apply from the next run
+	var policy *channelPolicy
+	if cfg.policyRepo != "" {
+		if policy, err = loadChannelPolicy(ctx, cfg); err != nil {
+			return fmt.Errorf("can not load policy: %w", err)
+		}
+		logger.Info("loaded policy", "commit", policy.commit)
+	}
+
+	// Org-wide installs run in each of their workspaces, which may override the policy
+	if len(cfg.workspaces) > 0 {
+		return runWorkspaces(ctx, logger, cfg, policy, shards, auths, botUsers, store, result)
+	}
+	if policy != nil {
+		if cfg, err = policy.apply(cfg); err != nil {
+			return fmt.Errorf("can not apply policy from %s: %w", policy.commit, err)
+		}
+	}
+
+	return runWorkspace(ctx, logger, cfg, shards, auths, botUsers, store, result)
+}
+
+// runWorkspace will warn and archive the inactive channels of a workspace with cfg, once its policy was applied
+func runWorkspace(ctx context.Context, logger logr.Logger, cfg *config, shards []botShard, auths []*slack.AuthTestResponse,
+	botUsers map[string]bool, store Store, result *runResult) error {
+	var pinned pinnedExemptions
+	if cfg.pinnedExemptions {
+		var err error
+		pinned, err = loadPinnedExemptions(ctx, logger, shards[0].client, cfg.adminChannel)
+		if err != nil {
+			return fmt.Errorf("can not load pinned exemptions: %w", err)
+		}
+		logger.V(1).Info("loaded pinned exemptions", "count", len(pinned))
+	}
+
+	var exportTarget Exporter
+	if !cfg.dryRun {
+		var err error
+		exportTarget, err = newExporter(ctx, logger, cfg)
+		if err != nil {
+			return fmt.Errorf("can not create exporter: %w", err)
+		}
+	}
+
+	var owners *ownershipMap
+	if cfg.ownersSource != "" {
+		var err error
+		owners, err = loadOwnershipMap(ctx, cfg.ownersSource)
+		if err != nil {
+			return fmt.Errorf("can not load ownership map: %w", err)
+		}
+	}
+
+	var activityTrackingSince time.Time
+	// Tracked activity is only complete while the daemon is running to receive message events
+	if cfg.trackActivity && cfg.daemon {
+		var err error
+		activityTrackingSince, err = getActivityTrackingSince(ctx, store)
+		if err != nil {
+			return fmt.Errorf("can not get activity tracking start: %w", err)
+		}
+	}
+
+	slackers := make([]*ArchiveSlacker, len(shards))
+	for i, shard := range shards {
+		shardLogger := logger
+		if len(shards) > 1 {
+			shardLogger = logger.WithValues("shard", i)
+		}
+		slackers[i] = newShardSlacker(shardLogger, shard, cfg, exportTarget, store, result)
+		slackers[i].owners = owners
+		slackers[i].pinned = pinned
+		slackers[i].shard = i
+		slackers[i].workspace = auths[i]
+		// Only the bot token's app receives message events, so only its channels are tracked
+		if i == 0 {
+			slackers[i].activityTrackingSince = activityTrackingSince
+		}
+	}
+	archiveSlacker := slackers[0]
+
+	// Dry runs tell nobody what they would have done
+	var notify *notifiers
+	if !cfg.dryRun {
+		notify = newNotifiers(logger, cfg, archiveSlacker)
+		for _, a := range slackers {
+			a.notifier = notify
+		}
+	}
+
+	summary := summaryMessageData{RunID: result.RunID, Threshold: cfg.archiveThreshold}
+	// Archiving is spread out over the run, so hundreds of channels are not archived in one burst
+	archivePace := newPacer(cfg.archivesPerMinute)
+	// archived counts the channels archived this run, or that would be in dry runs, against the maximum per run
+	archived := 0
+
+	// Whether the run may archive is decided once, so a run crossing the end of the window archives its whole list
+	inArchiveWindow := cfg.archiveWindow.contains(time.Now())
+
+	// Channels whose archiving failed transiently in earlier runs are retried first
+	var retried map[string]bool
+	if store != nil && cfg.archiveRetryAttempts > 0 && !cfg.dryRun && cfg.plan == nil && inArchiveWindow {
+		var err error
+		if retried, archived, err = retryArchives(ctx, logger, cfg, slackers, store, archivePace, &summary); err != nil {
+			notify.error(ctx, nil, err)
+			return err
+		}
+	}
+
+	var err error
+	// escalations are how far along the escalation chain each warned channel is
+	var escalations map[string]*escalation
+	if cfg.escalationSteps != nil {
+		if escalations, err = listEscalations(ctx, store); err != nil {
+			err = fmt.Errorf("can not get escalations: %w", err)
+			notify.error(ctx, nil, err)
+			return err
+		}
+	}
+	escalationOf := func(c slack.Channel) *escalation {
+		if escalations[c.ID] == nil {
+			escalations[c.ID] = &escalation{}
+		}
+		return escalations[c.ID]
+	}
+	// approvals are the requests to approve archiving channels
+	var approvals map[string]*archiveApproval
+	if cfg.archiveApproval {
+		if approvals, err = listApprovals(ctx, store); err != nil {
+			err = fmt.Errorf("can not get approvals: %w", err)
+			notify.error(ctx, nil, err)
+			return err
+		}
+	}
+	now := time.Now()
+
+	// get all unarchived channels, a page at a time when they are streamed
+	batches := newChannelBatches(slackers, cfg.channelPageSize)
+	// slackerFor is the shard that acts on each channel of the current batch
+	var slackerFor map[string]*ArchiveSlacker
+	// shardChannels are the channels each shard handles in the current batch, every channel unless they are streamed
+	var shardChannels [][]slack.Channel
+	for {
+		batch, err := batches.next(ctx)
+		if err != nil {
+			err = fmt.Errorf("failed to get channels: %w", err)
+			notify.error(ctx, nil, err)
+			return err
+		}
+		if batch == nil {
+			break
+		}
+		shardChannels = batch
+
+		// Triggered runs can be limited to some channels
+		if cfg.channelFilter != nil {
+			for i, channels := range shardChannels {
+				shardChannels[i] = filterChannels(channels, cfg.channelFilter)
+			}
+		}
+
+		// Channels retried this run are not acted on twice
+		if len(retried) > 0 {
+			for i, channels := range shardChannels {
+				shardChannels[i] = slices.DeleteFunc(channels, func(c slack.Channel) bool { return retried[c.ID] })
+			}
+		}
+
+		// Channels someone unarchived after they were archived are exempted before they are checked
+		if store != nil && cfg.unarchiveExemptionDays > 0 && !cfg.dryRun {
+			for i, channels := range shardChannels {
+				if err := slackers[i].exemptUnarchivedChannels(ctx, channels, cfg.unarchiveExemptionDays, time.Now()); err != nil {
+					err = fmt.Errorf("failed to exempt unarchived channels: %w", err)
+					notify.error(ctx, nil, err)
+					return err
+				}
+			}
+		}
+
+		slackerFor = map[string]*ArchiveSlacker{}
+		for i, channels := range shardChannels {
+			for _, c := range channels {
+				slackerFor[c.ID] = slackers[i]
+			}
+		}
+
+		archiveableChannels := []inactiveChannel{}
+		warnableChannels := []inactiveChannel{}
+		exemptChannels := []exemptChannel{}
+		errs := make([]error, len(slackers))
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for i, a := range slackers {
+			wg.Add(1)
+			go func(i int, a *ArchiveSlacker) {
+				defer wg.Done()
+
+				channels := shardChannels[i]
+
+				// Checking if there are any new public channels to join
+				// auto-archiver must be added to private channels manually if you wish to auto-archive
+				switch {
+				case cfg.dryRun || !cfg.autoJoin:
+					// A dry run can only read the history of channels auto-archiver is already a member of,
+					// and with auto-join off it only acts on the channels it was invited to
+					channels = memberChannels(channels)
+				default:
+					var err error
+					if channels, err = a.joinPublicChannels(ctx, channels); err != nil {
+						errs[i] = fmt.Errorf("failed to join new public channels: %w", err)
+						return
+					}
+				}
+
+				// Find all channels that auto-archiver is a member of that are close to or older than the archive threshold
+				archiveable, warnable, exempt := a.findInactiveChannels(ctx, channels)
+
+				mu.Lock()
+				defer mu.Unlock()
+				archiveableChannels = append(archiveableChannels, archiveable...)
+				warnableChannels = append(warnableChannels, warnable...)
+				exemptChannels = append(exemptChannels, exempt...)
+			}(i, a)
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil {
+				notify.error(ctx, nil, err)
+				return err
+			}
+		}
+
+		for _, c := range exemptChannels {
+			e := c.exemption
+			summary.Exempt = append(summary.Exempt, summaryChannel{Channel: c.channel, Exemption: &e})
+
+			// Permanently exempt channels will never be acted on, so there is no reason to stay in them
+			if cfg.leaveExemptChannels && !cfg.dryRun && e.Until.IsZero() && c.channel.IsMember {
+				logger.Info("leaving permanently exempt channel", "channel", c.channel.Name)
+				if _, err := slackerFor[c.channel.ID].client.LeaveConversationContext(ctx, c.channel.ID); err != nil {
+					logger.Error(err, "failed to leave permanently exempt channel", "channel", c.channel.Name)
+				}
+			}
+		}
+
+		for _, c := range warnableChannels {
+			if slackerFor[c.channel.ID].reportOnly(c) {
+				logger.Info("not warning private channel, only reporting it", "channel", c.channel.Name)
+				result.addReportOnly(c.channel, decisionWarn, "", c.daysInactive)
+				continue
+			}
+
+			allowed, err := archiveSlacker.runHook(ctx, hookPreWarn, c)
+			if err != nil {
+				logger.Error(err, "failed to run pre-warn hook", "channel", c.channel.Name)
+				notify.error(ctx, &c.channel, err)
+				result.addChannel(c.channel, decisionWarn, "", c.daysInactive, err)
+				continue
+			}
+			if !allowed {
+				result.addChannel(c.channel, decisionKeep, "", c.daysInactive, nil)
+				continue
+			}
+			if !cfg.planned(c.channel, decisionWarn) {
+				logger.Info("not warning channel the plan does not warn", "channel", c.channel.Name)
+				result.addChannel(c.channel, decisionKeep, "", c.daysInactive, nil)
+				continue
+			}
+
+			if escalations != nil {
+				_, err = slackerFor[c.channel.ID].escalate(ctx, c, escalationOf(c.channel), now)
+				result.addChannel(c.channel, decisionWarn, "", c.daysInactive, err)
+				if err != nil {
+					logger.Error(err, "failed to escalate channel", "channel", c.channel.Name)
+					notify.error(ctx, &c.channel, err)
+					continue
+				}
+				summary.Warned = append(summary.Warned, summaryChannel{Channel: c.channel})
+				continue
+			}
+
+			if cfg.dryRun {
+				logger.Info("would warn channel", "channel", c.channel.Name)
+				result.addChannel(c.channel, decisionWarn, "", c.daysInactive, nil)
+				continue
+			}
+
+			logger.Info("warning channel", "channel", c.channel.Name)
+			err = slackerFor[c.channel.ID].warnChannel(ctx, c)
+			result.addChannel(c.channel, decisionWarn, "", c.daysInactive, err)
+			if err != nil {
+				logger.Error(err, "failed to warn channel", "channel", c.channel.Name)
+				notify.error(ctx, &c.channel, err)
+				continue
+			}
+			summary.Warned = append(summary.Warned, summaryChannel{Channel: c.channel})
+		}
+
+		// archiveDone will record the outcome of archiving a channel
+		archiveDone := func(c inactiveChannel, err error) {
+			result.addChannel(c.channel, decisionArchive, c.reason, c.daysInactive, err)
+			if err != nil {
+				logger.Error(err, "failed to archive channel", "channel", c.channel.Name, "reason", c.reason)
+				notify.error(ctx, &c.channel, err)
+				if store != nil && cfg.archiveRetryAttempts > 0 && isTransientError(err) {
+					if err := slackerFor[c.channel.ID].queueArchiveRetry(ctx, c.channel, 1, err); err != nil {
+						logger.Error(err, "failed to queue archive retry", "channel", c.channel.Name)
+					}
+				}
+				summary.Failed = append(summary.Failed, summaryChannel{Channel: c.channel, Reason: c.reason})
+				return
+			}
+			summary.Archived = append(summary.Archived, summaryChannel{Channel: c.channel, Reason: c.reason})
+		}
+
+		// Large cleanups archive their channels in batches with the admin token, a single call per batch
+		var bulk *bulkArchiver
+		if cfg.bulkArchiveMinChannels > 0 && len(archiveableChannels) >= cfg.bulkArchiveMinChannels && !cfg.dryRun {
+			logger.Info("archiving channels in bulk", "channels", len(archiveableChannels))
+			bulk = &bulkArchiver{client: newRawSlackClient(cfg, cfg.adminToken)}
+		}
+		// archiveBulk will archive the channels of the bulk archiver's batch, paced as a single archive
+		archiveBulk := func() error {
+			if err := archivePace.wait(ctx); err != nil {
+				return err
+			}
+			channels, id, err := bulk.archive(ctx)
+			if err != nil {
+				logger.Error(err, "failed to start bulk archive", "channels", len(channels))
+			} else {
+				logger.Info("started bulk archive", "channels", len(channels), "bulk_action_id", id)
+			}
+			for _, c := range channels {
+				if err == nil {
+					slackerFor[c.channel.ID].finishArchive(ctx, c)
+				}
+				archiveDone(c, err)
+			}
+			return nil
+		}
+
+		for _, c := range archiveableChannels {
+			if slackerFor[c.channel.ID].reportOnly(c) {
+				logger.Info("not archiving private channel, only reporting it", "channel", c.channel.Name, "reason", c.reason)
+				result.addReportOnly(c.channel, decisionArchive, c.reason, c.daysInactive)
+				continue
+			}
+
+			if !cfg.planned(c.channel, decisionArchive) {
+				logger.Info("not archiving channel the plan does not archive", "channel", c.channel.Name)
+				result.addChannel(c.channel, decisionKeep, c.reason, c.daysInactive, nil)
+				continue
+			}
+
+			// Channels are only archived once every step of the escalation chain has been taken
+			if escalations != nil {
+				done, err := slackerFor[c.channel.ID].escalate(ctx, c, escalationOf(c.channel), now)
+				if err != nil {
+					logger.Error(err, "failed to escalate channel", "channel", c.channel.Name)
+					notify.error(ctx, &c.channel, err)
+					result.addChannel(c.channel, decisionWarn, c.reason, c.daysInactive, err)
+					summary.Failed = append(summary.Failed, summaryChannel{Channel: c.channel, Reason: c.reason})
+					continue
+				}
+				if !done {
+					result.addChannel(c.channel, decisionWarn, c.reason, c.daysInactive, nil)
+					summary.Warned = append(summary.Warned, summaryChannel{Channel: c.channel})
+					continue
+				}
+			}
+
+			allowed, exempt, err := archiveSlacker.isArchiveAllowed(ctx, c)
+			if err != nil {
+				logger.Error(err, "failed to check if channel may be archived", "channel", c.channel.Name)
+				notify.error(ctx, &c.channel, err)
+				result.addChannel(c.channel, decisionArchive, c.reason, c.daysInactive, err)
+				summary.Failed = append(summary.Failed, summaryChannel{Channel: c.channel, Reason: c.reason})
+				continue
+			}
+			if exempt != nil {
+				result.addChannel(c.channel, decisionExempt, "", 0, nil)
+				summary.Exempt = append(summary.Exempt, summaryChannel{Channel: c.channel, Exemption: exempt})
+				continue
+			}
+			if !allowed {
+				result.addChannel(c.channel, decisionKeep, c.reason, c.daysInactive, nil)
+				continue
+			}
+
+			// Channels waiting for approval are reported as warned until enough users approved archiving them
+			var approvers []string
+			if approvals != nil {
+				approval := approvals[c.channel.ID]
+				switch {
+				case approval != nil && approval.approved():
+					approvers = approval.approverIDs()
+				case cfg.dryRun:
+					logger.Info("would wait for archive approval", "channel", c.channel.Name)
+					result.addChannel(c.channel, decisionWarn, c.reason, c.daysInactive, nil)
+					continue
+				default:
+					if approval == nil {
+						_, err = slackerFor[c.channel.ID].requestApproval(ctx, c, now)
+					}
+					logger.Info("waiting for archive approval", "channel", c.channel.Name)
+					result.addChannel(c.channel, decisionWarn, c.reason, c.daysInactive, err)
+					if err != nil {
+						logger.Error(err, "failed to request archive approval", "channel", c.channel.Name)
+						notify.error(ctx, &c.channel, err)
+					}
+					continue
+				}
+			}
+
+			// Channels stay warned outside the archive window and are archived by a run within it
+			if !inArchiveWindow && !cfg.dryRun {
+				logger.Info("outside the archive window, leaving channel for a later run", "channel", c.channel.Name)
+				result.addChannel(c.channel, decisionWarn, c.reason, c.daysInactive, nil)
+				continue
+			}
+
+			// Channels over the maximum stay warned and are archived by a later run
+			if cfg.maxArchivesPerRun > 0 && archived >= cfg.maxArchivesPerRun {
+				logger.Info("reached the maximum archives per run, leaving channel for a later run", "channel", c.channel.Name)
+				result.addChannel(c.channel, decisionWarn, c.reason, c.daysInactive, nil)
+				continue
+			}
+			archived++
+
+			if cfg.dryRun {
+				logger.Info("would archive channel", "channel", c.channel.Name, "reason", c.reason)
+				result.addChannel(c.channel, decisionArchive, c.reason, c.daysInactive, nil)
+				continue
+			}
+
+			path := slackerFor[c.channel.ID].archivePath(c.channel.ID)
+			if bulk != nil {
+				path = archivePathBulk
+			} else if err := archivePace.wait(ctx); err != nil {
+				return err
+			}
+			if approvers != nil {
+				// The approvers are logged with the channel they approved archiving, for auditing
+				logger.Info("archiving channel", "channel", c.channel.Name, "reason", c.reason, "approvers", approvers, "path", path)
+			} else {
+				logger.Info("archiving channel", "channel", c.channel.Name, "reason", c.reason, "path", path)
+			}
+
+			if bulk == nil {
+				archiveDone(c, slackerFor[c.channel.ID].autoarchiveChannel(ctx, c))
+				continue
+			}
+			if err := slackerFor[c.channel.ID].prepareArchive(ctx, c); err != nil {
+				archiveDone(c, err)
+				continue
+			}
+			if bulk.add(c) {
+				if err := archiveBulk(); err != nil {
+					return err
+				}
+			}
+		}
+		if bulk != nil && len(bulk.pending) > 0 {
+			if err := archiveBulk(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cfg.dryRun {
+		return nil
+	}
+
+	// Channels that were kept, exempted or archived start the escalation chain and approval over if they become
+	// inactive again
+	for _, r := range result.Channels {
+		if r.Decision == decisionWarn || r.Decision == decisionError || r.Error != "" {
+			continue
+		}
+		if escalations[r.ID] != nil {
+			if err := store.Delete(ctx, bucketEscalations, r.ID); err != nil {
+				logger.Error(err, "failed to reset escalation", "channel", r.Name)
+			}
+		}
+		if approvals[r.ID] != nil {
+			if err := store.Delete(ctx, bucketApprovals, r.ID); err != nil {
+				logger.Error(err, "failed to reset approval", "channel", r.Name)
+			}
+		}
+	}
+
+	if store != nil && cfg.exemptionReminderDays > 0 {
+		if err := archiveSlacker.sendExemptionReminders(ctx, time.Now()); err != nil {
+			logger.Error(err, "failed to send exemption reminders")
+		}
+	}
+
+	if cfg.detectDuplicates {
+		channels := []slack.Channel{}
+		for _, cs := range shardChannels {
+			channels = append(channels, cs...)
+		}
+		summary.Duplicates = findDuplicateChannels(ctx, channels, slackerFor, result, cfg.archiveThreshold,
+			cfg.duplicateMemberOverlap, botUsers)
+	}
+
+	notify.summary(ctx, summary)
+
+	return nil
+}
+
+// filterChannels will return the channels whose ID or name is in filter
+func filterChannels(channels []slack.Channel, filter map[string]bool) []slack.Channel {
+	filtered := []slack.Channel{}
+	for _, c := range channels {
+		if filter[c.ID] || filter[c.Name] {
+			filtered = append(filtered, c)
+		}
+	}
+
+	return filtered
+}
+
+// memberChannels will return the channels auto-archiver is a member of
+func memberChannels(channels []slack.Channel) []slack.Channel {
+	members := []slack.Channel{}
+	for _, c := range channels {
+		if c.IsMember {
+			members = append(members, c)
+		}
+	}
+
+	return members
+}
+
+// timeFlag will return a flag parser for a date such as 2024-03-01 or an RFC 3339 time
+func timeFlag(t *time.Time) func(string) error {
+	return func(value string) error {
+		var err error
+		*t, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			*t, err = time.Parse(time.DateOnly, value)
+		}
+
+		return err
+	}
+}
+
+type ArchiveSlacker struct {
+	logger          logr.Logger
+	client          *slack.Client
+	threshold       int
+	warningDays     int
+	since           time.Time
+	until           time.Time
+	activityAuthors activityAuthors
+	// reactionWeight is how much each reaction counts towards one message of activity
+	reactionWeight float64
+	canvasActivity bool
+	raw            *rawSlackClient
+	incidents      *incidentPolicy
+	webhook        *decisionWebhook
+	dryRun         bool
+	store          Store
+	adminChannel   string
+	digestUsers    []string
+	notifyCreator  bool
+	owners         *ownershipMap
+	templates      *messageTemplates
+	exporter       *channelExporter
+	result         *runResult
+
+	// deactivatedCreatorThreshold replaces the threshold for channels whose creator is deactivated, nil when disabled
+	deactivatedCreatorThreshold *int
+	// maxJoins is the most public channels joined in a run, 0 is unlimited
+	maxJoins  int
+	joinDelay time.Duration
+	// directory resolves the managers warnings are escalated to, nil when disabled
+	directory *scimDirectory
+	// activityTrackingSince is when the daemon started tracking activity from message events, the zero time
+	// when activity is not tracked
+	activityTrackingSince time.Time
+	// users caches the users looked up during the run by ID
+	users map[string]*slack.User
+	// notifier is told about warnings, archives and errors, nil in dry runs
+	notifier *notifiers
+	// hooks are the commands run before warning and before and after archiving, nil when none are configured
+	hooks *hooks
+	// policy adds per-prefix thresholds and exemptions from the policy repository, nil when there is none
+	policy *channelPolicy
+	// pinned are the exemptions pinned in the admin channel, read at the start of each run
+	pinned pinnedExemptions
+
+	// archiveWithoutHumanMembers archives channels whose only members are bots regardless of the threshold
+	archiveWithoutHumanMembers bool
+	// archiveMembersDeactivated archives channels whose human members are all deactivated regardless of the threshold
+	archiveMembersDeactivated bool
+	// singleMemberThreshold replaces the threshold for channels with one human member when it is lower, nil when disabled
+	singleMemberThreshold *int
+	// retention gets channels' custom retention policies with the org admin token, nil when they are not checked
+	retention       *rawSlackClient
+	retentionAction string
+	// exemptionReminderDays is how long before an exemption ends the user who made it is reminded
+	exemptionReminderDays int
+	// daemon is set when the daemon is running to handle interactions with messages
+	daemon bool
+	// escalationSteps are the steps taken before archiving an inactive channel, nil when channels are warned
+	// every run until they are archived
+	escalationSteps []escalationStep
+	// shard is the index of the bot shard the slacker acts for
+	shard int
+	// twoPersonApprovalMembers and twoPersonApprovalAgeDays are the member count and age in days from which
+	// archiving a channel needs two approvers, 0 when they do not
+	twoPersonApprovalMembers int
+	twoPersonApprovalAgeDays int
+	// respectDND schedules direct messages to users with Do Not Disturb on for when it ends
+	respectDND bool
+	// recreationWindowDays is how long archived channels are remembered to find channels recreating them
+	recreationWindowDays int
+	// unarchiveExemptionDays is how long channels unarchived after being archived are exempt, 0 when they are not
+	unarchiveExemptionDays int
+	// maxScanIntervalDays is the longest a kept channel goes without being scanned, 0 when every channel is
+	// scanned every run
+	maxScanIntervalDays int
+	// historyPageSize and historyMaxPages bound how much history is read, historyMaxPages 0 reads all of it
+	historyPageSize  int
+	historyMaxPages  int
+	historyInclusive bool
+	// minActivityMessages is how many messages of activity within the threshold keep a channel active
+	minActivityMessages int
+	// guestWeight and externalWeight are how much messages of guests and external users count towards one message
+	guestWeight    float64
+	externalWeight float64
+	// workspace is the team and organization the bot is installed in, nil when unknown
+	workspace *slack.AuthTestResponse
+	// privateChannels is whether private channels are ignored, reported on or archived
+	privateChannels string
+	// privateThreshold and privateWarningDays replace threshold and warningDays for private channels when set
+	privateThreshold   *int
+	privateWarningDays *int
+	// teamID is the workspace channels are listed in for org-wide installs, empty for the token's workspace
+	teamID string
+	// exemptionService is asked whether candidates are exempt, nil when there is none
+	exemptionService *exemptionService
+	// groupDirectory looks up the groups of channel owners, nil when no groups are exempt
+	groupDirectory *ldapDirectory
+	exemptGroups   []string
+	// tagsEnabled reads channel tags, cached in tags by channel ID for the run
+	tagsEnabled bool
+	tags        map[string]*channelTags
+	// staleWarnings is what is done with the warnings of channels that survived
+	staleWarnings string
+	// countdownWarnings updates the last warning of a channel instead of posting a new one
+	countdownWarnings bool
+	// nudgeMembers is how many recently active members are asked about a channel instead of warning it, 0 warns it
+	nudgeMembers int
+	// warningMentionLevel is who warnings mention, and warningMentionMaxMembers the member count above which
+	// channels are not mentioned, 0 for no limit
+	warningMentionLevel      string
+	warningMentionMaxMembers int
+	// admin calls admin.conversations methods for the public channels the bot could not join, adminChannels, nil
+	// without the admin fallback
+	admin         *rawSlackClient
+	adminChannels map[string]bool
+	// archiveUser archives channels with a user token instead of the bot token, nil to archive with the bot token
+	archiveUser *rawSlackClient
+}
+
+func NewArchiveSlacker(logger logr.Logger, client *slack.Client, cfg *config, exportTarget Exporter, store Store, result *runResult) *ArchiveSlacker {
+	var exporter *channelExporter
+	if exportTarget != nil {
+		exporter = newChannelExporter(logger, client, exportTarget, cfg, result.RunID)
+	}
+
+	var webhook *decisionWebhook
+	if cfg.decisionWebhookURL != "" {
+		webhook = newDecisionWebhook(cfg.decisionWebhookURL, cfg.decisionWebhookToken, cfg.decisionWebhookTimeout)
+	}
+
+	var exemptions *exemptionService
+	if cfg.exemptionServiceURL != "" {
+		exemptions = newExemptionService(cfg.exemptionServiceURL, cfg.exemptionServiceToken, cfg.exemptionServiceTimeout,
+			cfg.exemptionServiceCacheTTL, store)
+	}
+
+	var directory *scimDirectory
+	if cfg.directorySCIMURL != "" {
+		directory = newSCIMDirectory(cfg.directorySCIMURL, cfg.directorySCIMToken)
+	}
+
+	var groupDirectory *ldapDirectory
+	if cfg.ldapURL != nil && len(cfg.exemptGroups) > 0 {
+		groupDirectory = newLDAPDirectory(cfg.ldapURL, cfg.ldapBindDN, cfg.ldapBindPassword, cfg.ldapBaseDN,
+			cfg.ldapUserAttribute, cfg.ldapTimeout)
+	}
+
+	var retention *rawSlackClient
+	if cfg.retentionToken != "" {
+		retention = newRawSlackClient(cfg, cfg.retentionToken)
+	}
+
+	var admin *rawSlackClient
+	if cfg.adminFallback {
+		admin = newRawSlackClient(cfg, cfg.adminToken)
+	}
+
+	var archiveUser *rawSlackClient
+	if cfg.archiveUserToken != "" {
+		archiveUser = newRawSlackClient(cfg, cfg.archiveUserToken)
+	}
+
+	// Triggered runs check their channels now, however recently they were scanned
+	maxScanIntervalDays := cfg.maxScanIntervalDays
+	if cfg.channelFilter != nil {
+		maxScanIntervalDays = 0
+	}
+
+	return &ArchiveSlacker{
+		logger:          logger,
+		client:          client,
+		threshold:       cfg.archiveThreshold,
+		warningDays:     cfg.warningDays,
+		since:           cfg.since,
+		until:           cfg.until,
+		activityAuthors: newActivityAuthors(cfg),
+		reactionWeight:  cfg.reactionWeight,
+		canvasActivity:  cfg.canvasActivity,
+		raw:             newRawSlackClient(cfg, cfg.botToken),
+		incidents:       cfg.incidents,
+		webhook:         webhook,
+		dryRun:          cfg.dryRun,
+		store:           store,
+		adminChannel:    cfg.adminChannel,
+		digestUsers:     cfg.adminDigestUsers,
+		notifyCreator:   cfg.notifyCreator,
+		templates:       cfg.templates,
+		exporter:        exporter,
+		result:          result,
+
+		deactivatedCreatorThreshold: cfg.deactivatedCreatorThreshold,
+		maxJoins:                    cfg.maxJoinsPerRun,
+		joinDelay:                   cfg.joinDelay,
+		directory:                   directory,
+		users:                       map[string]*slack.User{},
+		hooks:                       newHooks(cfg),
+		policy:                      cfg.policy,
+
+		archiveWithoutHumanMembers: cfg.archiveWithoutHumanMembers,
+		archiveMembersDeactivated:  cfg.archiveMembersDeactivated,
+		singleMemberThreshold:      cfg.singleMemberThreshold,
+		retention:                  retention,
+		retentionAction:            cfg.retentionAction,
+		exemptionReminderDays:      cfg.exemptionReminderDays,
+		daemon:                     cfg.daemon,
+		escalationSteps:            cfg.escalationSteps,
+		twoPersonApprovalMembers:   cfg.twoPersonApprovalMembers,
+		twoPersonApprovalAgeDays:   cfg.twoPersonApprovalAgeDays,
+		respectDND:                 cfg.respectDND,
+		recreationWindowDays:       cfg.recreationWindowDays,
+		unarchiveExemptionDays:     cfg.unarchiveExemptionDays,
+		maxScanIntervalDays:        maxScanIntervalDays,
+		historyPageSize:            cfg.historyPageSize,
+		historyMaxPages:            cfg.historyMaxPages,
+		historyInclusive:           cfg.historyInclusive,
+		minActivityMessages:        cfg.minActivityMessages,
+		guestWeight:                cfg.guestWeight,
+		externalWeight:             cfg.externalWeight,
+		privateChannels:            cfg.privateChannels,
+		privateThreshold:           cfg.privateArchiveThreshold,
+		privateWarningDays:         cfg.privateWarningDays,
+		teamID:                     cfg.teamID,
+		exemptionService:           exemptions,
+		groupDirectory:             groupDirectory,
+		exemptGroups:               cfg.exemptGroups,
+		tagsEnabled:                cfg.channelTags,
+		tags:                       map[string]*channelTags{},
+		staleWarnings:              cfg.staleWarnings,
+		countdownWarnings:          cfg.countdownWarnings,
+		nudgeMembers:               cfg.nudgeMembers,
+		warningMentionLevel:        cfg.warningMention,
+		warningMentionMaxMembers:   cfg.warningMentionMaxMembers,
+		admin:                      admin,
+		adminChannels:              map[string]bool{},
+		archiveUser:                archiveUser,
+	}
+}
+
+// inactiveChannel is a channel without user-entered messages for some time
+type inactiveChannel struct {
+	channel slack.Channel
+	// lastActivity is the channel's last activity within the threshold, the zero time if there was none
+	lastActivity time.Time
+	daysInactive int
+	threshold    int
+	archiveDate  time.Time
+	reason       archiveReason
+	// skipExport is set for channels whose history must not be exported, such as those under custom retention
+	skipExport bool
+}
+
+// exemptChannel is a channel skipped because of an exemption
+type exemptChannel struct {
+	channel   slack.Channel
+	exemption exemption
+}
+
+// findInactiveChannels will get all channels that are past the archive threshold, all channels that are
+// within the warning period before the threshold and all channels skipped because they are exempt
+func (a *ArchiveSlacker) findInactiveChannels(ctx context.Context, channels []slack.Channel) ([]inactiveChannel, []inactiveChannel, []exemptChannel) {
+	archivableChannels := []inactiveChannel{}
+	warnableChannels := []inactiveChannel{}
+	exemptChannels := []exemptChannel{}
+
+	now := a.now()
+
+	// Iterate over channels to find channels past auto-archive threshold
+	for _, c := range channels {
+		logger := a.logger.V(1).WithValues("channel", c.Name)
+
+		logger.Info("checking if channel should be archived")
+		e, err := a.evaluateChannel(ctx, c, now)
+		if err == nil {
+			e, err = a.checkCandidateExemptions(ctx, c, e, now)
+		}
+		if err != nil {
+			logger.Error(err, "could not determine if channel is archivable")
+			a.result.addChannel(c, decisionError, "", 0, err)
+			a.notifier.error(ctx, &c, err)
+			continue
+		}
+
+		switch e.decision {
+		case decisionExempt:
+			logger.Info("channel is exempt", "until", e.exemption.Until, "reason", e.exemption.Reason)
+			a.result.addChannel(c, decisionExempt, "", 0, nil)
+			exemptChannels = append(exemptChannels, exemptChannel{channel: c, exemption: *e.exemption})
+			if err := a.cleanUpWarnings(ctx, c, e); err != nil {
+				logger.Error(err, "failed to clean up warnings")
+			}
+		case decisionArchive:
+			archivableChannels = append(archivableChannels, inactiveChannel{
+				channel:      c,
+				daysInactive: e.daysInactive,
+				threshold:    e.threshold,
+				archiveDate:  e.archiveDate,
+				reason:       e.reason,
+				skipExport:   e.skipExport,
+			})
+		case decisionWarn:
+			warnableChannels = append(warnableChannels, inactiveChannel{
+				channel:      c,
+				lastActivity: e.lastActivity,
+				daysInactive: e.daysInactive,
+				threshold:    e.threshold,
+				archiveDate:  e.archiveDate,
+			})
+		default:
+			a.result.addChannel(c, decisionKeep, "", e.daysInactive, nil)
+			if err := a.cleanUpWarnings(ctx, c, e); err != nil {
+				logger.Error(err, "failed to clean up warnings")
+			}
+			if a.adaptiveScan() {
+				if err := a.scheduleNextScan(ctx, c, e, now); err != nil {
+					logger.Error(err, "failed to schedule next scan")
+				}
+			}
+		}
+	}
+
+	return archivableChannels, warnableChannels, exemptChannels
+}
+
+// channelEvaluation is what evaluating a channel decided
+type channelEvaluation struct {
+	decision decision
+	// lastActivity is the last qualifying activity within the threshold, or the zero time if there was none
+	lastActivity time.Time
+	daysInactive int
+	// threshold is the number of days without activity after which the channel is archived
+	threshold int
+	// archiveDate is when the channel is or will be archived, or the zero time if it is exempt
+	archiveDate time.Time
+	reason      archiveReason
+	exemption   *exemption
+	// skipExport is set for channels whose history must not be exported
+	skipExport bool
+	// scheduled is set when the channel was not scanned because its next scan is not due yet
+	scheduled bool
+}
+
+// now will return the time channels are evaluated as of
+func (a *ArchiveSlacker) now() time.Time {
+	if !a.until.IsZero() {
+		return a.until
+	}
+
+	return time.Now()
+}
+
+// evaluateChannel will decide whether a channel should be kept, warned, archived or skipped as exempt
+func (a *ArchiveSlacker) evaluateChannel(ctx context.Context, c slack.Channel, now time.Time) (channelEvaluation, error) {
+	logger := a.logger.V(1).WithValues("channel", c.Name)
+
+	exempt, err := a.activeExemption(ctx, c, now)
+	if err != nil {
+		return channelEvaluation{}, err
+	}
+	if exempt != nil {
+		return channelEvaluation{decision: decisionExempt, exemption: exempt}, nil
+	}
+
+	if a.adaptiveScan() {
+		scheduled, err := a.scheduledEvaluation(ctx, c, now)
+		if err != nil {
+			return channelEvaluation{}, fmt.Errorf("could not get scan schedule: %w", err)
+		}
+		if scheduled != nil {
+			logger.Info("skipping scan, channel can not need attention yet", "last_activity", scheduled.lastActivity)
+			return *scheduled, nil
+		}
+	}
+
+	tags, err := a.channelTags(ctx, c)
+	if err != nil {
+		return channelEvaluation{}, fmt.Errorf("could not get channel tags: %w", err)
+	}
+	if exempt := tags.exemption(c, now); exempt != nil {
+		return channelEvaluation{decision: decisionExempt, exemption: exempt}, nil
+	}
+
+	// Channels with a custom retention policy are usually managed for compliance
+	skipExport := false
+	if a.retention != nil {
+		retention, err := a.retention.getCustomRetention(ctx, c.ID)
+		if err != nil {
+			return channelEvaluation{}, fmt.Errorf("could not get channel retention policy: %w", err)
+		}
+		if retention.Enabled {
+			logger.Info("channel has a custom retention policy", "days", retention.Days)
+			if a.retentionAction == retentionExempt {
+				return channelEvaluation{decision: decisionExempt, exemption: retentionExemption(c, retention)}, nil
+			}
+			skipExport = true
+		}
+	}
 
-	logger := newLogger()
+	// Incident channels are archived a fixed time after the incident is resolved,
+	// open incidents fall back to the inactivity threshold
+	if a.incidents != nil && a.incidents.matches(c) {
+		resolvedAt, err := a.getIncidentResolution(ctx, c, now)
+		if err != nil {
+			return channelEvaluation{}, fmt.Errorf("could not determine if incident is resolved: %w", err)
+		}
 
-	// Get slack tokens and configurations
-	// TODO(dpe): write package to handle config and secret generation
-	appToken := os.Getenv("AUTO_ARCHIVER_APP_TOKEN")
-	botToken := os.Getenv("AUTO_ARCHIVER_BOT_TOKEN")
+		if !resolvedAt.IsZero() {
+			e := channelEvaluation{
+				decision:     decisionKeep,
+				lastActivity: resolvedAt,
+				daysInactive: int(now.Sub(resolvedAt).Hours() / 24),
+				threshold:    a.incidents.days,
+				archiveDate:  resolvedAt.AddDate(0, 0, a.incidents.days),
+			}
+			if now.Before(e.archiveDate) {
+				logger.Info("incident resolved recently", "resolved", resolvedAt)
+				return e, nil
+			}
 
-	verbosityString := os.Getenv("AUTO_ARCHIVER_VERBOSITY")
-	verbosity, err := strconv.Atoi(verbosityString)
-	if err != nil {
-		logger.Error(err, "can not parse verbosity into an int")
-		os.Exit(1)
+			e.decision = decisionArchive
+			e.archiveDate = now
+			e.reason = reasonIncidentResolved
+			return e, nil
+		}
 	}
 
-	logfmtr.SetVerbosity(verbosity)
+	threshold := a.policy.threshold(c, a.channelThreshold(c))
+	if tags != nil && tags.TTL > 0 {
+		logger.Info("channel is tagged with its own threshold", "ttl", tags.TTL)
+		threshold = tags.TTL
+	}
+	warningDays := a.channelWarningDays(c)
+	creatorDeactivated := false
+	if a.deactivatedCreatorThreshold != nil && c.Creator != "" {
+		creatorDeactivated, err = a.isUserDeactivated(ctx, c.Creator)
+		if err != nil {
+			return channelEvaluation{}, fmt.Errorf("could not get channel creator: %w", err)
+		}
+		if creatorDeactivated {
+			logger.Info("channel creator is deactivated", "creator", c.Creator)
+			threshold = *a.deactivatedCreatorThreshold
+		}
+	}
+
+	// Nobody can be using a channel without human members or whose members have all left, however recently bots
+	// posted in it, and channels with one member are usually abandoned personal scratch channels
+	noHumanMembers, membersDeactivated, singleMember := false, false, false
+	if a.archiveWithoutHumanMembers || a.archiveMembersDeactivated || a.singleMemberThreshold != nil {
+		members, err := a.countHumanMembers(ctx, c.ID, 2)
+		if err != nil {
+			return channelEvaluation{}, fmt.Errorf("could not get channel members: %w", err)
+		}
+		if members.humans == 0 && a.archiveWithoutHumanMembers {
+			logger.Info("channel has no human members")
+			noHumanMembers = true
+		}
+		if members.humans > 0 && members.active == 0 && a.archiveMembersDeactivated {
+			logger.Info("every human member of the channel is deactivated")
+			membersDeactivated = true
+		}
+		if members.humans == 1 && a.singleMemberThreshold != nil && *a.singleMemberThreshold < threshold {
+			logger.Info("channel has a single human member")
+			singleMember = true
+			threshold = *a.singleMemberThreshold
+		}
+	}
 
-	archiveThresholdString := os.Getenv("AUTO_ARCHIVER_ARCHIVE_THRESHOLD")
-	archiveThreshold, err := strconv.Atoi(archiveThresholdString)
+	minMessages := a.policy.minActivityMessages(c, a.minActivityMessages)
+	lastActivity, sawMessages, err := a.getLastActivity(ctx, c, now, threshold, minMessages)
 	if err != nil {
-		logger.Error(err, "can not parse archive threshold into an int")
-		os.Exit(1)
+		return channelEvaluation{}, err
 	}
 
-	api := slack.New(
-		botToken,
-		slack.OptionDebug(true),
-		slack.OptionLog(log.New(os.Stdout, "slack client: ", log.Lshortfile|log.LstdFlags)),
-		slack.OptionAppLevelToken(appToken),
-	)
+	// Canvas edits only matter for channels that would otherwise be warned or archived
+	if a.canvasActivity && a.needsAttention(lastActivity, now, threshold, warningDays) {
+		edited, err := a.raw.getCanvasLastEdited(ctx, c.ID)
+		if err != nil {
+			return channelEvaluation{}, fmt.Errorf("could not get channel canvas: %w", err)
+		}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+		if edited.After(lastActivity) && edited.After(a.windowStart(now, threshold)) && !edited.After(now) {
+			logger.Info("canvas edited within the threshold", "edited", edited)
+			lastActivity = edited
+		}
+	}
+
+	if noHumanMembers || membersDeactivated {
+		e := channelEvaluation{
+			decision:     decisionArchive,
+			lastActivity: lastActivity,
+			daysInactive: a.daysInactiveWithoutActivity(c, now, threshold),
+			threshold:    threshold,
+			archiveDate:  now,
+			reason:       reasonNoHumanMembers,
+		}
+		if !lastActivity.IsZero() {
+			e.daysInactive = int(now.Sub(lastActivity).Hours() / 24)
+		}
+		if membersDeactivated {
+			e.reason = reasonMembersDeactivated
+		}
+		e.skipExport = skipExport
 
-	archiveSlacker := NewArchiveSlacker(logger, api, archiveThreshold)
+		return e, nil
+	}
 
-	// get all unarchived channels
-	channels, err := archiveSlacker.getUnarchivedChannels(ctx)
-	if err != nil {
-		logger.Error(err, "failed to get channels")
-		os.Exit(1)
+	// No user-entered message within the threshold means the channel is archivable
+	if lastActivity.IsZero() {
+		reason := reasonNoHumanMessages
+		if !sawMessages {
+			reason = reasonEmptyChannel
+		}
+		if creatorDeactivated {
+			reason = reasonCreatorDeactivated
+		}
+		if singleMember {
+			reason = reasonSingleMember
+		}
+
+		return channelEvaluation{
+			decision:     decisionArchive,
+			daysInactive: a.daysInactiveWithoutActivity(c, now, threshold),
+			threshold:    threshold,
+			archiveDate:  now,
+			reason:       reason,
+			skipExport:   skipExport,
+		}, nil
+	}
+
+	e := channelEvaluation{
+		decision:     decisionKeep,
+		lastActivity: lastActivity,
+		daysInactive: int(now.Sub(lastActivity).Hours() / 24),
+		threshold:    threshold,
+		archiveDate:  lastActivity.AddDate(0, 0, threshold),
+	}
+	if a.needsAttention(lastActivity, now, threshold, warningDays) {
+		e.decision = decisionWarn
+	}
+
+	return e, nil
+}
+
+// activeExemption will return the exemption of a channel in effect at now, or nil if it has none
+func (a *ArchiveSlacker) activeExemption(ctx context.Context, c slack.Channel, now time.Time) (*exemption, error) {
+	if e := a.policy.exemption(c, now); e != nil {
+		return e, nil
+	}
+	if e := a.pinned.exemption(c, now); e != nil {
+		return e, nil
 	}
 
-	// Checking if there are any new public channels to join
-	// auto-archiver must be added to private channels manually if you wish to auto-archive
-	if err := archiveSlacker.joinPublicChannels(ctx, channels); err != nil {
-		logger.Error(err, "failed to join new public channels")
-		os.Exit(1)
+	if a.store == nil {
+		return nil, nil
 	}
 
-	// Find all channels that auto-archiver is a member and is older than archive threshold and archive them
-	archiveableChannels := archiveSlacker.findArchivableChannels(ctx, channels)
+	e, err := getExemption(ctx, a.store, c.ID)
 	if err != nil {
-		logger.Error(err, "failed to get channels past auto-archive threshold")
+		return nil, fmt.Errorf("could not get channel exemption: %w", err)
+	}
+	if e == nil || !e.activeAt(now) {
+		return nil, nil
 	}
 
-	for _, c := range archiveableChannels {
-		logger.Info("archiving channel", "channel", c.Name)
-		if err := archiveSlacker.autoarchiveChannel(ctx, c); err != nil {
-			logger.Error(err, "failed to archive channel", "channel", c.Name)
-			continue
-		}
+	return e, nil
+}
+
+// needsAttention will report whether a channel last active at lastActivity should be warned or archived
+func (a *ArchiveSlacker) needsAttention(lastActivity, now time.Time, threshold, warningDays int) bool {
+	if lastActivity.IsZero() {
+		return true
 	}
+
+	archiveDate := lastActivity.AddDate(0, 0, threshold)
+	return warningDays > 0 && now.AddDate(0, 0, warningDays).After(archiveDate)
 }
 
-type ArchiveSlacker struct {
-	logger    logr.Logger
-	client    *slack.Client
-	threshold int
+// windowStart will return the oldest time that activity is searched for from
+func (a *ArchiveSlacker) windowStart(now time.Time, threshold int) time.Time {
+	if !a.since.IsZero() {
+		return a.since
+	}
+
+	return now.AddDate(0, 0, (threshold * -1))
 }
 
-func NewArchiveSlacker(logger logr.Logger, client *slack.Client, threshold int) *ArchiveSlacker {
-	return &ArchiveSlacker{
-		logger:    logger,
-		client:    client,
-		threshold: threshold,
+// getLastActivity will return the time of the most recent user-entered message within the archive threshold,
+// or the zero time if there is none, and whether any messages at all were posted within the threshold. When
+// minMessages are needed to be active, it is the time of the oldest of the minMessages most recent messages.
+func (a *ArchiveSlacker) getLastActivity(ctx context.Context, c slack.Channel, now time.Time, threshold, minMessages int) (time.Time, bool, error) {
+	logger := a.logger.V(1).WithValues("channel", c.Name)
+
+	// Only the last activity is tracked and remembered from warnings, not how many messages there were or by whom
+	windowStart := a.windowStart(now, threshold)
+	// The history of channels the bot could not join can not be read, only when they were last posted in
+	if a.adminChannels[c.ID] {
+		logger.Info("getting last activity from the admin API")
+		return a.getAdminLastActivity(ctx, c, windowStart)
+	}
+	if minMessages > 1 || a.weighsAuthors() {
+		return a.readLastActivity(ctx, logger, c, windowStart, minMessages)
+	}
+
+	lastActivity, sawMessages, tracked, err := a.getTrackedActivity(ctx, c, now, threshold)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if tracked {
+		logger.Info("using tracked activity")
+		return lastActivity, sawMessages, nil
+	}
+
+	// Channels warned before only need the history since, the older history was read when they were warned
+	warned, err := a.getWarnedChannel(ctx, c, windowStart)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if warned == nil {
+		return a.readLastActivity(ctx, logger, c, windowStart, minMessages)
+	}
+
+	logger.Info("reading history since the channel was warned", "warned_at", warned.WarnedAt)
+	lastActivity, sawMessages, err = a.readLastActivity(ctx, logger, c, warned.WarnedAt, minMessages)
+	if err != nil || !lastActivity.IsZero() {
+		return lastActivity, sawMessages, err
 	}
+	// Without newer activity, the activity found when the channel was warned is still its last
+	if warned.LastActivity.After(windowStart) {
+		return warned.LastActivity, true, nil
+	}
+
+	// Once that activity is past the threshold, whether anything else was posted decides the archive reason
+	return a.readLastActivity(ctx, logger, c, windowStart, minMessages)
 }
 
-// findArchivableChannels will get all channels that are past the ArchiverDaysThreshold
-func (a *ArchiveSlacker) findArchivableChannels(ctx context.Context, channels []slack.Channel) []slack.Channel {
-	archivableChannels := []slack.Channel{}
+// readLastActivity will read a channel's history from oldest for its last activity, a page at a time until activity
+// is found, returning it or the zero time if there is none, and whether there were any messages
+func (a *ArchiveSlacker) readLastActivity(ctx context.Context, logger logr.Logger, c slack.Channel, oldest time.Time, minMessages int) (time.Time, bool, error) {
+	params := &slack.GetConversationHistoryParameters{
+		ChannelID: c.ID,
+		Oldest:    strconv.Itoa(int(oldest.Unix())),
+		Inclusive: a.historyInclusive,
+		Limit:     a.historyPageSize,
+	}
 
-	// Iterate over channels to find channels past auto-archive threshold
-	for _, c := range channels {
-		logger := a.logger.V(1).WithValues("channel", c.Name)
+	// Evaluating as of a past time ignores anything posted after it
+	if !a.until.IsZero() {
+		params.Latest = strconv.Itoa(int(a.until.Unix()))
+	}
 
-		logger.Info("checking if channel should be archived")
-		archivable, err := a.isChannelArchivable(ctx, c)
+	messages := []slack.Message{}
+	for page := 1; ; page++ {
+		logger.Info("getting channels message history", "page", page)
+		response, err := a.client.GetConversationHistoryContext(ctx, params)
 		if err != nil {
-			logger.Error(err, "could not determine if channel is archivable")
+			return time.Time{}, false, err
+		}
+		messages = append(messages, response.Messages...)
+
+		lastActivity, sawMessages, err := a.lastActivityIn(ctx, logger, messages, minMessages)
+		if err != nil || !lastActivity.IsZero() {
+			return lastActivity, sawMessages, err
+		}
+
+		if !response.HasMore || response.ResponseMetaData.NextCursor == "" || page == a.historyMaxPages {
+			return lastActivity, sawMessages, nil
+		}
+		params.Cursor = response.ResponseMetaData.NextCursor
+	}
+}
+
+// lastActivityIn will return the time of the most recent activity in messages ordered newest first,
+// or the zero time if there is none, and whether there were any messages. A channel needs minMessages messages
+// of activity to be active, so its activity is the time of the minMessages-th most recent one. Messages whose
+// author weighs less only count as one once their combined weight reaches that of one message.
+func (a *ArchiveSlacker) lastActivityIn(ctx context.Context, logger logr.Logger, messages []slack.Message, minMessages int) (time.Time, bool, error) {
+	// Reactions to messages that are not activity themselves, such as bot announcements, are a weak signal
+	// that only counts as a message once their combined weight reaches that of one message.
+	// Slack does not say when a reaction was added, so the reacted message's time is used instead.
+	reactionScore := 0.0
+	reactedAt := ""
+	messageScore := 0.0
+	active := 0
+
+	// Messages are returned newest first, so the first user-entered messages are the last activity
+	for _, m := range messages {
+		logger.Info("messages", "message", m.Text, "subtype", m.SubType)
+		if isActivity(m, a.activityAuthors) {
+			weight, err := a.messageWeight(ctx, m)
+			if err != nil {
+				return time.Time{}, false, fmt.Errorf("could not get message author: %w", err)
+			}
+			if messageScore += weight; messageScore < 1 {
+				continue
+			}
+			messageScore = 0
+			active++
+			if active < minMessages {
+				continue
+			}
+			lastActivity, err := parseSlackTimestamp(m.Timestamp)
+			return lastActivity, true, err
+		}
+
+		if a.reactionWeight == 0 || len(m.Reactions) == 0 {
 			continue
 		}
 
-		if archivable {
-			archivableChannels = append(archivableChannels, c)
+		if reactedAt == "" {
+			reactedAt = m.Timestamp
+		}
+		for _, r := range m.Reactions {
+			reactionScore += float64(r.Count) * a.reactionWeight
+		}
+
+		if reactionScore >= 1 {
+			active++
+			if active < minMessages {
+				reactionScore, reactedAt = 0, ""
+				continue
+			}
+			lastActivity, err := parseSlackTimestamp(reactedAt)
+			return lastActivity, true, err
 		}
 	}
 
-	return archivableChannels
+	return time.Time{}, len(messages) > 0, nil
 }
 
-// isChannelArchivable will validate if a channel is archivable
-func (a *ArchiveSlacker) isChannelArchivable(ctx context.Context, c slack.Channel) (bool, error) {
-	logger := a.logger.V(1).WithValues("channel", c.Name)
+// activityAuthors are whose messages count as activity beyond messages from people
+type activityAuthors struct {
+	// bots are the bot and bot user IDs whose messages count as activity, all bots count when empty
+	bots map[string]bool
+	// always are the users whose messages count as activity like a person's, even when they are bots, and never
+	// those whose messages do not count even when they are people, such as a janitor account posting reminders
+	always map[string]bool
+	never  map[string]bool
+	// ignoreReminders and ignoreWorkflows ignore the reminders Slackbot posts and the posts of Workflow Builder
+	ignoreReminders bool
+	ignoreWorkflows bool
+}
 
-	// Calcuated the oldest UNIX timestamp to search for in a channels message history
-	oldestTS := time.Now().AddDate(0, 0, (a.threshold * -1)).Unix()
+// workflowBuilderName is the bot profile name of messages posted by Workflow Builder workflows
+const workflowBuilderName = "Workflow Builder"
 
-	// Get message history of a channel before the time threshold
-	logger.Info("getting channels message history")
-	response, err := a.client.GetConversationHistoryContext(ctx, &slack.GetConversationHistoryParameters{
-		ChannelID: c.ID,
-		Oldest:    strconv.Itoa(int(oldestTS)),
-	})
+// newActivityAuthors will read whose messages count as activity from the config
+func newActivityAuthors(cfg *config) activityAuthors {
+	authors := activityAuthors{
+		bots:            map[string]bool{},
+		always:          map[string]bool{},
+		never:           map[string]bool{},
+		ignoreReminders: !cfg.countReminders,
+		ignoreWorkflows: !cfg.countWorkflows,
+	}
+	for _, id := range cfg.activityBots {
+		authors.bots[id] = true
+	}
+	for _, id := range cfg.activityUsers {
+		authors.always[id] = true
+	}
+	for _, id := range cfg.ignoredUsers {
+		authors.never[id] = true
+	}
+
+	return authors
+}
+
+// isActivity will report whether a message keeps a channel active. Messages from people always count,
+// bot messages count if the bot is allowlisted or, when no allowlist is configured, always. Messages of ignored
+// users never count, nor reminders and workflow posts when they are ignored.
+func isActivity(m slack.Message, authors activityAuthors) bool {
+	if m.SubType != "" && m.SubType != "bot_message" {
+		return false
+	}
+
+	if authors.never[m.User] || authors.never[m.BotID] {
+		return false
+	}
+	// Slackbot posts the reminders set with /remind, which are otherwise counted like a person's messages
+	if authors.ignoreReminders && m.User == slackbotUserID {
+		return false
+	}
+	if authors.ignoreWorkflows && m.BotProfile != nil && m.BotProfile.Name == workflowBuilderName {
+		return false
+	}
+
+	if m.SubType == "" && m.BotID == "" {
+		return true
+	}
+
+	if len(authors.bots) == 0 {
+		return true
+	}
+
+	return authors.bots[m.BotID] || authors.bots[m.User] || authors.always[m.User] || authors.always[m.BotID]
+}
+
+// daysInactiveWithoutActivity will return how long a channel with no activity within the threshold has been inactive.
+// Channels younger than the threshold have been inactive since they were created.
+func (a *ArchiveSlacker) daysInactiveWithoutActivity(c slack.Channel, now time.Time, threshold int) int {
+	days := int(now.Sub(c.Created.Time()).Hours() / 24)
+	if days < threshold {
+		return days
+	}
+
+	return threshold
+}
+
+// isUserDeactivated will report whether a user has been deactivated
+func (a *ArchiveSlacker) isUserDeactivated(ctx context.Context, userID string) (bool, error) {
+	user, err := a.getUser(ctx, userID)
 	if err != nil {
 		return false, err
 	}
 
-	// If user-entered message in channel history then not archivable else is archivable
-	messages := response.Messages
-	for _, m := range messages {
-		logger.Info("messages", "message", m.Text, "subtype", m.SubType)
-		if m.SubType == "" || m.SubType == "bot_message" {
-			return false, nil
-		}
+	return user.Deleted, nil
+}
+
+// getUser will get a user, remembering them for the rest of the run
+func (a *ArchiveSlacker) getUser(ctx context.Context, userID string) (*slack.User, error) {
+	if user, ok := a.users[userID]; ok {
+		return user, nil
+	}
+
+	user, err := a.client.GetUserInfoContext(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	return true, nil
+	a.users[userID] = user
+	return user, nil
 }
 
 // getUnarchivedChannels will get all public channels or private channels auto-archiver is a member of
@@ -151,47 +1690,351 @@ func (a *ArchiveSlacker) getUnarchivedChannels(ctx context.Context) ([]slack.Cha
 	channels := []slack.Channel{}
 
 	logger.Info("getting channels")
-	moreChannels, _, err := a.client.GetConversationsContext(ctx, &slack.GetConversationsParameters{ExcludeArchived: true})
+	params := &slack.GetConversationsParameters{ExcludeArchived: true, Types: channelTypes(a.privateChannels), Limit: 1000, TeamID: a.teamID}
+	for {
+		moreChannels, cursor, err := a.client.GetConversationsContext(ctx, params)
+		if err != nil {
+			return channels, err
+		}
+
+		channels = append(channels, moreChannels...)
+		if cursor == "" {
+			return channels, nil
+		}
+		params.Cursor = cursor
+	}
+}
+
+// autoarchiveChannel will back up the channel if exporting is enabled, post message to channel
+// indicating it is being archived and then the channel will be archived
+func (a *ArchiveSlacker) autoarchiveChannel(ctx context.Context, c inactiveChannel) error {
+	if err := a.prepareArchive(ctx, c); err != nil {
+		return err
+	}
+
+	var err error
+	switch a.archivePath(c.channel.ID) {
+	case archivePathAdmin:
+		err = a.admin.adminArchive(ctx, c.channel.ID)
+	case archivePathUser:
+		err = a.archiveUser.archiveConversation(ctx, c.channel.ID)
+	default:
+		err = a.client.ArchiveConversationContext(ctx, c.channel.ID)
+	}
 	if err != nil {
-		return channels, err
+		// TODO(dpe): write message if failed to archive
+		return err
+	}
+
+	a.finishArchive(ctx, c)
+
+	return nil
+}
+
+// prepareArchive will back up a channel if exporting is enabled and post the notice that it is being archived
+func (a *ArchiveSlacker) prepareArchive(ctx context.Context, c inactiveChannel) error {
+	// A channel is never archived without its backup when exporting is enabled, unless its history must not be kept
+	if a.exporter != nil && !c.skipExport {
+		location, err := a.exporter.exportChannel(ctx, c.channel, c.archiveDate)
+		if err != nil {
+			return fmt.Errorf("can not export channel: %w", err)
+		}
+		a.logger.V(1).Info("exported channel", "channel", c.channel.Name, "location", location)
+	}
+
+	// The bot can not post in channels it could not join, so they are archived without a notice
+	if a.adminChannels[c.channel.ID] {
+		return nil
+	}
+
+	return a.postMessage(ctx, c.channel.ID, a.templates.archiveNotice, a.messageData(c))
+}
+
+// finishArchive will record a channel that was archived, run the post-archive hook and notify about it
+func (a *ArchiveSlacker) finishArchive(ctx context.Context, c inactiveChannel) {
+	// Archived channels are remembered to report channels that are created again soon after
+	if a.store != nil {
+		if err := a.recordArchived(ctx, c, time.Now()); err != nil {
+			a.logger.Error(err, "failed to record archived channel", "channel", c.channel.Name)
+		}
+	}
+
+	// The channel is already archived, so a failing post-archive hook is only logged
+	if _, err := a.runHook(ctx, hookPostArchive, c); err != nil {
+		a.logger.Error(err, "failed to run post-archive hook", "channel", c.channel.Name)
 	}
 
-	channels = append(channels, moreChannels...)
+	a.notifier.archived(ctx, a.messageData(c))
+}
+
+// isArchiveAllowed will ask the decision webhook and then the pre-archive hook, as they are configured,
+// whether a channel may be archived. A channel the webhook denies is exempted, returning the exemption so it is
+// reported as exempt. Dry runs do not ask the webhook, as it may act on the candidates it is sent.
+func (a *ArchiveSlacker) isArchiveAllowed(ctx context.Context, c inactiveChannel) (bool, *exemption, error) {
+	if a.webhook != nil && !a.dryRun {
+		decision, err := a.webhook.decide(ctx, webhookRequest{
+			Channel:      c.channel,
+			Reason:       c.reason,
+			DaysInactive: c.daysInactive,
+			Threshold:    c.threshold,
+			RunID:        a.result.RunID,
+		})
+		if err != nil {
+			return false, nil, err
+		}
 
-	return channels, nil
+		switch decision.Decision {
+		case webhookDeny:
+			a.logger.Info("decision webhook exempted channel", "channel", c.channel.Name, "until", decision.Until, "reason", decision.Reason)
+			e := exemption{
+				ChannelID:   c.channel.ID,
+				ChannelName: c.channel.Name,
+				Until:       decision.Until,
+				Reason:      decision.Reason,
+				ExemptedBy:  webhookExemptedBy,
+				CreatedAt:   time.Now(),
+			}
+			if a.store != nil {
+				if err := putExemption(ctx, a.store, e); err != nil {
+					a.logger.Error(err, "failed to record exemption", "channel", c.channel.Name)
+				}
+			}
+			return false, &e, nil
+		case webhookDefer:
+			a.logger.Info("decision webhook deferred channel", "channel", c.channel.Name, "reason", decision.Reason)
+			return false, nil, nil
+		}
+	}
 
+	allowed, err := a.runHook(ctx, hookPreArchive, c)
+	return allowed, nil, err
 }
 
-// autoarchiveChannel will post message to channel indicating it is being archived
-// and then the channel will be archived
-func (a *ArchiveSlacker) autoarchiveChannel(ctx context.Context, c slack.Channel) error {
-	err := a.client.ArchiveConversationContext(ctx, c.ID)
+// warnChannel will post message to channel indicating it will soon be archived. With countdown warnings, the warning
+// posted before is updated in place instead, so the channel is not notified again every run. When nudging, the most
+// recently active members are asked privately instead, and the channel is only warned if none of them can be.
+func (a *ArchiveSlacker) warnChannel(ctx context.Context, c inactiveChannel) error {
+	data := a.messageData(c)
+	// Channels the bot could not join can not be warned in, only their owners are notified
+	if a.adminChannels[c.channel.ID] {
+		a.logger.V(1).Info("not posting warning in channel the bot could not join", "channel", c.channel.Name)
+		a.notifier.warn(ctx, data)
+		return nil
+	}
+	if a.nudgeMembers > 0 {
+		nudged, err := a.nudgeChannel(ctx, c)
+		if err != nil {
+			return fmt.Errorf("could not nudge members: %w", err)
+		}
+		if nudged {
+			if err := a.recordWarning(ctx, c, time.Now(), ""); err != nil {
+				a.logger.Error(err, "failed to record warning", "channel", c.channel.Name)
+			}
+			a.notifier.warn(ctx, data)
+			return nil
+		}
+	}
+
+	text, err := render(a.templates.warning, data)
+	if err != nil {
+		return err
+	}
+	if mention := a.warningMention(c.channel); mention != "" {
+		text = mention + " " + text
+	}
+
+	ts, err := a.updateWarning(ctx, c.channel, text)
 	if err != nil {
-		// TODO(dpe): write message if failed to archive
 		return err
 	}
+	if ts == "" {
+		if _, ts, err = a.client.PostMessageContext(ctx, c.channel.ID, slack.MsgOptionText(text, false)); err != nil {
+			return err
+		}
+	}
+
+	if a.store != nil {
+		if err := a.recordWarning(ctx, c, time.Now(), ts); err != nil {
+			a.logger.Error(err, "failed to record warning", "channel", c.channel.Name)
+		}
+	}
+
+	a.notifier.warn(ctx, data)
+
 	return nil
 }
 
-// joinPublicChannels will join any public channels they are not yet part of
-func (a *ArchiveSlacker) joinPublicChannels(ctx context.Context, channels []slack.Channel) error {
-	logger := a.logger.V(1)
-	for _, c := range channels {
-		if !c.IsMember {
-			logger.Info("auto-archiver is not a member of public channel, joining channel.", "channel", c.Name)
-			_, _, _, err := a.client.JoinConversationContext(ctx, c.ID)
+// warningMention will return the @here or @channel mention warnings of a channel start with, or an empty string for
+// none. Channels with more members than the mention's limit are not mentioned, as it would notify too many people.
+func (a *ArchiveSlacker) warningMention(c slack.Channel) string {
+	if a.warningMentionMaxMembers > 0 && c.NumMembers > a.warningMentionMaxMembers {
+		return ""
+	}
+
+	switch a.warningMentionLevel {
+	case mentionHere:
+		return "<!here>"
+	case mentionChannel:
+		return "<!channel>"
+	}
+	return ""
+}
+
+// messageData will build the template data for a message about an inactive channel
+func (a *ArchiveSlacker) messageData(c inactiveChannel) channelMessageData {
+	data := a.templates.channelData(c.channel, c.daysInactive, c.threshold, c.archiveDate)
+	data.Reason = c.reason
+	data.ReasonDescription = c.reason.Description()
+	data.RunID = a.result.RunID
+	data.DaysLeft = max(int(math.Ceil(time.Until(c.archiveDate).Hours()/24)), 0)
+
+	return data
+}
+
+// notifyChannelOwner will send a direct message to an owner of an archived channel
+func (a *ArchiveSlacker) notifyChannelOwner(ctx context.Context, owner string, data channelMessageData) error {
+	return a.sendDirectMessage(ctx, owner, a.templates.dm, data)
+}
+
+// sendDirectMessage will render tmpl with data and send the result to a user. When respecting Do Not Disturb, a
+// user who has it on gets the message once it ends instead.
+func (a *ArchiveSlacker) sendDirectMessage(ctx context.Context, userID string, tmpl *template.Template, data any) error {
+	return a.deliverDirectMessage(ctx, userID, tmpl, data, a.respectDND)
+}
+
+// deliverDirectMessage will render tmpl with data and send the result to a user, holding it until their Do Not
+// Disturb ends when respectDND is set
+func (a *ArchiveSlacker) deliverDirectMessage(ctx context.Context, userID string, tmpl *template.Template, data any, respectDND bool) error {
+	dm, _, _, err := a.client.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{userID}})
+	if err != nil {
+		return err
+	}
+
+	if respectDND {
+		end, err := a.userDNDEnd(ctx, userID, time.Now())
+		if err != nil {
+			return fmt.Errorf("can not get do not disturb status: %w", err)
+		}
+		if !end.IsZero() {
+			text, err := render(tmpl, data)
 			if err != nil {
 				return err
 			}
+			a.logger.V(1).Info("scheduling direct message for after do not disturb", "user", userID, "post_at", end)
+			_, _, err = a.client.ScheduleMessageContext(ctx, dm.ID, strconv.FormatInt(end.Unix(), 10), slack.MsgOptionText(text, false))
+			return err
 		}
 	}
 
-	return nil
+	return a.postMessage(ctx, dm.ID, tmpl, data)
+}
+
+// postSummary will post a summary of the run to the admin channel, if one is configured
+func (a *ArchiveSlacker) postSummary(ctx context.Context, summary summaryMessageData) error {
+	if a.adminChannel == "" {
+		return nil
+	}
+
+	return a.postMessage(ctx, a.adminChannel, a.templates.summary, summary)
+}
+
+// postMessage will render tmpl with data and post the result to a channel
+func (a *ArchiveSlacker) postMessage(ctx context.Context, channelID string, tmpl *template.Template, data any) error {
+	text, err := render(tmpl, data)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = a.client.PostMessageContext(ctx, channelID, slack.MsgOptionText(text, false))
+	return err
+}
+
+// joinPublicChannels will join any public channels they are not yet part of, except exempt channels which
+// would be skipped anyway. At most the maximum joins per run are joined, so it returns the channels that can be
+// evaluated this run, leaving the rest to be joined by later runs.
+func (a *ArchiveSlacker) joinPublicChannels(ctx context.Context, channels []slack.Channel) ([]slack.Channel, error) {
+	logger := a.logger.V(1)
+	now := a.now()
+
+	available := []slack.Channel{}
+	joined := 0
+	skipped := 0
+	for _, c := range channels {
+		if c.IsMember {
+			available = append(available, c)
+			continue
+		}
+
+		e, err := a.activeExemption(ctx, c, now)
+		if err != nil {
+			return nil, err
+		}
+		if e != nil {
+			logger.Info("not joining exempt public channel", "channel", c.Name)
+			available = append(available, c)
+			continue
+		}
+
+		if a.maxJoins > 0 && joined >= a.maxJoins {
+			skipped++
+			continue
+		}
+
+		// Spacing joins out avoids rate limits and a burst of join messages
+		if joined > 0 && a.joinDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(a.joinDelay):
+			}
+		}
+
+		logger.Info("auto-archiver is not a member of public channel, joining channel.", "channel", c.Name)
+		_, _, _, err = a.client.JoinConversationContext(ctx, c.ID)
+		if err != nil && a.admin != nil && joinRestricted(err) {
+			a.logger.Info("can not join public channel, falling back to the admin API", "channel", c.Name, "error", err)
+			a.adminChannels[c.ID] = true
+			available = append(available, c)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		a.result.addJoined()
+		joined++
+		available = append(available, c)
+	}
+
+	if skipped > 0 {
+		a.logger.Info("reached the maximum joins per run, remaining channels will be joined by later runs",
+			"joined", joined, "remaining", skipped)
+	}
+
+	return available, nil
+}
+
+// parseSlackTimestamp will convert a Slack message timestamp such as "1355517523.000005" into a time
+func parseSlackTimestamp(ts string) (time.Time, error) {
+	seconds, _, _ := strings.Cut(ts, ".")
+	unix, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("can not parse message timestamp %q: %w", ts, err)
+	}
+
+	return time.Unix(unix, 0), nil
 }
 
-func newLogger() logr.Logger {
+// newLogger will return a logger writing to w, with Slack tokens redacted from everything it writes
+func newLogger(w io.Writer) logr.Logger {
 	opts := logfmtr.DefaultOptions()
+	opts.Writer = newRedactingWriter(w)
 	opts.Humanize = true
 	opts.AddCaller = true
 	return logfmtr.NewWithOptions(opts)
 }
+
+// newStdLogger will return a standard library logger writing through logger, so the logs of the Slack SDK are
+// redacted like every other log
+func newStdLogger(logger logr.Logger, name string) *log.Logger {
+	return slog.NewLogLogger(logr.ToSlogHandler(logger.WithName(name)), slog.LevelInfo)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+
+	"github.com/slack-go/slack"
+)
+
+// slackbotUserID is Slackbot, which is a member of some channels but is not reported as a bot
+const slackbotUserID = "USLACKBOT"
+
+// forEachMember will call f with the ID of each member of a channel until f returns false
+func (a *ArchiveSlacker) forEachMember(ctx context.Context, channelID string, f func(userID string) (bool, error)) error {
+	params := &slack.GetUsersInConversationParameters{ChannelID: channelID, Limit: 1000}
+	for {
+		ids, cursor, err := a.client.GetUsersInConversationContext(ctx, params)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			more, err := f(id)
+			if err != nil || !more {
+				return err
+			}
+		}
+
+		if cursor == "" {
+			return nil
+		}
+		params.Cursor = cursor
+	}
+}
+
+// memberCounts are how many of a channel's members are people, and how many of those are not deactivated
+type memberCounts struct {
+	humans int
+	active int
+}
+
+// countHumanMembers will count the members of a channel that are not bots. Counting stops once there are limit
+// humans, at least one of them active, so large channels do not need every member looked up.
+func (a *ArchiveSlacker) countHumanMembers(ctx context.Context, channelID string, limit int) (memberCounts, error) {
+	counts := memberCounts{}
+	err := a.forEachMember(ctx, channelID, func(userID string) (bool, error) {
+		if userID == slackbotUserID {
+			return true, nil
+		}
+
+		user, err := a.getUser(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		if user.IsBot {
+			return true, nil
+		}
+
+		counts.humans++
+		if !user.Deleted {
+			counts.active++
+		}
+
+		return counts.humans < limit || counts.active == 0, nil
+	})
+
+	return counts, err
+}
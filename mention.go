@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// mentionHelp is the reply to "@auto-archiver help" and to mentions auto-archiver does not understand
+const mentionHelp = "You can ask me:\n" +
+	"• `status`: whether this channel is at risk of being archived\n" +
+	"• `keep 90d [reason]`: exempt this channel for 90 days, or `keep forever [reason]` to exempt it permanently\n" +
+	"• `help`: show this message"
+
+var (
+	// mentionPattern matches user mentions, such as the mention of auto-archiver that starts a command
+	mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+(\|[^>]*)?>`)
+	// keepDurationPattern matches the duration of a keep command, in days
+	keepDurationPattern = regexp.MustCompile(`^(\d+)d$`)
+)
+
+// handleMention will reply in thread to a message that mentions auto-archiver
+func (d *daemon) handleMention(ctx context.Context, ev *slackevents.AppMentionEvent, teamID string) {
+	// Ignore bots, including auto-archiver's own messages
+	if ev.BotID != "" {
+		return
+	}
+
+	logger := d.logger.WithValues("channel", ev.Channel, "user", ev.User)
+
+	fields := strings.Fields(mentionPattern.ReplaceAllString(ev.Text, ""))
+	command := ""
+	if len(fields) > 0 {
+		command = strings.ToLower(fields[0])
+	}
+
+	var reply string
+	switch command {
+	case "status":
+		text, err := d.channelStatus(ctx, ev.Channel, teamID)
+		if err != nil {
+			logger.Error(err, "failed to get channel status")
+			text = "Something went wrong getting the status of this channel, please try again."
+		}
+		reply = text
+	case "keep":
+		reply = d.keepFromMention(ctx, ev, fields[1:])
+	default:
+		reply = mentionHelp
+	}
+
+	threadTS := ev.ThreadTimeStamp
+	if threadTS == "" {
+		threadTS = ev.TimeStamp
+	}
+	if _, _, err := d.api.PostMessageContext(ctx, ev.Channel, slack.MsgOptionText(reply, false), slack.MsgOptionTS(threadTS)); err != nil {
+		logger.Error(err, "failed to reply to mention")
+	}
+}
+
+// keepFromMention will exempt the channel of a "keep <duration> [reason]" mention, returning the reply
+func (d *daemon) keepFromMention(ctx context.Context, ev *slackevents.AppMentionEvent, args []string) string {
+	logger := d.logger.WithValues("channel", ev.Channel, "user", ev.User)
+
+	if len(args) == 0 {
+		return "Tell me how long to keep this channel for, such as `keep 90d` or `keep forever`."
+	}
+
+	days := 0
+	if duration := strings.ToLower(args[0]); duration != "forever" {
+		match := keepDurationPattern.FindStringSubmatch(duration)
+		if match == nil {
+			return fmt.Sprintf("I don't understand %q, use a number of days such as `keep 90d` or `keep forever`.", args[0])
+		}
+		days, _ = strconv.Atoi(match[1])
+		if days == 0 {
+			return "Keep this channel for at least 1 day, or use `keep forever`."
+		}
+	}
+
+	authorized, err := d.isAuthorized(ctx, ev.User)
+	if err != nil {
+		logger.Error(err, "failed to check if user may exempt channels")
+		return "Something went wrong, please try again."
+	}
+	if !authorized {
+		return "You are not allowed to exempt channels."
+	}
+
+	e, err := d.exemptChannel(ctx, ev.Channel, ev.User, days, strings.Join(args[1:], " "))
+	if err != nil {
+		logger.Error(err, "failed to store exemption")
+		return "Something went wrong, please try again."
+	}
+
+	logger.Info("exempted channel", "until", e.Until, "reason", e.Reason)
+
+	return "This channel is now exempt from auto-archiving " + describeExemption(e) + "."
+}
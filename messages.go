@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	defaultWarningTemplate = "This channel has had no activity for {{.DaysInactive}} days and will be archived on {{.ArchiveDate}}, " +
+		"in {{.DaysLeft}} days. Post a message to keep it around."
+	defaultArchiveNoticeTemplate = "This channel has had no activity for {{.DaysInactive}} days and is being archived " +
+		"({{.ReasonDescription}}). {{.UnarchiveHowTo}}"
+	defaultDMTemplate = "#{{.Channel.Name}}, a channel you created, has had no activity for {{.DaysInactive}} days " +
+		"and was archived on {{.ArchiveDate}}. {{.UnarchiveHowTo}}"
+	defaultEscalationTemplate = "#{{.Channel.Name}} was created by <@{{.Channel.Creator}}>, who has left. As their manager, " +
+		"you are being told that it has had no activity for {{.DaysInactive}} days and will be archived on {{.ArchiveDate}}. " +
+		"Post a message in it to keep it around."
+	defaultSummaryTemplate = "auto-archiver run {{.RunID}} finished: {{len .Archived}} archived, {{len .Warned}} warned, {{len .Failed}} failed, " +
+		"{{len .Exempt}} exempt." +
+		"{{range .Archived}}\n• archived #{{.Name}} ({{.Reason}}){{end}}" +
+		"{{range .Warned}}\n• warned #{{.Name}}{{end}}" +
+		"{{range .Failed}}\n• failed #{{.Name}}{{end}}" +
+		"{{range .Duplicates}}\n• #{{.Dormant.Name}} ({{.DaysInactive}} days inactive) shares {{.Overlap}}% of its members " +
+		"with #{{.Active.Name}}, consider archiving it or merging it into #{{.Active.Name}}{{end}}"
+	defaultOwnerEscalationTemplate = "#{{.Channel.Name}}, a channel you own, has had no activity for {{.DaysInactive}} days " +
+		"and will be archived. Post a message in it to keep it around."
+	defaultAdminEscalationTemplate = "Nobody has responded to the warnings in #{{.Channel.Name}}, which has had no activity for " +
+		"{{.DaysInactive}} days. It will be archived unless it is exempted."
+	defaultExemptionReminderTemplate = "Your exemption of #{{.Exemption.ChannelName}} from auto-archiving ends on {{.UntilDate}}, " +
+		"in {{.DaysLeft}} days. Renew it if the channel should still be kept, otherwise it will be archived once it is inactive."
+	defaultApprovalRequestTemplate = "#{{.Channel.Name}} has had no activity for {{.DaysInactive}} days and is ready to be archived " +
+		"({{.ReasonDescription}}). It is archived on the next run once {{.Required}} of you approve it."
+	defaultWarningResolvedTemplate = "~This channel had no activity for a while and was going to be archived.~ " +
+		"It is active again, so it will not be archived."
+	defaultNudgeTemplate = "Do you still need #{{.Channel.Name}}? It has had no activity for {{.DaysInactive}} days and will be " +
+		"archived on {{.ArchiveDate}}, in {{.DaysLeft}} days."
+	defaultUnarchiveHowTo = "To bring it back, open the channel from the channel browser and select \"Unarchive channel\"."
+
+	// archiveDateLayout is the format used for dates rendered into messages
+	archiveDateLayout = "January 2, 2006"
+)
+
+// channelMessageData is the data available to the warning, archive notice, DM and escalation templates
+type channelMessageData struct {
+	Channel           slack.Channel
+	DaysInactive      int
+	Threshold         int
+	ArchiveDate       string
+	UnarchiveHowTo    string
+	Reason            archiveReason
+	ReasonDescription string
+	// RunID is the ID of the run sending the message
+	RunID string
+	// DaysLeft is how many days are left until the archive date of a warned channel
+	DaysLeft int
+}
+
+// summaryChannel is a channel listed in the summary along with why it was archived or exempted, if it was
+type summaryChannel struct {
+	slack.Channel
+	Reason    archiveReason
+	Exemption *exemption
+}
+
+// summaryMessageData is the data available to the summary template
+type summaryMessageData struct {
+	RunID     string
+	Archived  []summaryChannel
+	Warned    []summaryChannel
+	Failed    []summaryChannel
+	Exempt    []summaryChannel
+	Threshold int
+
+	// Duplicates are the dormant channels that likely duplicate another channel, when detecting duplicates
+	Duplicates []duplicateChannel
+}
+
+// sampleSummaryData will return the summary data templates are validated with. Every list has a channel, so the
+// bodies of ranges over them are rendered too.
+func sampleSummaryData() summaryMessageData {
+	c := summaryChannel{Reason: reasonNoHumanMessages, Exemption: &exemption{}}
+
+	return summaryMessageData{
+		Archived:   []summaryChannel{c},
+		Warned:     []summaryChannel{c},
+		Failed:     []summaryChannel{c},
+		Exempt:     []summaryChannel{c},
+		Duplicates: []duplicateChannel{{}},
+	}
+}
+
+// messageTemplates holds the parsed templates for every message auto-archiver posts
+type messageTemplates struct {
+	warning        *template.Template
+	archiveNotice  *template.Template
+	dm             *template.Template
+	escalation     *template.Template
+	summary        *template.Template
+	unarchiveHowTo string
+
+	exemptionReminder *template.Template
+	ownerEscalation   *template.Template
+	adminEscalation   *template.Template
+	approvalRequest   *template.Template
+	warningResolved   *template.Template
+	nudge             *template.Template
+}
+
+// newMessageTemplates parses the message templates, falling back to the defaults for any not overridden,
+// and validates them by rendering each with sample data
+func newMessageTemplates(getenv func(string) string) (*messageTemplates, error) {
+	t := &messageTemplates{unarchiveHowTo: getenv("AUTO_ARCHIVER_UNARCHIVE_HOW_TO")}
+	if t.unarchiveHowTo == "" {
+		t.unarchiveHowTo = defaultUnarchiveHowTo
+	}
+
+	var err error
+	for _, tmpl := range []struct {
+		key  string
+		def  string
+		dest **template.Template
+		data any
+	}{
+		{"AUTO_ARCHIVER_WARNING_TEMPLATE", defaultWarningTemplate, &t.warning, channelMessageData{}},
+		{"AUTO_ARCHIVER_ARCHIVE_NOTICE_TEMPLATE", defaultArchiveNoticeTemplate, &t.archiveNotice, channelMessageData{}},
+		{"AUTO_ARCHIVER_DM_TEMPLATE", defaultDMTemplate, &t.dm, channelMessageData{}},
+		{"AUTO_ARCHIVER_ESCALATION_TEMPLATE", defaultEscalationTemplate, &t.escalation, channelMessageData{}},
+		{"AUTO_ARCHIVER_SUMMARY_TEMPLATE", defaultSummaryTemplate, &t.summary, sampleSummaryData()},
+		{"AUTO_ARCHIVER_OWNER_ESCALATION_TEMPLATE", defaultOwnerEscalationTemplate, &t.ownerEscalation, channelMessageData{}},
+		{"AUTO_ARCHIVER_ADMIN_ESCALATION_TEMPLATE", defaultAdminEscalationTemplate, &t.adminEscalation, channelMessageData{}},
+		{"AUTO_ARCHIVER_EXEMPTION_REMINDER_TEMPLATE", defaultExemptionReminderTemplate, &t.exemptionReminder, exemptionReminderData{}},
+		{"AUTO_ARCHIVER_APPROVAL_REQUEST_TEMPLATE", defaultApprovalRequestTemplate, &t.approvalRequest, approvalRequestData{}},
+		{"AUTO_ARCHIVER_WARNING_RESOLVED_TEMPLATE", defaultWarningResolvedTemplate, &t.warningResolved, channelMessageData{}},
+		{"AUTO_ARCHIVER_NUDGE_TEMPLATE", defaultNudgeTemplate, &t.nudge, channelMessageData{}},
+	} {
+		text := getenv(tmpl.key)
+		if text == "" {
+			text = tmpl.def
+		}
+
+		*tmpl.dest, err = template.New(tmpl.key).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse %s: %w", tmpl.key, err)
+		}
+
+		// Rendering with sample data catches references to fields that do not exist
+		if _, err := render(*tmpl.dest, tmpl.data); err != nil {
+			return nil, fmt.Errorf("can not render %s: %w", tmpl.key, err)
+		}
+	}
+
+	return t, nil
+}
+
+// channelData builds the template data for a message about channel c
+func (t *messageTemplates) channelData(c slack.Channel, daysInactive, threshold int, archiveDate time.Time) channelMessageData {
+	return channelMessageData{
+		Channel:        c,
+		DaysInactive:   daysInactive,
+		Threshold:      threshold,
+		ArchiveDate:    archiveDate.Format(archiveDateLayout),
+		UnarchiveHowTo: t.unarchiveHowTo,
+	}
+}
+
+// render executes tmpl with data and returns the resulting text
+func render(tmpl *template.Template, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
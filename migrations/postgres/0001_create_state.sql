@@ -0,0 +1,24 @@
+-- auto_archiver_state holds every Store value, grouped into buckets such as exemptions
+CREATE TABLE auto_archiver_state (
+    bucket     text        NOT NULL,
+    key        text        NOT NULL,
+    value      jsonb       NOT NULL,
+    expires_at timestamptz,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (bucket, key)
+);
+
+CREATE INDEX auto_archiver_state_expires_at ON auto_archiver_state (expires_at) WHERE expires_at IS NOT NULL;
+
+-- auto_archiver_exemptions makes exemptions easy to query
+CREATE VIEW auto_archiver_exemptions AS
+SELECT
+    key AS channel_id,
+    value ->> 'channel_name' AS channel_name,
+    -- Permanent exemptions have the zero time as their end
+    NULLIF(value ->> 'until', '0001-01-01T00:00:00Z')::timestamptz AS until,
+    value ->> 'reason' AS reason,
+    value ->> 'exempted_by' AS exempted_by,
+    (value ->> 'created_at')::timestamptz AS created_at
+FROM auto_archiver_state
+WHERE bucket = 'exemptions';
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// staleGroupDM is a multi-person DM without messages within the archive threshold. Group DMs can not be archived,
+// so they are only reported.
+type staleGroupDM struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// LastActivity is when the last message was posted, zero when the group DM has none
+	LastActivity time.Time `json:"last_activity"`
+	DaysInactive int       `json:"days_inactive"`
+}
+
+// findStaleGroupDMs will return how many multi-person DMs the bot can see, which are only those it is a member of,
+// and those without messages within the archive threshold. It needs the mpim:read and mpim:history scopes.
+func (a *ArchiveSlacker) findStaleGroupDMs(ctx context.Context, now time.Time) (int, []staleGroupDM, error) {
+	checked := 0
+	stale := []staleGroupDM{}
+	params := &slack.GetConversationsParameters{ExcludeArchived: true, Types: []string{"mpim"}, Limit: 1000, TeamID: a.teamID}
+	for {
+		groups, cursor, err := a.client.GetConversationsContext(ctx, params)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		for _, g := range groups {
+			checked++
+			history, err := a.client.GetConversationHistoryContext(ctx, &slack.GetConversationHistoryParameters{ChannelID: g.ID, Limit: 1})
+			if err != nil {
+				return 0, nil, err
+			}
+
+			dm := staleGroupDM{ID: g.ID, Name: g.Name, DaysInactive: int(now.Sub(g.Created.Time()).Hours() / 24)}
+			if len(history.Messages) > 0 {
+				dm.LastActivity, err = parseSlackTimestamp(history.Messages[0].Timestamp)
+				if err != nil {
+					return 0, nil, err
+				}
+				dm.DaysInactive = int(now.Sub(dm.LastActivity).Hours() / 24)
+			}
+			if dm.DaysInactive >= a.threshold {
+				stale = append(stale, dm)
+			}
+		}
+
+		if cursor == "" {
+			return checked, stale, nil
+		}
+		params.Cursor = cursor
+	}
+}
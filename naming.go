@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// namingConvention is a class of channel names, such as team- or proj- channels
+type namingConvention struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// loadNamingConventions reads the naming conventions, each a name and a regular expression separated by =
+func loadNamingConventions(getenv func(string) string) ([]namingConvention, error) {
+	conventions := []namingConvention{}
+	for _, v := range listSetting(getenv, "AUTO_ARCHIVER_NAMING_CONVENTIONS") {
+		name, expr, ok := strings.Cut(v, "=")
+		if !ok || name == "" || expr == "" {
+			return nil, fmt.Errorf("naming convention %q must be a name and a regular expression separated by =", v)
+		}
+
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse naming convention %s into a regular expression: %w", name, err)
+		}
+		conventions = append(conventions, namingConvention{name: name, pattern: pattern})
+	}
+
+	return conventions, nil
+}
+
+// matchNamingConvention will return the name of the first convention a channel's name follows,
+// or an empty string if it follows none
+func matchNamingConvention(conventions []namingConvention, c slack.Channel) string {
+	for _, convention := range conventions {
+		if convention.pattern.MatchString(c.Name) {
+			return convention.name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/slack-go/slack"
+)
+
+// Names of the notifiers that can be chosen with AUTO_ARCHIVER_NOTIFIERS
+const (
+	notifierSlack   = "slack"
+	notifierWebhook = "webhook"
+	notifierEmail   = "email"
+)
+
+// Notifier is a target that is told what a run did, such as Slack or an email inbox
+type Notifier interface {
+	// Warn is called after a channel is warned that it will be archived
+	Warn(ctx context.Context, data channelMessageData) error
+	// Archived is called after a channel is archived
+	Archived(ctx context.Context, data channelMessageData) error
+	// Summary is called once a run has acted on every channel
+	Summary(ctx context.Context, summary summaryMessageData) error
+	// Error is called when evaluating or acting on a channel fails, c is nil when the error stopped the run
+	Error(ctx context.Context, c *slack.Channel, err error) error
+}
+
+// namedNotifier is a configured Notifier and the name it was chosen by
+type namedNotifier struct {
+	name string
+	Notifier
+}
+
+// notifiers tells every configured Notifier, logging the ones that fail rather than failing the run
+type notifiers struct {
+	logger  logr.Logger
+	targets []namedNotifier
+}
+
+// newNotifiers will create the notifiers chosen in the config. Slack notifications are sent by a,
+// the bot token's slacker.
+func newNotifiers(logger logr.Logger, cfg *config, a *ArchiveSlacker) *notifiers {
+	n := &notifiers{logger: logger}
+	for _, name := range cfg.notifiers {
+		var target Notifier
+		switch name {
+		case notifierSlack:
+			target = &slackNotifier{a: a}
+		case notifierWebhook:
+			target = newWebhookNotifier(cfg.notifyWebhookURL, cfg.notifyWebhookToken, a.result.RunID)
+		case notifierEmail:
+			target = newEmailNotifier(cfg, a.templates)
+		}
+		n.targets = append(n.targets, namedNotifier{name: name, Notifier: target})
+	}
+
+	return n
+}
+
+// warn will tell every notifier a channel was warned. Nothing is told when n is nil, as in dry runs.
+func (n *notifiers) warn(ctx context.Context, data channelMessageData) {
+	if n == nil {
+		return
+	}
+
+	for _, t := range n.targets {
+		if err := t.Warn(ctx, data); err != nil {
+			n.logger.Error(err, "failed to notify of warning", "notifier", t.name, "channel", data.Channel.Name)
+		}
+	}
+}
+
+// archived will tell every notifier a channel was archived
+func (n *notifiers) archived(ctx context.Context, data channelMessageData) {
+	if n == nil {
+		return
+	}
+
+	for _, t := range n.targets {
+		if err := t.Archived(ctx, data); err != nil {
+			n.logger.Error(err, "failed to notify of archive", "notifier", t.name, "channel", data.Channel.Name)
+		}
+	}
+}
+
+// summary will tell every notifier what the run did
+func (n *notifiers) summary(ctx context.Context, summary summaryMessageData) {
+	if n == nil {
+		return
+	}
+
+	for _, t := range n.targets {
+		if err := t.Summary(ctx, summary); err != nil {
+			n.logger.Error(err, "failed to notify of run summary", "notifier", t.name)
+		}
+	}
+}
+
+// error will tell every notifier about an error with a channel, or that stopped the run when c is nil
+func (n *notifiers) error(ctx context.Context, c *slack.Channel, runErr error) {
+	if n == nil {
+		return
+	}
+
+	for _, t := range n.targets {
+		if err := t.Error(ctx, c, runErr); err != nil {
+			n.logger.Error(err, "failed to notify of error", "notifier", t.name)
+		}
+	}
+}
+
+// slackNotifier escalates warnings to managers, sends owners a direct message when their channel is archived
+// and posts the run summary to the admin channel and digest users, as each of those is configured
+type slackNotifier struct {
+	a *ArchiveSlacker
+}
+
+// Warn will escalate the warning to the manager of a deactivated creator
+func (s *slackNotifier) Warn(ctx context.Context, data channelMessageData) error {
+	// Nobody is left to act on the warning when the creator is gone, so it is escalated to their manager
+	if s.a.directory == nil || data.Channel.Creator == "" {
+		return nil
+	}
+
+	return s.a.escalateToManager(ctx, data.Channel.Creator, data)
+}
+
+// Archived will send each owner of the channel a direct message
+func (s *slackNotifier) Archived(ctx context.Context, data channelMessageData) error {
+	if !s.a.notifyCreator {
+		return nil
+	}
+
+	owners, err := s.a.channelOwners(ctx, data.Channel)
+	if err != nil {
+		return err
+	}
+	for _, owner := range owners {
+		if err := s.a.notifyChannelOwner(ctx, owner, data); err != nil {
+			s.a.logger.Error(err, "failed to notify channel owner", "channel", data.Channel.Name, "owner", owner)
+		}
+	}
+
+	return nil
+}
+
+// Summary will post the summary to the admin channel and send it to the digest users
+func (s *slackNotifier) Summary(ctx context.Context, summary summaryMessageData) error {
+	s.a.sendAdminDigests(ctx, summary)
+
+	return s.a.postSummary(ctx, summary)
+}
+
+// Error does nothing, failed channels are listed in the summary
+func (s *slackNotifier) Error(ctx context.Context, c *slack.Channel, err error) error {
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// emailNotifier emails the run summary and errors to a list of addresses over SMTP. Warnings and archives
+// are only listed in the summary, as an email for each channel would flood the inbox.
+type emailNotifier struct {
+	addr      string
+	from      string
+	to        []string
+	auth      smtp.Auth
+	templates *messageTemplates
+}
+
+func newEmailNotifier(cfg *config, templates *messageTemplates) *emailNotifier {
+	var auth smtp.Auth
+	if cfg.notifyEmailUsername != "" {
+		host, _, _ := net.SplitHostPort(cfg.notifyEmailSMTPAddr)
+		auth = smtp.PlainAuth("", cfg.notifyEmailUsername, cfg.notifyEmailPassword, host)
+	}
+
+	return &emailNotifier{
+		addr:      cfg.notifyEmailSMTPAddr,
+		from:      cfg.notifyEmailFrom,
+		to:        cfg.notifyEmailTo,
+		auth:      auth,
+		templates: templates,
+	}
+}
+
+// Warn does nothing, warned channels are listed in the summary
+func (e *emailNotifier) Warn(ctx context.Context, data channelMessageData) error {
+	return nil
+}
+
+// Archived does nothing, archived channels are listed in the summary
+func (e *emailNotifier) Archived(ctx context.Context, data channelMessageData) error {
+	return nil
+}
+
+// Summary will email the summary rendered with the summary template
+func (e *emailNotifier) Summary(ctx context.Context, summary summaryMessageData) error {
+	text, err := render(e.templates.summary, summary)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("auto-archiver: %d archived, %d warned, %d failed", len(summary.Archived), len(summary.Warned), len(summary.Failed))
+	return e.send(subject, text)
+}
+
+// Error will email the error
+func (e *emailNotifier) Error(ctx context.Context, c *slack.Channel, err error) error {
+	if c == nil {
+		return e.send("auto-archiver: run failed", err.Error())
+	}
+
+	return e.send("auto-archiver: failed on #"+c.Name, err.Error())
+}
+
+// send will send a plain text email to every recipient
+func (e *emailNotifier) send(subject, body string) error {
+	msg := strings.Join([]string{
+		"From: " + e.from,
+		"To: " + strings.Join(e.to, ", "),
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		body,
+	}, "\r\n")
+
+	return smtp.SendMail(e.addr, e.auth, e.from, e.to, []byte(msg))
+}
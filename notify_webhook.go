@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Events POSTed to the notification webhook
+const (
+	notifyEventWarn     = "warn"
+	notifyEventArchived = "archived"
+	notifyEventSummary  = "summary"
+	notifyEventError    = "error"
+)
+
+// notifyWebhookTimeout is the timeout of each request to the notification webhook
+const notifyWebhookTimeout = 10 * time.Second
+
+// webhookNotifier POSTs each notification to an HTTP endpoint as JSON
+type webhookNotifier struct {
+	client *http.Client
+	url    string
+	token  string
+	// runID is the ID of the run the notifications are about
+	runID string
+}
+
+func newWebhookNotifier(url, token, runID string) *webhookNotifier {
+	return &webhookNotifier{
+		client: &http.Client{Timeout: notifyWebhookTimeout},
+		url:    url,
+		token:  token,
+		runID:  runID,
+	}
+}
+
+// notifyChannel is a channel in a notification
+type notifyChannel struct {
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Reason archiveReason `json:"reason,omitempty"`
+}
+
+// notifyEvent is the body POSTed to the notification webhook, only the fields of its event are set
+type notifyEvent struct {
+	Event        string         `json:"event"`
+	RunID        string         `json:"run_id"`
+	Channel      *notifyChannel `json:"channel,omitempty"`
+	DaysInactive int            `json:"days_inactive,omitempty"`
+	Threshold    int            `json:"threshold,omitempty"`
+	ArchiveDate  string         `json:"archive_date,omitempty"`
+	Error        string         `json:"error,omitempty"`
+
+	Archived []notifyChannel `json:"archived,omitempty"`
+	Warned   []notifyChannel `json:"warned,omitempty"`
+	Failed   []notifyChannel `json:"failed,omitempty"`
+	Exempt   []notifyChannel `json:"exempt,omitempty"`
+}
+
+// Warn will POST a warn event
+func (w *webhookNotifier) Warn(ctx context.Context, data channelMessageData) error {
+	return w.post(ctx, channelEvent(notifyEventWarn, data))
+}
+
+// Archived will POST an archived event
+func (w *webhookNotifier) Archived(ctx context.Context, data channelMessageData) error {
+	return w.post(ctx, channelEvent(notifyEventArchived, data))
+}
+
+// Summary will POST a summary event
+func (w *webhookNotifier) Summary(ctx context.Context, summary summaryMessageData) error {
+	return w.post(ctx, notifyEvent{
+		Event:     notifyEventSummary,
+		Threshold: summary.Threshold,
+		Archived:  notifyChannels(summary.Archived),
+		Warned:    notifyChannels(summary.Warned),
+		Failed:    notifyChannels(summary.Failed),
+		Exempt:    notifyChannels(summary.Exempt),
+	})
+}
+
+// Error will POST an error event
+func (w *webhookNotifier) Error(ctx context.Context, c *slack.Channel, err error) error {
+	event := notifyEvent{Event: notifyEventError, Error: err.Error()}
+	if c != nil {
+		event.Channel = &notifyChannel{ID: c.ID, Name: c.Name}
+	}
+
+	return w.post(ctx, event)
+}
+
+// post will POST an event to the webhook, which must reply with a 2xx status
+func (w *webhookNotifier) post(ctx context.Context, event notifyEvent) error {
+	event.RunID = w.runID
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("notification webhook returned %s: %s", resp.Status, msg)
+	}
+
+	return nil
+}
+
+// channelEvent will build the event about a single channel
+func channelEvent(event string, data channelMessageData) notifyEvent {
+	return notifyEvent{
+		Event:        event,
+		Channel:      &notifyChannel{ID: data.Channel.ID, Name: data.Channel.Name, Reason: data.Reason},
+		DaysInactive: data.DaysInactive,
+		Threshold:    data.Threshold,
+		ArchiveDate:  data.ArchiveDate,
+	}
+}
+
+// notifyChannels will list the channels of a summary
+func notifyChannels(channels []summaryChannel) []notifyChannel {
+	list := []notifyChannel{}
+	for _, c := range channels {
+		list = append(list, notifyChannel{ID: c.ID, Name: c.Name, Reason: c.Reason})
+	}
+
+	return list
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// bucketNudges holds the members nudged about each channel in its warning period, by channel ID
+	bucketNudges = "nudges"
+
+	nudgeKeepActionID    = "nudge_keep"
+	nudgeArchiveActionID = "nudge_archive"
+	// nudgeKeepReason is the reason of the exemptions of channels kept from a nudge
+	nudgeKeepReason = "Still needed, kept from a nudge"
+	// nudgeHistoryLimit is how many of a channel's latest messages are read for its recently active members
+	nudgeHistoryLimit = 200
+)
+
+// nudge is the members privately asked whether a channel is still needed instead of warning the whole channel
+type nudge struct {
+	ChannelID   string    `json:"channel_id"`
+	ChannelName string    `json:"channel_name"`
+	NudgedAt    time.Time `json:"nudged_at"`
+	Users       []string  `json:"users"`
+	// ArchiveVotes are when each nudged member answered that the channel can be archived
+	ArchiveVotes map[string]time.Time `json:"archive_votes,omitempty"`
+}
+
+// nudgeChannel will send the most recently active members of a channel an ephemeral message asking whether they still
+// need it, with buttons to keep or archive it, reporting false if nobody could be nudged and the channel has to be
+// warned instead. Members are nudged once per warning period.
+func (a *ArchiveSlacker) nudgeChannel(ctx context.Context, c inactiveChannel) (bool, error) {
+	var n nudge
+	if err := getJSON(ctx, a.store, bucketNudges, c.channel.ID, &n); err == nil {
+		a.logger.V(1).Info("members were already nudged", "channel", c.channel.Name, "nudged_at", n.NudgedAt)
+		return true, nil
+	} else if !errors.Is(err, errNotFound) {
+		return false, err
+	}
+
+	users, err := a.recentlyActiveMembers(ctx, c.channel.ID, a.nudgeMembers)
+	if err != nil {
+		return false, fmt.Errorf("could not get recently active members: %w", err)
+	}
+
+	text, err := render(a.templates.nudge, a.messageData(c))
+	if err != nil {
+		return false, err
+	}
+	keep := slack.NewButtonBlockElement(nudgeKeepActionID, c.channel.ID, slack.NewTextBlockObject(slack.PlainTextType, "Keep", false, false))
+	keep.Style = slack.StylePrimary
+	archive := slack.NewButtonBlockElement(nudgeArchiveActionID, c.channel.ID, slack.NewTextBlockObject(slack.PlainTextType, "Archive", false, false))
+	blocks := slack.MsgOptionBlocks(
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+		slack.NewActionBlock("", keep, archive),
+	)
+
+	n = nudge{ChannelID: c.channel.ID, ChannelName: c.channel.Name, NudgedAt: time.Now()}
+	for _, userID := range users {
+		_, err := a.client.PostEphemeralContext(ctx, c.channel.ID, userID, slack.MsgOptionText(text, false), blocks)
+		// Members who left since their last message can not be nudged
+		var slackErr slack.SlackErrorResponse
+		if errors.As(err, &slackErr) && slackErr.Err == "user_not_in_channel" {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		n.Users = append(n.Users, userID)
+	}
+	if len(n.Users) == 0 {
+		return false, nil
+	}
+
+	a.logger.V(1).Info("nudged recently active members", "channel", c.channel.Name, "users", n.Users)
+	return true, putJSON(ctx, a.store, bucketNudges, c.channel.ID, n, time.Duration(c.threshold)*24*time.Hour)
+}
+
+// recentlyActiveMembers will return up to limit users who most recently posted activity in a channel, the most
+// recent first
+func (a *ArchiveSlacker) recentlyActiveMembers(ctx context.Context, channelID string, limit int) ([]string, error) {
+	history, err := a.client.GetConversationHistoryContext(ctx, &slack.GetConversationHistoryParameters{
+		ChannelID: channelID,
+		Limit:     nudgeHistoryLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	users := []string{}
+	for _, m := range history.Messages {
+		if m.User == "" || m.BotID != "" || !isActivity(m, a.activityAuthors) || slices.Contains(users, m.User) {
+			continue
+		}
+		users = append(users, m.User)
+		if len(users) == limit {
+			break
+		}
+	}
+
+	return users, nil
+}
+
+// answerNudge will keep a channel a nudged member still needs by exempting it for the archive threshold, or record
+// that they do not need it, replacing the nudge with the outcome
+func (d *daemon) answerNudge(ctx context.Context, callback slack.InteractionCallback, action *slack.BlockAction) {
+	channelID := action.Value
+	logger := d.logger.WithValues("channel", channelID, "user", callback.User.ID)
+
+	reply := func(text string) {
+		if err := slack.PostWebhookContext(ctx, callback.ResponseURL, &slack.WebhookMessage{Text: text, ReplaceOriginal: true}); err != nil {
+			logger.Error(err, "failed to answer nudge")
+		}
+	}
+
+	var n nudge
+	if err := getJSON(ctx, d.store, bucketNudges, channelID, &n); err != nil {
+		if errors.Is(err, errNotFound) {
+			reply("This question has expired.")
+			return
+		}
+		logger.Error(err, "failed to get nudge")
+		reply("Something went wrong, please try again.")
+		return
+	}
+	// Only the nudged members may keep the channel, as keeping it exempts it
+	if !slices.Contains(n.Users, callback.User.ID) {
+		reply("This question has expired.")
+		return
+	}
+
+	if action.ActionID == nudgeKeepActionID {
+		e, err := d.exemptChannel(ctx, channelID, callback.User.ID, d.cfg.archiveThreshold, nudgeKeepReason)
+		if err != nil {
+			logger.Error(err, "failed to keep nudged channel")
+			reply("Something went wrong, please try again.")
+			return
+		}
+		logger.Info("kept nudged channel", "until", e.Until)
+		reply(fmt.Sprintf("Thanks, #%s will be kept until at least %s.", n.ChannelName, e.Until.Format(archiveDateLayout)))
+		return
+	}
+
+	if n.ArchiveVotes == nil {
+		n.ArchiveVotes = map[string]time.Time{}
+	}
+	n.ArchiveVotes[callback.User.ID] = time.Now()
+	// The nudge is kept until the end of the warning period it was sent in
+	ttl := time.Until(n.NudgedAt.AddDate(0, 0, d.cfg.archiveThreshold))
+	if err := putJSON(ctx, d.store, bucketNudges, channelID, n, max(ttl, time.Hour)); err != nil {
+		logger.Error(err, "failed to record archive vote")
+		reply("Something went wrong, please try again.")
+		return
+	}
+	logger.Info("nudged member does not need channel", "votes", len(n.ArchiveVotes), "nudged", len(n.Users))
+	reply(fmt.Sprintf("Thanks, #%s will be archived unless someone else still needs it.", n.ChannelName))
+}
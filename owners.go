@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// ownershipRule assigns an owner to the channels whose names match pattern
+type ownershipRule struct {
+	pattern *regexp.Regexp
+	// owner is a user ID, or a user group ID starting with S
+	owner string
+}
+
+// ownershipMap assigns owners to channels, so notifications reach a team that still exists
+// rather than a channel creator who may have left
+type ownershipMap struct {
+	rules []ownershipRule
+}
+
+// loadOwnershipMap will read the ownership CSV from a file or an http(s) URL. Each row is a channel name
+// regular expression and an owner, and a header row of "pattern,owner" is skipped.
+func loadOwnershipMap(ctx context.Context, source string) (*ownershipMap, error) {
+	var r io.ReadCloser
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		client := &http.Client{Timeout: 30 * time.Second}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("ownership map returned %s", resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		r = f
+	}
+	defer r.Close()
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	m := &ownershipMap{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can not parse ownership map: %w", err)
+		}
+
+		if line == 1 && record[0] == "pattern" && record[1] == "owner" {
+			continue
+		}
+
+		pattern, err := regexp.Compile(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("can not parse ownership map pattern %q into a regular expression: %w", record[0], err)
+		}
+		m.rules = append(m.rules, ownershipRule{pattern: pattern, owner: strings.TrimSpace(record[1])})
+	}
+
+	return m, nil
+}
+
+// owner will return the owner of the first rule matching the channel, or an empty string if none match
+func (m *ownershipMap) owner(c slack.Channel) string {
+	for _, rule := range m.rules {
+		if rule.pattern.MatchString(c.Name) {
+			return rule.owner
+		}
+	}
+
+	return ""
+}
+
+// channelOwners will return the users to notify about a channel: the members of its tagged or mapped owner,
+// or its creator if it has no owner
+func (a *ArchiveSlacker) channelOwners(ctx context.Context, c slack.Channel) ([]string, error) {
+	tags, err := a.channelTags(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("could not get channel tags: %w", err)
+	}
+
+	owner := ""
+	if tags != nil && tags.Owner != "" {
+		owner = tags.Owner
+	} else if a.owners != nil {
+		owner = a.owners.owner(c)
+	}
+
+	switch {
+	case owner == "":
+		if c.Creator == "" {
+			return nil, nil
+		}
+		return []string{c.Creator}, nil
+	case strings.HasPrefix(owner, "S"):
+		return a.client.GetUserGroupMembersContext(ctx, owner)
+	default:
+		return []string{owner}, nil
+	}
+}
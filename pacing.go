@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// pacer spaces actions out evenly so that at most a number of them are taken per minute
+type pacer struct {
+	interval time.Duration
+	last     time.Time
+}
+
+// newPacer will return a pacer allowing perMinute actions a minute, 0 does not space actions out
+func newPacer(perMinute int) *pacer {
+	if perMinute <= 0 {
+		return &pacer{}
+	}
+
+	return &pacer{interval: time.Minute / time.Duration(perMinute)}
+}
+
+// wait will block until the next action may be taken, or ctx is done
+func (p *pacer) wait(ctx context.Context) error {
+	if p.interval == 0 {
+		return nil
+	}
+
+	if !p.last.IsZero() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(p.last.Add(p.interval))):
+		}
+	}
+	p.last = time.Now()
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// parquetMagic starts and ends every Parquet file
+const parquetMagic = "PAR1"
+
+// Kinds of Parquet columns, each written with a matching physical and converted type
+const (
+	parquetString = iota
+	parquetInt64
+	parquetBool
+	parquetTimestamp
+)
+
+// Parquet physical types, converted types, repetition types and encodings, as numbered in parquet.thrift
+const (
+	parquetTypeBoolean        = 0
+	parquetTypeInt64          = 2
+	parquetTypeByteArray      = 6
+	parquetConvertedUTF8      = 0
+	parquetConvertedTimestamp = 9
+	parquetRequired           = 0
+	parquetEncodingPlain      = 0
+	parquetEncodingRLE        = 3
+	parquetDataPage           = 0
+	parquetUncompressed       = 0
+)
+
+// parquetColumn is a column of a Parquet file. Values are string, int64, bool or time.Time by kind, and there are
+// no nulls.
+type parquetColumn struct {
+	name   string
+	kind   int
+	values []any
+}
+
+// physicalType will return the physical type a column is stored as, and its converted type if it has one
+func (c parquetColumn) physicalType() (int32, int32, bool) {
+	switch c.kind {
+	case parquetInt64:
+		return parquetTypeInt64, 0, false
+	case parquetBool:
+		return parquetTypeBoolean, 0, false
+	case parquetTimestamp:
+		return parquetTypeInt64, parquetConvertedTimestamp, true
+	default:
+		return parquetTypeByteArray, parquetConvertedUTF8, true
+	}
+}
+
+// encode will return the column's values in the PLAIN encoding
+func (c parquetColumn) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	if c.kind == parquetBool {
+		bits := make([]byte, (len(c.values)+7)/8)
+		for i, v := range c.values {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("value %d of column %s is not a bool", i, c.name)
+			}
+			if b {
+				bits[i/8] |= 1 << (i % 8)
+			}
+		}
+		return bits, nil
+	}
+
+	for i, v := range c.values {
+		switch v := v.(type) {
+		case string:
+			if c.kind != parquetString {
+				return nil, fmt.Errorf("value %d of column %s is a string", i, c.name)
+			}
+			buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(v))))
+			buf.WriteString(v)
+		case int64:
+			if c.kind != parquetInt64 {
+				return nil, fmt.Errorf("value %d of column %s is an int", i, c.name)
+			}
+			buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+		case time.Time:
+			if c.kind != parquetTimestamp {
+				return nil, fmt.Errorf("value %d of column %s is a time", i, c.name)
+			}
+			buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(v.UnixMilli())))
+		default:
+			return nil, fmt.Errorf("value %d of column %s has unsupported type %T", i, c.name, v)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeParquet will write the columns as a Parquet file of a single uncompressed row group, with one PLAIN
+// encoded data page per column. That is all that is needed for the small files of a run, and keeps a Parquet
+// library out of the dependencies.
+func writeParquet(w io.Writer, columns []parquetColumn, rows int) error {
+	var file bytes.Buffer
+	file.WriteString(parquetMagic)
+
+	// Offsets and sizes of each column chunk, for the file's metadata
+	offsets := make([]int64, len(columns))
+	sizes := make([]int64, len(columns))
+	for i, c := range columns {
+		if len(c.values) != rows {
+			return fmt.Errorf("column %s has %d values for %d rows", c.name, len(c.values), rows)
+		}
+		data, err := c.encode()
+		if err != nil {
+			return err
+		}
+
+		header := &thriftWriter{}
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.beginStruct(5)
+		header.i32(1, int32(rows))
+		header.i32(2, parquetEncodingPlain)
+		header.i32(3, parquetEncodingRLE)
+		header.i32(4, parquetEncodingRLE)
+		header.endStruct()
+		header.stop()
+
+		offsets[i] = int64(file.Len())
+		sizes[i] = int64(header.buf.Len() + len(data))
+		file.Write(header.buf.Bytes())
+		file.Write(data)
+	}
+
+	meta := &thriftWriter{}
+	meta.i32(1, 1)
+	meta.beginList(2, thriftStruct, len(columns)+1)
+	meta.beginElement()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(columns)))
+	meta.endStruct()
+	for _, c := range columns {
+		physical, converted, ok := c.physicalType()
+		meta.beginElement()
+		meta.i32(1, physical)
+		meta.i32(3, parquetRequired)
+		meta.binary(4, c.name)
+		if ok {
+			meta.i32(6, converted)
+		}
+		meta.endStruct()
+	}
+	meta.i64(3, int64(rows))
+
+	// A file without rows has no row groups
+	groups := 0
+	if rows > 0 {
+		groups = 1
+	}
+	meta.beginList(4, thriftStruct, groups)
+	if rows > 0 {
+		var total int64
+		meta.beginElement()
+		meta.beginList(1, thriftStruct, len(columns))
+		for i, c := range columns {
+			physical, _, _ := c.physicalType()
+			total += sizes[i]
+
+			meta.beginElement()
+			meta.i64(2, offsets[i])
+			meta.beginStruct(3)
+			meta.i32(1, physical)
+			meta.beginList(2, thriftI32, 1)
+			meta.varint(zigzag(parquetEncodingPlain))
+			meta.beginList(3, thriftBinary, 1)
+			meta.str(c.name)
+			meta.i32(4, parquetUncompressed)
+			meta.i64(5, int64(rows))
+			meta.i64(6, sizes[i])
+			meta.i64(7, sizes[i])
+			meta.i64(9, offsets[i])
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.i64(2, total)
+		meta.i64(3, int64(rows))
+		meta.endStruct()
+	}
+	meta.binary(6, "auto-archiver")
+	meta.stop()
+
+	file.Write(meta.buf.Bytes())
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(meta.buf.Len())))
+	file.WriteString(parquetMagic)
+
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// Thrift compact protocol types, as used in field and list headers
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter writes the Thrift compact protocol that Parquet's page headers and file metadata are encoded in.
+// Fields must be written in increasing order of their IDs within each struct.
+type thriftWriter struct {
+	buf bytes.Buffer
+	// last is the ID of the last field written in the current struct, and stack those of the enclosing structs
+	last  int16
+	stack []int16
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(zigzag(int64(id)))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (t *thriftWriter) str(s string) {
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.str(s)
+}
+
+// beginStruct will start a struct field, which is ended with endStruct
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.beginElement()
+}
+
+// beginList will start a list field of size elements, which are written without field headers
+func (t *thriftWriter) beginList(id int16, elemType byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	t.buf.WriteByte(0xf0 | elemType)
+	t.varint(uint64(size))
+}
+
+// beginElement will start a struct that is an element of a list, which is ended with endStruct
+func (t *thriftWriter) beginElement() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) endStruct() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+// stop will end the outermost struct
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
+
+// zigzag will encode a signed integer so small negative values stay small as varints
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
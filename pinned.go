@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/slack-go/slack"
+)
+
+// pinnedExemptionPattern matches a "keep #channel [yyyy-mm-dd] [reason]" line. Slack sends channels mentioned
+// in messages as <#C0123456789|name>, while channels that could not be linked stay as plain #name.
+var pinnedExemptionPattern = regexp.MustCompile(`^keep\s+(?:<#([A-Z0-9]+)(?:\|[^>]*)?>|#([^\s]+))(?:\s+(\d{4}-\d{2}-\d{2}))?(?:\s+(.*))?$`)
+
+// pinnedExemption is an exemption read from a message pinned in the admin channel
+type pinnedExemption struct {
+	// channel is the ID or name of the exempt channel
+	channel string
+	exemption
+}
+
+// pinnedExemptions are the exemptions kept as pinned messages in the admin channel, so small teams can manage
+// them without a state backend
+type pinnedExemptions []pinnedExemption
+
+// loadPinnedExemptions will read the exemptions from the "keep" lines of the messages pinned in channelID.
+// Lines that can not be parsed are logged and skipped, so one typo does not stop every run.
+func loadPinnedExemptions(ctx context.Context, logger logr.Logger, client *slack.Client, channelID string) (pinnedExemptions, error) {
+	items, _, err := client.ListPinsContext(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	exemptions := pinnedExemptions{}
+	for _, item := range items {
+		if item.Message == nil {
+			continue
+		}
+
+		for _, line := range strings.Split(item.Message.Text, "\n") {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, "keep ") {
+				continue
+			}
+
+			e, err := parsePinnedExemption(line)
+			if err != nil {
+				logger.Error(err, "skipping pinned exemption", "line", line)
+				continue
+			}
+			e.ExemptedBy = item.Message.User
+			exemptions = append(exemptions, e)
+		}
+	}
+
+	return exemptions, nil
+}
+
+// parsePinnedExemption will parse a "keep #channel [yyyy-mm-dd] [reason]" line
+func parsePinnedExemption(line string) (pinnedExemption, error) {
+	match := pinnedExemptionPattern.FindStringSubmatch(line)
+	if match == nil {
+		return pinnedExemption{}, errors.New(`exemptions must look like "keep #channel [yyyy-mm-dd] [reason]"`)
+	}
+
+	e := pinnedExemption{channel: match[1], exemption: exemption{Reason: match[4]}}
+	if e.channel == "" {
+		e.channel = match[2]
+	}
+
+	if match[3] != "" {
+		until, err := time.Parse(time.DateOnly, match[3])
+		if err != nil {
+			return pinnedExemption{}, fmt.Errorf("can not parse date %s: %w", match[3], err)
+		}
+		e.Until = until
+	}
+
+	return e, nil
+}
+
+// exemption will return the pinned exemption of a channel in effect at now, or nil if it has none
+func (p pinnedExemptions) exemption(c slack.Channel, now time.Time) *exemption {
+	for _, e := range p {
+		if e.channel != c.ID && e.channel != c.Name {
+			continue
+		}
+
+		exempt := e.exemption
+		exempt.ChannelID = c.ID
+		exempt.ChannelName = c.Name
+		if exempt.activeAt(now) {
+			return &exempt
+		}
+	}
+
+	return nil
+}
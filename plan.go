@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// ANSI colors of the plan output
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorBold   = "\x1b[1m"
+)
+
+// planSymbols are the terraform style markers of each decision shown in the plan, other decisions change nothing
+var planSymbols = map[decision]struct{ symbol, color string }{
+	decisionArchive: {"-", colorRed},
+	decisionWarn:    {"~", colorYellow},
+	decisionError:   {"!", colorBold},
+}
+
+// writePlan will write the channels the run acts on as a terraform style diff for reviewing in a terminal or CI
+// log, archives first. Kept and exempt channels are only counted. The API budget of the real run is written below
+// the plan when it was estimated.
+func (r *runResult) writePlan(w io.Writer, color bool, budget *apiBudget) error {
+	paint := func(c, s string) string {
+		if !color {
+			return s
+		}
+		return c + s + colorReset
+	}
+
+	channels := []channelResult{}
+	for _, c := range r.Channels {
+		if c.Error != "" {
+			c.Decision = decisionError
+		}
+		if _, ok := planSymbols[c.Decision]; ok {
+			channels = append(channels, c)
+		}
+	}
+	order := map[decision]int{decisionArchive: 0, decisionWarn: 1, decisionError: 2}
+	sort.Slice(channels, func(i, j int) bool {
+		if channels[i].Decision != channels[j].Decision {
+			return order[channels[i].Decision] < order[channels[j].Decision]
+		}
+		return channels[i].Name < channels[j].Name
+	})
+
+	for _, c := range channels {
+		detail := fmt.Sprintf("%dd inactive", c.DaysInactive)
+		switch {
+		case c.Decision == decisionError:
+			detail = c.Error
+		case c.Reason != "":
+			detail += ", " + c.Reason.Description()
+		}
+		if c.ReportOnly {
+			detail += ", report only"
+		}
+
+		s := planSymbols[c.Decision]
+		if _, err := fmt.Fprintln(w, paint(s.color, fmt.Sprintf("%s %s #%s (%s)", s.symbol, c.Decision, c.Name, detail))); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\n%s %d to archive, %d to warn, %d failed, %d exempt, %d report only, %d unchanged.\n",
+		paint(colorBold, "Plan:"), r.Counts.Archived, r.Counts.Warned, r.Counts.Failed, r.Counts.Exempt, r.Counts.ReportOnly, r.Counts.Kept)
+	if err != nil || budget == nil {
+		return err
+	}
+
+	return budget.write(w)
+}
+
+// isTerminal will report whether f is a terminal that colors can be written to, honoring NO_COLOR
+func isTerminal(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
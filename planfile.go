@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"time"
+
+	"github.com/slack-go/slack"
+	"golang.org/x/crypto/ssh"
+)
+
+// planSignatureNamespace is the namespace plan files are signed in, as in ssh-keygen -Y sign -n auto-archiver-plan
+const planSignatureNamespace = "auto-archiver-plan"
+
+// runPlan is the machine-readable plan of a dry run, which a later run applies after it was reviewed
+type runPlan struct {
+	RunID     string           `json:"run_id"`
+	CreatedAt time.Time        `json:"created_at"`
+	Channels  []plannedChannel `json:"channels"`
+}
+
+// plannedChannel is a channel the plan warns or archives
+type plannedChannel struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Decision decision      `json:"decision"`
+	Reason   archiveReason `json:"reason,omitempty"`
+}
+
+// writePlanFile will write the channels the run would warn or archive as a plan file
+func (r *runResult) writePlanFile(path string) error {
+	plan := runPlan{RunID: r.RunID, CreatedAt: r.StartedAt, Channels: []plannedChannel{}}
+	for _, c := range r.Channels {
+		if c.Error != "" || c.ReportOnly || (c.Decision != decisionWarn && c.Decision != decisionArchive) {
+			continue
+		}
+		plan.Channels = append(plan.Channels, plannedChannel{ID: c.ID, Name: c.Name, Decision: c.Decision, Reason: c.Reason})
+	}
+
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// loadPlanFile will read a plan file to apply. When trusted keys are configured the plan must be signed by one of
+// them in <path>.sig, and it must be younger than maxAge, so only reviewed and recent plans are applied.
+func loadPlanFile(path string, cfg *config, now time.Time) (map[string]decision, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can not read plan: %w", err)
+	}
+
+	if cfg.planTrustedKeys != nil {
+		signature, err := os.ReadFile(path + ".sig")
+		if err != nil {
+			return nil, fmt.Errorf("can not read plan signature: %w", err)
+		}
+		if err := verifySSHSignature(data, signature, planSignatureNamespace, cfg.planTrustedKeys); err != nil {
+			return nil, fmt.Errorf("can not verify plan signature: %w", err)
+		}
+	}
+
+	var plan runPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("can not parse plan: %w", err)
+	}
+	if now.Sub(plan.CreatedAt) > cfg.planMaxAge {
+		return nil, fmt.Errorf("plan %s was made at %s and is older than %s", plan.RunID, plan.CreatedAt.Format(time.RFC3339), cfg.planMaxAge)
+	}
+
+	planned := map[string]decision{}
+	for _, c := range plan.Channels {
+		planned[c.ID] = c.Decision
+	}
+
+	return planned, nil
+}
+
+// planned will report whether the plan being applied makes decision d for channel c, which it always does when no
+// plan is applied
+func (c *config) planned(channel slack.Channel, d decision) bool {
+	return c.plan == nil || c.plan[channel.ID] == d
+}
+
+// loadTrustedKeys will read the public keys in an authorized_keys formatted file
+func loadTrustedKeys(path string) ([]ssh.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can not read trusted keys: %w", err)
+	}
+
+	keys := []ssh.PublicKey{}
+	for len(bytes.TrimSpace(data)) > 0 {
+		key, _, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse trusted keys: %w", err)
+		}
+		keys = append(keys, key)
+		data = rest
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s has no keys", path)
+	}
+
+	return keys, nil
+}
+
+// verifySSHSignature will verify an armored SSH signature of message, as made by ssh-keygen -Y sign, was made in
+// namespace by one of the trusted keys
+func verifySSHSignature(message, armored []byte, namespace string, trusted []ssh.PublicKey) error {
+	block, _ := pem.Decode(armored)
+	if block == nil || block.Type != "SSH SIGNATURE" {
+		return errors.New("not an SSH signature")
+	}
+
+	magic := []byte("SSHSIG")
+	if !bytes.HasPrefix(block.Bytes, magic) {
+		return errors.New("not an SSH signature")
+	}
+	var sig struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}
+	if err := ssh.Unmarshal(block.Bytes[len(magic):], &sig); err != nil {
+		return fmt.Errorf("can not parse SSH signature: %w", err)
+	}
+	if sig.Version != 1 {
+		return fmt.Errorf("unsupported SSH signature version %d", sig.Version)
+	}
+	if sig.Namespace != namespace {
+		return fmt.Errorf("signature is for namespace %q instead of %q", sig.Namespace, namespace)
+	}
+
+	key, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return fmt.Errorf("can not parse signing key: %w", err)
+	}
+	isTrusted := false
+	for _, t := range trusted {
+		if bytes.Equal(t.Marshal(), key.Marshal()) {
+			isTrusted = true
+			break
+		}
+	}
+	if !isTrusted {
+		return fmt.Errorf("signing key %s is not trusted", ssh.FingerprintSHA256(key))
+	}
+
+	var h hash.Hash
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported signature hash algorithm %q", sig.HashAlgorithm)
+	}
+	h.Write(message)
+
+	var signature ssh.Signature
+	if err := ssh.Unmarshal(sig.Signature, &signature); err != nil {
+		return fmt.Errorf("can not parse signature: %w", err)
+	}
+
+	signed := append(magic, ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{sig.Namespace, sig.Reserved, sig.HashAlgorithm, h.Sum(nil)})...)
+
+	return key.Verify(signed, &signature)
+}
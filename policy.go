@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/slack-go/slack"
+)
+
+// policyExemptedBy is who policy exemptions are recorded as exempted by
+const policyExemptedBy = "policy"
+
+// channelPolicy is the archive policy kept in a Git repository, so changes to it go through review
+type channelPolicy struct {
+	// ArchiveThreshold, WarningDays and MinActivityMessages replace the configured values when set
+	ArchiveThreshold    *int `json:"archive_threshold"`
+	WarningDays         *int `json:"warning_days"`
+	MinActivityMessages *int `json:"min_activity_messages"`
+	// ActivityUsers and IgnoredUsers are added to the configured users whose messages always or never count
+	ActivityUsers []string          `json:"activity_users"`
+	IgnoredUsers  []string          `json:"ignored_users"`
+	Rules         []policyRule      `json:"rules"`
+	Exemptions    []policyExemption `json:"exemptions"`
+	// ExemptGroups are added to the configured directory groups whose members' channels are exempt
+	ExemptGroups []string `json:"exempt_groups"`
+
+	// ArchivePrivate archives private channels when true and only reports on them when false, replacing
+	// AUTO_ARCHIVER_PRIVATE_CHANNELS when set
+	ArchivePrivate *bool `json:"archive_private"`
+	// AdminChannel replaces AUTO_ARCHIVER_ADMIN_CHANNEL when set
+	AdminChannel string `json:"admin_channel"`
+	// Workspaces override the policy in the workspaces of an Enterprise Grid organization, by team ID
+	Workspaces map[string]*channelPolicy `json:"workspaces"`
+
+	// commit is the commit the policy was read from
+	commit string
+}
+
+// policyRule applies to the channels whose names start with Prefix, the longest matching prefix winning
+type policyRule struct {
+	Prefix           string `json:"prefix"`
+	ArchiveThreshold int    `json:"archive_threshold"`
+	// MinActivityMessages is how many messages keep a matching channel active, 0 for the policy's default
+	MinActivityMessages int `json:"min_activity_messages"`
+	// Exempt keeps every matching channel
+	Exempt bool   `json:"exempt"`
+	Reason string `json:"reason"`
+}
+
+// policyExemption exempts a channel by ID or name
+type policyExemption struct {
+	Channel string `json:"channel"`
+	// Until is a date or RFC 3339 time the exemption ends at, empty for a permanent exemption
+	Until  string `json:"until"`
+	Reason string `json:"reason"`
+
+	until time.Time
+}
+
+// loadChannelPolicy will read the policy file from the configured ref of the policy repository, cloning it
+// into memory so nothing is left on disk between runs
+func loadChannelPolicy(ctx context.Context, cfg *config) (*channelPolicy, error) {
+	opts := &git.CloneOptions{
+		URL:          cfg.policyRepo,
+		SingleBranch: true,
+		Depth:        1,
+	}
+	if cfg.policyToken != "" {
+		opts.Auth = &githttp.BasicAuth{Username: "auto-archiver", Password: cfg.policyToken}
+	}
+
+	// Refs are tried as a branch and then as a tag, unless they are already fully qualified
+	refs := []plumbing.ReferenceName{""}
+	switch {
+	case strings.HasPrefix(cfg.policyRef, "refs/"):
+		refs = []plumbing.ReferenceName{plumbing.ReferenceName(cfg.policyRef)}
+	case cfg.policyRef != "":
+		refs = []plumbing.ReferenceName{plumbing.NewBranchReferenceName(cfg.policyRef), plumbing.NewTagReferenceName(cfg.policyRef)}
+	}
+
+	var repo *git.Repository
+	var err error
+	for _, ref := range refs {
+		opts.ReferenceName = ref
+		repo, err = git.CloneContext(ctx, memory.NewStorage(), nil, opts)
+		if err == nil || !isMissingRef(err) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not clone %s: %w", cfg.policyRepo, err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	file, err := commit.File(cfg.policyPath)
+	if err != nil {
+		return nil, fmt.Errorf("can not find %s at %s: %w", cfg.policyPath, head.Hash(), err)
+	}
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &channelPolicy{commit: head.Hash().String()}
+	if err := json.Unmarshal([]byte(contents), policy); err != nil {
+		return nil, fmt.Errorf("can not decode %s: %w", cfg.policyPath, err)
+	}
+
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	for teamID, w := range policy.Workspaces {
+		if w == nil {
+			return nil, fmt.Errorf("workspace %s has no policy", teamID)
+		}
+		if w.Workspaces != nil {
+			return nil, fmt.Errorf("workspace %s can not have workspaces of its own", teamID)
+		}
+		if err := w.validate(); err != nil {
+			return nil, fmt.Errorf("workspace %s: %w", teamID, err)
+		}
+	}
+
+	return policy, nil
+}
+
+// validate will check the policy's rules and parse when its exemptions end
+func (p *channelPolicy) validate() error {
+	var err error
+	for i, e := range p.Exemptions {
+		if e.Channel == "" {
+			return fmt.Errorf("exemption %d has no channel", i)
+		}
+		if e.Until == "" {
+			continue
+		}
+		if p.Exemptions[i].until, err = time.Parse(time.RFC3339, e.Until); err != nil {
+			if p.Exemptions[i].until, err = time.Parse(time.DateOnly, e.Until); err != nil {
+				return fmt.Errorf("can not parse until of exemption of %s: %w", e.Channel, err)
+			}
+		}
+	}
+
+	for _, r := range p.Rules {
+		if r.Prefix == "" {
+			return errors.New("rules must have a prefix")
+		}
+		if r.ArchiveThreshold < 0 || r.MinActivityMessages < 0 {
+			return fmt.Errorf("rule for %s can not set a negative archive threshold or min activity messages", r.Prefix)
+		}
+		if !r.Exempt && r.ArchiveThreshold == 0 && r.MinActivityMessages == 0 {
+			return fmt.Errorf("rule for %s must set a positive archive threshold, min activity messages or exempt", r.Prefix)
+		}
+	}
+
+	return nil
+}
+
+// isMissingRef will report whether cloning failed because the ref does not exist
+func isMissingRef(err error) bool {
+	var noMatch git.NoMatchingRefSpecError
+	return errors.As(err, &noMatch) || errors.Is(err, plumbing.ErrReferenceNotFound)
+}
+
+// policyCacheTTL is how long the daemon answers commands with the policy it read before reading it again
+const policyCacheTTL = 5 * time.Minute
+
+// cachedPolicy keeps the policy read from Git for a while, so the daemon can answer commands the way a run would
+// without cloning the policy repository for each of them
+type cachedPolicy struct {
+	mu       sync.Mutex
+	policy   *channelPolicy
+	loadedAt time.Time
+}
+
+// get will return the policy, reading it again once it is older than policyCacheTTL
+func (c *cachedPolicy) get(ctx context.Context, cfg *config) (*channelPolicy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.policy != nil && time.Since(c.loadedAt) < policyCacheTTL {
+		return c.policy, nil
+	}
+
+	policy, err := loadChannelPolicy(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.policy, c.loadedAt = policy, time.Now()
+
+	return policy, nil
+}
+
+// apply will return a copy of cfg using the policy's thresholds and rules
+func (p *channelPolicy) apply(cfg *config) (*config, error) {
+	applied := *cfg
+	applied.policy = p
+
+	if p.ArchiveThreshold != nil {
+		applied.archiveThreshold = *p.ArchiveThreshold
+	}
+	if p.WarningDays != nil {
+		applied.warningDays = *p.WarningDays
+	}
+	if p.MinActivityMessages != nil {
+		applied.minActivityMessages = *p.MinActivityMessages
+	}
+	applied.activityUsers = append(slices.Clip(cfg.activityUsers), p.ActivityUsers...)
+	applied.ignoredUsers = append(slices.Clip(cfg.ignoredUsers), p.IgnoredUsers...)
+	applied.exemptGroups = append(slices.Clip(cfg.exemptGroups), p.ExemptGroups...)
+	if p.ArchivePrivate != nil {
+		applied.privateChannels = privateReport
+		if *p.ArchivePrivate {
+			applied.privateChannels = privateArchive
+		}
+	}
+	if p.AdminChannel != "" {
+		applied.adminChannel = p.AdminChannel
+	}
+
+	if len(applied.exemptGroups) > 0 && applied.ldapURL == nil {
+		return nil, errors.New("exempt groups need AUTO_ARCHIVER_LDAP_URL to look owners up")
+	}
+	if applied.minActivityMessages < 1 {
+		return nil, fmt.Errorf("min activity messages must be at least 1, got %d", applied.minActivityMessages)
+	}
+	if applied.warningDays < 0 || applied.warningDays >= applied.archiveThreshold {
+		return nil, fmt.Errorf("warning days must be between 0 and the archive threshold, got %d", applied.warningDays)
+	}
+	if err := applied.validatePrivateThresholds(); err != nil {
+		return nil, err
+	}
+	if applied.deactivatedCreatorThreshold != nil && *applied.deactivatedCreatorThreshold > applied.archiveThreshold {
+		return nil, fmt.Errorf("deactivated creator threshold must not be over the archive threshold of %d", applied.archiveThreshold)
+	}
+	if applied.singleMemberThreshold != nil && *applied.singleMemberThreshold > applied.archiveThreshold {
+		return nil, fmt.Errorf("single member threshold must not be over the archive threshold of %d", applied.archiveThreshold)
+	}
+
+	return &applied, nil
+}
+
+// rule will return the rule with the longest prefix of the channel's name, or nil if none match
+func (p *channelPolicy) rule(c slack.Channel) *policyRule {
+	var match *policyRule
+	for i, r := range p.Rules {
+		if strings.HasPrefix(c.Name, r.Prefix) && (match == nil || len(r.Prefix) > len(match.Prefix)) {
+			match = &p.Rules[i]
+		}
+	}
+
+	return match
+}
+
+// threshold will return the archive threshold of a channel, def unless a rule sets one
+func (p *channelPolicy) threshold(c slack.Channel, def int) int {
+	if p == nil {
+		return def
+	}
+	if r := p.rule(c); r != nil && r.ArchiveThreshold > 0 {
+		return r.ArchiveThreshold
+	}
+
+	return def
+}
+
+// minActivityMessages will return how many messages keep a channel active, def unless a rule sets it
+func (p *channelPolicy) minActivityMessages(c slack.Channel, def int) int {
+	if p == nil {
+		return def
+	}
+	if r := p.rule(c); r != nil && r.MinActivityMessages > 0 {
+		return r.MinActivityMessages
+	}
+
+	return def
+}
+
+// exemption will return the policy's exemption of a channel in effect at now, or nil if it has none
+func (p *channelPolicy) exemption(c slack.Channel, now time.Time) *exemption {
+	if p == nil {
+		return nil
+	}
+
+	for _, e := range p.Exemptions {
+		if e.Channel != c.ID && e.Channel != c.Name {
+			continue
+		}
+
+		exempt := exemption{
+			ChannelID:   c.ID,
+			ChannelName: c.Name,
+			Until:       e.until,
+			Reason:      e.Reason,
+			ExemptedBy:  policyExemptedBy,
+		}
+		if exempt.activeAt(now) {
+			return &exempt
+		}
+	}
+
+	if r := p.rule(c); r != nil && r.Exempt {
+		reason := r.Reason
+		if reason == "" {
+			reason = fmt.Sprintf("channels starting with %s are exempt", r.Prefix)
+		}
+		return &exemption{ChannelID: c.ID, ChannelName: c.Name, Reason: reason, ExemptedBy: policyExemptedBy}
+	}
+
+	return nil
+}
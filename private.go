@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/slack-go/slack"
+)
+
+// How private channels are handled, chosen with AUTO_ARCHIVER_PRIVATE_CHANNELS
+const (
+	// privateIgnore does not list private channels at all
+	privateIgnore = "ignore"
+	// privateReport evaluates the private channels auto-archiver was invited to and reports what would be done with
+	// them, without warning or archiving them
+	privateReport = "report"
+	// privateArchive warns and archives private channels like public ones
+	privateArchive = "archive"
+)
+
+// channelTypes will return the conversation types to list, private channels needing the groups:read scope
+func channelTypes(privateChannels string) []string {
+	if privateChannels == privateIgnore {
+		return []string{"public_channel"}
+	}
+
+	return []string{"public_channel", "private_channel"}
+}
+
+// reportOnly reports whether a channel is only reported on, rather than warned and archived
+func (a *ArchiveSlacker) reportOnly(c inactiveChannel) bool {
+	return c.channel.IsPrivate && a.privateChannels != privateArchive
+}
+
+// validateThreshold will check the archive threshold changed from Slack still fits the warning days and the
+// thresholds that must be under it
+func (c *config) validateThreshold() error {
+	if c.warningDays >= c.archiveThreshold {
+		return fmt.Errorf("archive threshold must be over the %d warning days, got %d", c.warningDays, c.archiveThreshold)
+	}
+	if c.deactivatedCreatorThreshold != nil && *c.deactivatedCreatorThreshold > c.archiveThreshold {
+		return fmt.Errorf("deactivated creator threshold must not be over the archive threshold of %d", c.archiveThreshold)
+	}
+	if c.singleMemberThreshold != nil && *c.singleMemberThreshold > c.archiveThreshold {
+		return fmt.Errorf("single member threshold must not be over the archive threshold of %d", c.archiveThreshold)
+	}
+
+	return c.validatePrivateThresholds()
+}
+
+// validatePrivateThresholds will check the warning days of private channels are within their archive threshold,
+// either of which may fall back to the value of public channels
+func (c *config) validatePrivateThresholds() error {
+	if c.privateArchiveThreshold == nil && c.privateWarningDays == nil {
+		return nil
+	}
+
+	threshold, warningDays := c.archiveThreshold, c.warningDays
+	if c.privateArchiveThreshold != nil {
+		threshold = *c.privateArchiveThreshold
+	}
+	if c.privateWarningDays != nil {
+		warningDays = *c.privateWarningDays
+	}
+	if threshold < 1 {
+		return fmt.Errorf("private archive threshold must be at least 1, got %d", threshold)
+	}
+	if warningDays < 0 || warningDays >= threshold {
+		return fmt.Errorf("private warning days must be between 0 and the private archive threshold, got %d", warningDays)
+	}
+
+	return nil
+}
+
+// channelThreshold will return the archive threshold of a channel before policy rules apply
+func (a *ArchiveSlacker) channelThreshold(c slack.Channel) int {
+	if c.IsPrivate && a.privateThreshold != nil {
+		return *a.privateThreshold
+	}
+
+	return a.threshold
+}
+
+// channelWarningDays will return how many days before its archive date a channel is warned
+func (a *ArchiveSlacker) channelWarningDays(c slack.Channel) int {
+	if c.IsPrivate && a.privateWarningDays != nil {
+		return *a.privateWarningDays
+	}
+
+	return a.warningDays
+}
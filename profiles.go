@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// configFile holds named configuration profiles, so one document can drive several deployments
+type configFile struct {
+	Profiles map[string]configProfile `json:"profiles"`
+}
+
+// configProfile is a named set of settings, keyed by their environment variable names
+type configProfile struct {
+	// Extends is the profile whose settings this profile overrides
+	Extends string `json:"extends"`
+	// DryRun makes every run of the profile a dry run, as with --dry-run
+	DryRun   *bool             `json:"dry_run"`
+	Settings map[string]string `json:"settings"`
+}
+
+// addProfileFlags will add the --config and --profile flags to flags, which default to AUTO_ARCHIVER_CONFIG_FILE
+// and AUTO_ARCHIVER_PROFILE
+func addProfileFlags(flags *flag.FlagSet) (*string, *string) {
+	file := flags.String("config", os.Getenv("AUTO_ARCHIVER_CONFIG_FILE"), "JSON file of named configuration profiles")
+	profile := flags.String("profile", os.Getenv("AUTO_ARCHIVER_PROFILE"), "configuration profile to use from --config")
+
+	return file, profile
+}
+
+// loadProfileConfig will load the configuration from the environment, falling back to the settings of a profile
+// of a config file when one is given. Environment variables win, so secrets can stay out of the file.
+func loadProfileConfig(file, name string) (*config, error) {
+	if file == "" {
+		if name != "" {
+			return nil, fmt.Errorf("--profile requires --config")
+		}
+		return loadConfig(os.Getenv)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("--config requires --profile")
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("can not read config file: %w", err)
+	}
+	var f configFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("can not parse config file %s: %w", file, err)
+	}
+
+	settings, dryRun, err := f.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := loadConfig(func(key string) string {
+		if v, ok := os.LookupEnv(key); ok {
+			return v
+		}
+		return settings[key]
+	})
+	if err != nil {
+		return nil, err
+	}
+	cfg.dryRun = dryRun
+
+	return cfg, nil
+}
+
+// resolve will return the settings of a profile merged over those of the profiles it extends, and whether it
+// is a dry run profile
+func (f configFile) resolve(name string) (map[string]string, bool, error) {
+	// chain is the profile and the profiles it extends, the profile first
+	chain := []configProfile{}
+	seen := map[string]bool{}
+	for n := name; n != ""; {
+		if seen[n] {
+			return nil, false, fmt.Errorf("profile %s extends itself", n)
+		}
+		seen[n] = true
+
+		p, ok := f.Profiles[n]
+		if !ok {
+			return nil, false, fmt.Errorf("unknown profile %q", n)
+		}
+		chain = append(chain, p)
+		n = p.Extends
+	}
+
+	settings := map[string]string{}
+	dryRun := false
+	for i := len(chain) - 1; i >= 0; i-- {
+		for key, value := range chain[i].Settings {
+			if !strings.HasPrefix(key, "AUTO_ARCHIVER_") {
+				return nil, false, fmt.Errorf("unknown setting %s in profile, settings are named like their environment variables", key)
+			}
+			settings[key] = value
+		}
+		if chain[i].DryRun != nil {
+			dryRun = *chain[i].DryRun
+		}
+	}
+
+	return settings, dryRun, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/gorilla/websocket"
+)
+
+// parseProxyURL will parse AUTO_ARCHIVER_HTTP_PROXY. Credentials in the URL authenticate with the proxy.
+func parseProxyURL(v string) (*url.URL, error) {
+	u, err := url.Parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse proxy URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+		return nil, fmt.Errorf("proxy URL must be http, https or socks5, got %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", v)
+	}
+
+	return u, nil
+}
+
+// loadCABundle will return the system's certificate authorities with those of a PEM bundle added, so Slack can be
+// reached through proxies that intercept TLS
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can not read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("CA bundle %s has no PEM certificates", path)
+	}
+
+	return pool, nil
+}
+
+// proxyFunc will return the proxy for requests to Slack, AUTO_ARCHIVER_HTTP_PROXY or else the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables
+func proxyFunc(cfg *config) func(*http.Request) (*url.URL, error) {
+	if cfg.httpProxy != nil {
+		return http.ProxyURL(cfg.httpProxy)
+	}
+
+	return http.ProxyFromEnvironment
+}
+
+// tlsConfig will return the TLS configuration for connections to Slack, nil to use the defaults
+func tlsConfig(cfg *config) *tls.Config {
+	if cfg.caCerts == nil {
+		return nil
+	}
+
+	return &tls.Config{RootCAs: cfg.caCerts}
+}
+
+// newSocketModeDialer will return the dialer of the Socket Mode connection, which goes through the same proxy
+// as API calls
+func newSocketModeDialer(cfg *config) *websocket.Dialer {
+	return &websocket.Dialer{
+		Proxy:            proxyFunc(cfg),
+		TLSClientConfig:  tlsConfig(cfg),
+		HandshakeTimeout: cfg.httpTimeout,
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// readOnlyMethods are the Slack API methods read-only runs may call, all of which only need read scopes
+var readOnlyMethods = map[string]bool{
+	"auth.test":                              true,
+	"conversations.list":                     true,
+	"conversations.info":                     true,
+	"conversations.history":                  true,
+	"conversations.replies":                  true,
+	"conversations.members":                  true,
+	"users.info":                             true,
+	"users.list":                             true,
+	"users.lookupByEmail":                    true,
+	"usergroups.users.list":                  true,
+	"pins.list":                              true,
+	"dnd.info":                               true,
+	"files.info":                             true,
+	"admin.conversations.getCustomRetention": true,
+}
+
+// readOnlyTransport refuses every Slack API call read-only runs may not make, so a run that would need a write
+// scope fails loudly instead of acting
+type readOnlyTransport struct {
+	next   http.RoundTripper
+	apiURL string
+}
+
+func (t *readOnlyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	if strings.HasPrefix(req.URL.String(), t.apiURL) && !readOnlyMethods[method] {
+		return nil, fmt.Errorf("%s is not allowed in read-only runs", method)
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// readOnlyStore reads state from a Store but drops every write to it, so read-only runs see the exemptions and
+// other state of real runs without changing any of it
+type readOnlyStore struct {
+	Store
+}
+
+func (s readOnlyStore) Put(context.Context, string, string, []byte, time.Duration) error {
+	return nil
+}
+
+func (s readOnlyStore) Delete(context.Context, string, string) error {
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+// archiveReason is the structured reason a channel was chosen for archiving
+type archiveReason string
+
+const (
+	// reasonEmptyChannel means no messages at all were posted within the threshold
+	reasonEmptyChannel archiveReason = "empty_channel"
+	// reasonNoHumanMessages means only automated messages such as joins were posted within the threshold
+	reasonNoHumanMessages archiveReason = "no_human_messages"
+	// reasonIncidentResolved means the channel's incident was resolved longer ago than the incident policy allows
+	reasonIncidentResolved archiveReason = "incident_resolved"
+	// reasonCreatorDeactivated means the channel's creator is deactivated and the channel was inactive for longer
+	// than the deactivated creator threshold
+	reasonCreatorDeactivated archiveReason = "creator_deactivated"
+	// reasonNoHumanMembers means the channel's only members are bots, so nobody can be using it
+	reasonNoHumanMembers archiveReason = "no_human_members"
+	// reasonSingleMember means the channel has one human member and was inactive for longer than the single
+	// member threshold
+	reasonSingleMember archiveReason = "single_member"
+	// reasonMembersDeactivated means every human member of the channel is deactivated, so nobody can be using it
+	reasonMembersDeactivated archiveReason = "members_deactivated"
+)
+
+// reasonDescriptions are the human readable descriptions of each archive reason used in messages
+var reasonDescriptions = map[archiveReason]string{
+	reasonEmptyChannel:       "no messages were posted",
+	reasonNoHumanMessages:    "only automated messages such as joins were posted",
+	reasonIncidentResolved:   "the incident was resolved",
+	reasonCreatorDeactivated: "the channel's creator has left and it had no recent activity",
+	reasonNoHumanMembers:     "it has no members other than bots",
+	reasonSingleMember:       "it has a single member and had no recent activity",
+	reasonMembersDeactivated: "everyone in it has left",
+}
+
+// Description will return the human readable description of the reason
+func (r archiveReason) Description() string {
+	if d, ok := reasonDescriptions[r]; ok {
+		return d
+	}
+
+	return string(r)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"io"
+	"regexp"
+)
+
+// slackTokenPattern matches Slack bot, user, app-level, refresh and configuration tokens
+var slackTokenPattern = regexp.MustCompile(`\b(xox[abposre]|xapp)-[A-Za-z0-9-]+`)
+
+// redactingWriter scrubs Slack tokens from everything written to it, so tokens leaked by the SDK's debug logs or
+// in error strings never reach the logs
+type redactingWriter struct {
+	w io.Writer
+}
+
+// newRedactingWriter will wrap w in a redactingWriter, unless it already is one
+func newRedactingWriter(w io.Writer) io.Writer {
+	if _, ok := w.(*redactingWriter); ok {
+		return w
+	}
+
+	return &redactingWriter{w: w}
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := r.w.Write(slackTokenPattern.ReplaceAll(p, []byte("$1-REDACTED"))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
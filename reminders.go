@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// renewExemptionActionID is the action ID of the button in exemption reminders that renews the exemption
+const renewExemptionActionID = "renew_exemption"
+
+// exemptionReminderData is the data available to the exemption reminder template
+type exemptionReminderData struct {
+	Exemption exemption
+	// UntilDate is when the exemption ends, formatted for messages
+	UntilDate string
+	DaysLeft  int
+}
+
+// sendExemptionReminders will remind the users who made stored exemptions that their exemption ends within the
+// reminder period. Each exemption is reminded of once, and renewing it stores a new exemption to be reminded of.
+func (a *ArchiveSlacker) sendExemptionReminders(ctx context.Context, now time.Time) error {
+	exemptions, err := listExemptions(ctx, a.store)
+	if err != nil {
+		return fmt.Errorf("can not list exemptions: %w", err)
+	}
+
+	for _, e := range exemptions {
+		if e.Until.IsZero() || !e.RemindedAt.IsZero() || e.ExemptedBy == "" || e.ExemptedBy == unarchiveExemptedBy || !e.activeAt(now) {
+			continue
+		}
+		if e.Until.After(now.AddDate(0, 0, a.exemptionReminderDays)) {
+			continue
+		}
+
+		logger := a.logger.WithValues("channel", e.ChannelName, "user", e.ExemptedBy)
+		if err := a.sendExemptionReminder(ctx, e, now); err != nil {
+			logger.Error(err, "failed to send exemption reminder")
+			continue
+		}
+		logger.Info("sent exemption reminder", "until", e.Until)
+
+		e.RemindedAt = now
+		if err := putExemption(ctx, a.store, e); err != nil {
+			logger.Error(err, "failed to record exemption reminder")
+		}
+	}
+
+	return nil
+}
+
+// sendExemptionReminder will send the user who made an exemption a direct message that it is ending. When the
+// daemon is running to receive the click, the message has a button to renew the exemption for as long again.
+func (a *ArchiveSlacker) sendExemptionReminder(ctx context.Context, e exemption, now time.Time) error {
+	text, err := render(a.templates.exemptionReminder, exemptionReminderData{
+		Exemption: e,
+		UntilDate: e.Until.Format(archiveDateLayout),
+		DaysLeft:  int(math.Ceil(e.Until.Sub(now).Hours() / 24)),
+	})
+	if err != nil {
+		return err
+	}
+
+	dm, _, _, err := a.client.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{e.ExemptedBy}})
+	if err != nil {
+		return err
+	}
+
+	options := []slack.MsgOption{slack.MsgOptionText(text, false)}
+	if a.daemon {
+		days := exemptionDays(e)
+		button := slack.NewButtonBlockElement(renewExemptionActionID, e.ChannelID,
+			slack.NewTextBlockObject(slack.PlainTextType, fmt.Sprintf("Renew for %d days", days), false, false))
+		options = append(options, slack.MsgOptionBlocks(
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+			slack.NewActionBlock("", button),
+		))
+	}
+
+	_, _, err = a.client.PostMessageContext(ctx, dm.ID, options...)
+	return err
+}
+
+// exemptionDays will return how many days an exemption was made for, or the shortest duration offered for
+// exemptions without a creation time, such as imported ones
+func exemptionDays(e exemption) int {
+	if e.CreatedAt.IsZero() {
+		return exemptDurations[0]
+	}
+
+	return max(1, int(math.Round(e.Until.Sub(e.CreatedAt).Hours()/24)))
+}
+
+// renewExemption will renew the exemption of the channel in a reminder's button for as long as it was first made
+// for, if the user who clicked it made the exemption or may manage auto-archiver
+func (d *daemon) renewExemption(ctx context.Context, callback slack.InteractionCallback, action *slack.BlockAction) {
+	channelID := action.Value
+	logger := d.logger.WithValues("channel", channelID, "user", callback.User.ID)
+
+	// Replacing the reminder's blocks removes the button, so it is only clicked once
+	reply := func(text string) {
+		_, _, _, err := d.api.UpdateMessageContext(ctx, callback.Channel.ID, callback.Message.Timestamp, slack.MsgOptionText(text, false),
+			slack.MsgOptionBlocks(slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil)))
+		if err != nil {
+			logger.Error(err, "failed to update exemption reminder")
+		}
+	}
+
+	e, err := getExemption(ctx, d.store, channelID)
+	if err != nil {
+		logger.Error(err, "failed to get exemption")
+		reply("Something went wrong, please try again.")
+		return
+	}
+	if e == nil || e.Until.IsZero() {
+		reply("This exemption no longer needs renewing.")
+		return
+	}
+
+	if e.ExemptedBy != callback.User.ID {
+		authorized, err := d.isAuthorized(ctx, callback.User.ID)
+		if err != nil {
+			logger.Error(err, "failed to check if user may exempt channels")
+			reply("Something went wrong, please try again.")
+			return
+		}
+		if !authorized {
+			reply("You are not allowed to exempt channels.")
+			return
+		}
+	}
+
+	renewed, err := d.exemptChannel(ctx, channelID, callback.User.ID, exemptionDays(*e), e.Reason)
+	if err != nil {
+		logger.Error(err, "failed to renew exemption")
+		reply("Something went wrong, please try again.")
+		return
+	}
+
+	logger.Info("renewed exemption", "until", renewed.Until)
+	reply(fmt.Sprintf("The exemption of #%s from auto-archiving was renewed until %s.", renewed.ChannelName,
+		renewed.Until.Format(archiveDateLayout)))
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// namingViolation is a channel whose name follows none of the naming conventions
+type namingViolation struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Creator string    `json:"creator"`
+	Created time.Time `json:"created"`
+}
+
+// channelReport is how the channels of the workspace follow the naming conventions, which channels were recreated
+// soon after auto-archiver archived them, and which group DMs are stale
+type channelReport struct {
+	Checked int `json:"checked"`
+	// Conventions is how many channels follow each convention
+	Conventions map[string]int    `json:"conventions"`
+	Violations  []namingViolation `json:"violations"`
+	// Recreated are the channels created soon after auto-archiver archived a channel with a similar name, and
+	// RecreatedByPrefix counts them by the prefix of the archived channel's name
+	Recreated         []channelRecreation `json:"recreated"`
+	RecreatedByPrefix map[string]int      `json:"recreated_by_prefix"`
+	// GroupDMsChecked and StaleGroupDMs are only set when reporting group DMs
+	GroupDMsChecked int            `json:"group_dms_checked,omitempty"`
+	StaleGroupDMs   []staleGroupDM `json:"stale_group_dms,omitempty"`
+}
+
+// runReportCommand will run "auto-archiver report" and return the exit code. It reports the channels whose
+// names follow none of the naming conventions, which per-prefix policy rules can not apply to, with a state store
+// the channels recreated soon after they were archived, and optionally the stale group DMs.
+func runReportCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	output := flags.String("output", "text", `format of the report, "text" or "json"`)
+	configFile, profile := addProfileFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(stderr, "unknown output format %q\n", *output)
+		return exitUsage
+	}
+
+	cfg, err := loadProfileConfig(*configFile, *profile)
+	if err != nil {
+		fmt.Fprintf(stderr, "can not load configuration: %v\n", err)
+		return exitConfig
+	}
+
+	store, err := newStore(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "can not open state store: %v\n", err)
+		return exitConfig
+	}
+	if store != nil {
+		defer store.Close()
+	}
+	if len(cfg.namingConventions) == 0 && store == nil && !cfg.reportGroupDMs {
+		fmt.Fprintln(stderr, "AUTO_ARCHIVER_NAMING_CONVENTIONS, AUTO_ARCHIVER_REPORT_GROUP_DMS or a state store must be set to report on channels")
+		return exitConfig
+	}
+
+	logger := newLogger(stderr)
+	shards := newBotShards(cfg, slack.OptionAPIURL(cfg.slackAPIURL))
+	for _, shard := range shards {
+		if _, err := shard.client.AuthTestContext(ctx); err != nil {
+			fmt.Fprintf(stderr, "can not authenticate with slack: %v\n", err)
+			if isAuthError(err) {
+				return exitAuth
+			}
+			return exitRunFailed
+		}
+	}
+
+	slackers := make([]*ArchiveSlacker, len(shards))
+	for i, shard := range shards {
+		slackers[i] = newShardSlacker(logger, shard, cfg, nil, nil, newRunResult(time.Now()))
+	}
+
+	shardChannels, err := getShardChannels(ctx, slackers)
+	if err != nil {
+		fmt.Fprintf(stderr, "can not get channels: %v\n", err)
+		return exitRunFailed
+	}
+
+	report := channelReport{
+		Conventions:       map[string]int{},
+		Violations:        []namingViolation{},
+		Recreated:         []channelRecreation{},
+		RecreatedByPrefix: map[string]int{},
+	}
+	for _, convention := range cfg.namingConventions {
+		report.Conventions[convention.name] = 0
+	}
+	allChannels := []slack.Channel{}
+	for _, channels := range shardChannels {
+		allChannels = append(allChannels, channels...)
+		for _, c := range channels {
+			report.Checked++
+			if len(cfg.namingConventions) == 0 {
+				continue
+			}
+			if name := matchNamingConvention(cfg.namingConventions, c); name != "" {
+				report.Conventions[name]++
+				continue
+			}
+			report.Violations = append(report.Violations, namingViolation{
+				ID:      c.ID,
+				Name:    c.Name,
+				Creator: c.Creator,
+				Created: c.Created.Time(),
+			})
+		}
+	}
+	sort.Slice(report.Violations, func(i, j int) bool { return report.Violations[i].Name < report.Violations[j].Name })
+
+	if store != nil {
+		report.Recreated, err = findRecreatedChannels(ctx, store, allChannels, cfg.recreationWindowDays)
+		if err != nil {
+			fmt.Fprintf(stderr, "can not find recreated channels: %v\n", err)
+			return exitRunFailed
+		}
+		report.RecreatedByPrefix = recreationsByPrefix(report.Recreated)
+	}
+
+	// Each bot only sees the group DMs it is in, which may overlap
+	if cfg.reportGroupDMs {
+		seen := map[string]bool{}
+		for _, a := range slackers {
+			checked, stale, err := a.findStaleGroupDMs(ctx, time.Now())
+			if err != nil {
+				fmt.Fprintf(stderr, "can not get group DMs: %v\n", err)
+				return exitRunFailed
+			}
+			report.GroupDMsChecked += checked
+			for _, dm := range stale {
+				if !seen[dm.ID] {
+					seen[dm.ID] = true
+					report.StaleGroupDMs = append(report.StaleGroupDMs, dm)
+				}
+			}
+		}
+		sort.Slice(report.StaleGroupDMs, func(i, j int) bool {
+			return report.StaleGroupDMs[i].DaysInactive > report.StaleGroupDMs[j].DaysInactive
+		})
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(stderr, "can not write report: %v\n", err)
+			return exitRunFailed
+		}
+		return exitOK
+	}
+
+	if err := report.writeText(cfg.namingConventions, cfg.reportGroupDMs, stdout); err != nil {
+		fmt.Fprintf(stderr, "can not write report: %v\n", err)
+		return exitRunFailed
+	}
+
+	return exitOK
+}
+
+// writeText will write the report as a count per convention followed by a table of the violations, then a table
+// of the recreated channels, and then a table of the stale group DMs
+func (r *channelReport) writeText(conventions []namingConvention, groupDMs bool, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if len(conventions) > 0 {
+		fmt.Fprintf(tw, "%d of %d channels follow no naming convention\n\n", len(r.Violations), r.Checked)
+		fmt.Fprintln(tw, "CONVENTION\tPATTERN\tCHANNELS")
+		for _, c := range conventions {
+			fmt.Fprintf(tw, "%s\t%s\t%d\n", c.name, c.pattern, r.Conventions[c.name])
+		}
+
+		if len(r.Violations) > 0 {
+			fmt.Fprintln(tw)
+			fmt.Fprintln(tw, "CHANNEL\tCREATOR\tCREATED")
+			for _, v := range r.Violations {
+				fmt.Fprintf(tw, "#%s\t%s\t%s\n", v.Name, v.Creator, v.Created.Format(time.DateOnly))
+			}
+		}
+	}
+
+	if len(r.Recreated) > 0 || (len(conventions) == 0 && !groupDMs) {
+		if len(conventions) > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "%d channels were recreated soon after they were archived\n", len(r.Recreated))
+		if len(r.Recreated) > 0 {
+			fmt.Fprintln(tw)
+			fmt.Fprintln(tw, "CHANNEL\tARCHIVED\tREASON\tARCHIVED ON\tDAYS LATER\tCREATOR")
+		}
+		for _, c := range r.Recreated {
+			fmt.Fprintf(tw, "#%s\t#%s\t%s\t%s\t%d\t%s\n", c.Name, c.Archived.ChannelName, c.Archived.Reason,
+				c.Archived.ArchivedAt.Format(time.DateOnly), c.DaysLater, c.Creator)
+		}
+
+		prefixes := make([]string, 0, len(r.RecreatedByPrefix))
+		for prefix := range r.RecreatedByPrefix {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+		if len(prefixes) > 0 {
+			fmt.Fprintln(tw)
+			fmt.Fprintln(tw, "PREFIX\tRECREATED")
+			for _, prefix := range prefixes {
+				fmt.Fprintf(tw, "%s\t%d\n", prefix, r.RecreatedByPrefix[prefix])
+			}
+		}
+	}
+
+	if groupDMs {
+		if len(conventions) > 0 || len(r.Recreated) > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "%d of %d group DMs are stale, they can not be archived\n", len(r.StaleGroupDMs), r.GroupDMsChecked)
+		if len(r.StaleGroupDMs) > 0 {
+			fmt.Fprintln(tw)
+			fmt.Fprintln(tw, "GROUP DM\tLAST ACTIVITY\tDAYS INACTIVE")
+		}
+		for _, dm := range r.StaleGroupDMs {
+			lastActivity := "never"
+			if !dm.LastActivity.IsZero() {
+				lastActivity = dm.LastActivity.Format(time.DateOnly)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\n", dm.Name, lastActivity, dm.DaysInactive)
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/slack-go/slack"
+)
+
+// decision is what auto-archiver decided to do with a channel
+type decision string
+
+const (
+	decisionKeep    decision = "keep"
+	decisionWarn    decision = "warn"
+	decisionArchive decision = "archive"
+	decisionExempt  decision = "exempt"
+	// decisionError means auto-archiver could not determine whether the channel is archivable
+	decisionError decision = "error"
+)
+
+// channelResult is the outcome of a run for a single channel
+type channelResult struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Decision     decision      `json:"decision"`
+	Reason       archiveReason `json:"reason,omitempty"`
+	DaysInactive int           `json:"days_inactive,omitempty"`
+	// AgeDays is how many days ago the channel was created
+	AgeDays int `json:"age_days,omitempty"`
+	// Error is set when evaluating the channel or acting on the decision failed
+	Error string `json:"error,omitempty"`
+	// ErrorClass is the class of Error, see classifyError
+	ErrorClass string `json:"error_class,omitempty"`
+	// ReportOnly is set for private channels that were not acted on, Decision being what would have been done
+	ReportOnly bool `json:"report_only,omitempty"`
+	// Workspace is the team ID of the channel's workspace in runs of org-wide installs
+	Workspace string `json:"workspace,omitempty"`
+}
+
+// runCounts are the totals of a run
+type runCounts struct {
+	Scanned  int `json:"scanned"`
+	Joined   int `json:"joined"`
+	Kept     int `json:"kept"`
+	Warned   int `json:"warned"`
+	Archived int `json:"archived"`
+	Exempt   int `json:"exempt"`
+	Failed   int `json:"failed"`
+	// ReportOnly are the private channels that would have been warned or archived
+	ReportOnly int `json:"report_only"`
+}
+
+// runResult is the machine-readable summary of a run
+type runResult struct {
+	// RunID identifies the run in logs
+	RunID      string          `json:"run_id"`
+	StartedAt  time.Time       `json:"started_at"`
+	DurationMS int64           `json:"duration_ms"`
+	Counts     runCounts       `json:"counts"`
+	Channels   []channelResult `json:"channels"`
+	// Errors are the errors that stopped the run, per channel errors are reported with each channel
+	Errors []string `json:"errors"`
+	// ErrorClasses are how many channel and run errors there were of each class, the most frequent first
+	ErrorClasses []errorClassCount `json:"error_classes"`
+	// Lifecycle summarizes the age and inactivity of the channels archived in the run, nil when none were
+	Lifecycle *lifecycleStats `json:"lifecycle,omitempty"`
+	// Workspace is the name of the workspace the run was in, empty when it stopped before authenticating
+	Workspace string `json:"workspace,omitempty"`
+
+	// teamID is the workspace channels are recorded in while running in each workspace of an org-wide install
+	teamID string
+	// errorClasses counts the errors of each class as they are recorded
+	errorClasses map[string]int
+	// mu guards the result while shards record their channels concurrently
+	mu sync.Mutex
+}
+
+func newRunResult(startedAt time.Time) *runResult {
+	return &runResult{
+		RunID:     newRunID(),
+		StartedAt: startedAt,
+		Channels:  []channelResult{},
+		Errors:    []string{},
+
+		errorClasses: map[string]int{},
+	}
+}
+
+// newRunID will return a random ID for a run
+func newRunID() string {
+	b := make([]byte, 8)
+	// crypto/rand only fails if the system's random source is broken
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// addJoined will record that auto-archiver joined a channel
+func (r *runResult) addJoined() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Counts.Joined++
+}
+
+// addChannel will record the decision made for a channel and whether it failed
+func (r *runResult) addChannel(c slack.Channel, d decision, reason archiveReason, daysInactive int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := channelResult{
+		ID:           c.ID,
+		Name:         c.Name,
+		Decision:     d,
+		Reason:       reason,
+		DaysInactive: daysInactive,
+		Workspace:    r.teamID,
+	}
+	if c.Created != 0 {
+		result.AgeDays = int(r.StartedAt.Sub(c.Created.Time()).Hours() / 24)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.ErrorClass = classifyError(err)
+		r.errorClasses[result.ErrorClass]++
+	}
+
+	r.Channels = append(r.Channels, result)
+}
+
+// addReportOnly will record a private channel that would have been warned or archived, but was only reported on
+func (r *runResult) addReportOnly(c slack.Channel, d decision, reason archiveReason, daysInactive int) {
+	r.addChannel(c, d, reason, daysInactive, nil)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Channels[len(r.Channels)-1].ReportOnly = true
+}
+
+// addError will record an error that stopped the run
+func (r *runResult) addError(err error) {
+	r.Errors = append(r.Errors, err.Error())
+	r.errorClasses[classifyError(err)]++
+}
+
+// finish will calculate the duration and the counts of the run
+func (r *runResult) finish(now time.Time) {
+	r.DurationMS = now.Sub(r.StartedAt).Milliseconds()
+
+	r.Counts.Scanned = len(r.Channels)
+	for _, c := range r.Channels {
+		switch {
+		case c.Error != "":
+			r.Counts.Failed++
+		case c.ReportOnly:
+			r.Counts.ReportOnly++
+		case c.Decision == decisionKeep:
+			r.Counts.Kept++
+		case c.Decision == decisionWarn:
+			r.Counts.Warned++
+		case c.Decision == decisionArchive:
+			r.Counts.Archived++
+		case c.Decision == decisionExempt:
+			r.Counts.Exempt++
+		}
+	}
+	r.ErrorClasses = sortedErrorClasses(r.errorClasses)
+	r.Lifecycle = archivedLifecycle(r.Channels)
+}
+
+// log will write the counts of a finished run as a single log record, so dashboards can chart runs from logs alone
+func (r *runResult) log(logger logr.Logger) {
+	logger.Info("run finished",
+		"scanned", r.Counts.Scanned,
+		"joined", r.Counts.Joined,
+		"kept", r.Counts.Kept,
+		"warned", r.Counts.Warned,
+		"archived", r.Counts.Archived,
+		"skipped", r.Counts.Exempt,
+		"report_only", r.Counts.ReportOnly,
+		"errors", r.Counts.Failed+len(r.Errors),
+		"duration_ms", r.DurationMS,
+	)
+
+	// One record for all errors, so the causes of a failing run do not have to be searched for in every channel's logs
+	if len(r.ErrorClasses) > 0 {
+		keysAndValues := make([]any, 0, 2*len(r.ErrorClasses))
+		for _, c := range r.ErrorClasses {
+			keysAndValues = append(keysAndValues, c.Class, c.Count)
+		}
+		logger.Info("run errors", keysAndValues...)
+	}
+
+	if r.Lifecycle != nil {
+		r.Lifecycle.log(logger)
+	}
+}
+
+// writeJSON will write the result as a single JSON document
+func (r *runResult) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Formats of the result files dropped to object storage, chosen with AUTO_ARCHIVER_RESULTS_FORMAT
+const (
+	resultsCSV     = "csv"
+	resultsParquet = "parquet"
+)
+
+// resultColumn is a column of the result files, whose value for a channel of a run is a string, int64, bool or
+// time.Time by kind
+type resultColumn struct {
+	name  string
+	kind  int
+	value func(r *runResult, c channelResult, dryRun bool) any
+}
+
+// resultColumns are the columns of the result files, one row per channel with the run it was evaluated in
+var resultColumns = []resultColumn{
+	{"run_id", parquetString, func(r *runResult, _ channelResult, _ bool) any { return r.RunID }},
+	{"started_at", parquetTimestamp, func(r *runResult, _ channelResult, _ bool) any { return r.StartedAt }},
+	{"dry_run", parquetBool, func(_ *runResult, _ channelResult, dryRun bool) any { return dryRun }},
+	{"channel_id", parquetString, func(_ *runResult, c channelResult, _ bool) any { return c.ID }},
+	{"channel_name", parquetString, func(_ *runResult, c channelResult, _ bool) any { return c.Name }},
+	{"decision", parquetString, func(_ *runResult, c channelResult, _ bool) any { return string(c.Decision) }},
+	{"reason", parquetString, func(_ *runResult, c channelResult, _ bool) any { return string(c.Reason) }},
+	{"days_inactive", parquetInt64, func(_ *runResult, c channelResult, _ bool) any { return int64(c.DaysInactive) }},
+	{"age_days", parquetInt64, func(_ *runResult, c channelResult, _ bool) any { return int64(c.AgeDays) }},
+	{"error", parquetString, func(_ *runResult, c channelResult, _ bool) any { return c.Error }},
+	{"error_class", parquetString, func(_ *runResult, c channelResult, _ bool) any { return c.ErrorClass }},
+	{"report_only", parquetBool, func(_ *runResult, c channelResult, _ bool) any { return c.ReportOnly }},
+}
+
+// resultsDrop writes the results of each run as a date partitioned file to object storage, for warehouses such as
+// Snowflake or Athena to ingest without auto-archiver needing warehouse credentials
+type resultsDrop struct {
+	target Exporter
+	format string
+}
+
+// newResultsDrop will create a drop writing under a gs://bucket/prefix or s3://bucket/prefix URL
+func newResultsDrop(ctx context.Context, rawURL, format string) (*resultsDrop, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse results URL: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("results URL must be gs://bucket/prefix or s3://bucket/prefix, got %s", rawURL)
+	}
+
+	prefix := strings.Trim(u.Path, "/")
+	var target Exporter
+	switch u.Scheme {
+	case "gs":
+		target, err = newGCSTarget(ctx, u.Host, prefix, "")
+	case "s3":
+		target, err = newS3Target(ctx, u.Host, prefix)
+	default:
+		return nil, fmt.Errorf("results URL must be gs://bucket/prefix or s3://bucket/prefix, got %s", rawURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &resultsDrop{target: target, format: format}, nil
+}
+
+// write will write a finished run as <prefix>/dt=<date>/run-<run ID>.<format>, partitioned by the day it started
+// in UTC, and return where it was written to
+func (d *resultsDrop) write(ctx context.Context, r *runResult, dryRun bool) (string, error) {
+	var data []byte
+	var err error
+	switch d.format {
+	case resultsParquet:
+		data, err = resultsParquetFile(r, dryRun)
+	default:
+		data, err = resultsCSVFile(r, dryRun)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	name := path.Join("dt="+r.StartedAt.UTC().Format(time.DateOnly), "run-"+r.RunID+"."+d.format)
+	return d.target.Write(ctx, name, data, map[string]string{"run-id": r.RunID})
+}
+
+// resultsCSVFile will return the channels of a run as CSV with a header row
+func resultsCSVFile(r *runResult, dryRun bool) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	header := make([]string, len(resultColumns))
+	for i, col := range resultColumns {
+		header[i] = col.name
+	}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	record := make([]string, len(resultColumns))
+	for _, c := range r.Channels {
+		for i, col := range resultColumns {
+			switch v := col.value(r, c, dryRun).(type) {
+			case string:
+				record[i] = v
+			case int64:
+				record[i] = strconv.FormatInt(v, 10)
+			case bool:
+				record[i] = strconv.FormatBool(v)
+			case time.Time:
+				record[i] = v.UTC().Format(time.RFC3339)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// resultsParquetFile will return the channels of a run as a Parquet file
+func resultsParquetFile(r *runResult, dryRun bool) ([]byte, error) {
+	columns := make([]parquetColumn, len(resultColumns))
+	for i, col := range resultColumns {
+		columns[i] = parquetColumn{name: col.name, kind: col.kind, values: make([]any, 0, len(r.Channels))}
+		for _, c := range r.Channels {
+			columns[i].values = append(columns[i].values, col.value(r, c, dryRun))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, columns, len(r.Channels)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeResults will write a finished run to the configured results drop
+func writeResults(ctx context.Context, logger logr.Logger, cfg *config, r *runResult) error {
+	drop, err := newResultsDrop(ctx, cfg.resultsURL, cfg.resultsFormat)
+	if err != nil {
+		return err
+	}
+
+	location, err := drop.write(ctx, r, cfg.dryRun)
+	if err != nil {
+		return err
+	}
+	logger.Info("wrote run result", "location", location)
+
+	return nil
+}
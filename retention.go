@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/slack-go/slack"
+)
+
+// What is done with channels that have a custom retention policy, chosen with AUTO_ARCHIVER_CUSTOM_RETENTION_ACTION
+const (
+	// retentionExempt skips the channels as exempt
+	retentionExempt = "exempt"
+	// retentionSkipExport archives the channels as usual, but without exporting their history
+	retentionSkipExport = "skip_export"
+)
+
+// retentionExemptedBy is who custom retention exemptions are recorded as exempted by
+const retentionExemptedBy = "retention"
+
+// customRetention is a channel's message retention policy when it differs from the workspace's
+type customRetention struct {
+	Enabled bool `json:"is_policy_enabled"`
+	Days    int  `json:"duration_days"`
+}
+
+// getCustomRetention will get the custom retention policy of a channel. It needs an org admin's user token
+// with the admin.conversations:read scope.
+func (r *rawSlackClient) getCustomRetention(ctx context.Context, channelID string) (customRetention, error) {
+	var retention customRetention
+	err := r.call(ctx, "admin.conversations.getCustomRetention", url.Values{"channel_id": {channelID}}, &retention)
+
+	return retention, err
+}
+
+// retentionExemption will return the exemption of a channel with a custom retention policy
+func retentionExemption(c slack.Channel, retention customRetention) *exemption {
+	return &exemption{
+		ChannelID:   c.ID,
+		ChannelName: c.Name,
+		Reason:      fmt.Sprintf("the channel has a custom retention policy of %d days", retention.Days),
+		ExemptedBy:  retentionExemptedBy,
+	}
+}
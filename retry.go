@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/slack-go/slack"
+)
+
+// bucketArchiveRetries holds the channels whose archiving failed transiently, by channel ID
+const bucketArchiveRetries = "archive_retries"
+
+// archiveRetry is a channel to archive again at the start of the next run
+type archiveRetry struct {
+	ChannelID   string `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	// Shard is the bot shard that failed to archive the channel
+	Shard int `json:"shard"`
+	// Attempts are how many times archiving the channel failed
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// queueArchiveRetry will record that archiving a channel failed, so the next run tries again before scanning
+func (a *ArchiveSlacker) queueArchiveRetry(ctx context.Context, c slack.Channel, attempts int, err error) error {
+	return putJSON(ctx, a.store, bucketArchiveRetries, c.ID, archiveRetry{
+		ChannelID:   c.ID,
+		ChannelName: c.Name,
+		Shard:       a.shard,
+		Attempts:    attempts,
+		LastError:   err.Error(),
+		FailedAt:    time.Now(),
+	}, 0)
+}
+
+// listArchiveRetries will return every queued archive retry
+func listArchiveRetries(ctx context.Context, store Store) ([]archiveRetry, error) {
+	values, err := store.List(ctx, bucketArchiveRetries)
+	if err != nil {
+		return nil, err
+	}
+
+	retries := []archiveRetry{}
+	for key, value := range values {
+		r := archiveRetry{}
+		if err := json.Unmarshal(value, &r); err != nil {
+			return nil, fmt.Errorf("can not decode %s/%s: %w", bucketArchiveRetries, key, err)
+		}
+		retries = append(retries, r)
+	}
+
+	return retries, nil
+}
+
+// retryArchives will try again to archive the channels whose archiving failed transiently in earlier runs, before
+// any channel is scanned. Each channel is evaluated again first, so channels that became active or exempt since are
+// dropped from the queue. It returns the channels that were acted on, which the scan skips, and how many were
+// archived, which count towards the maximum archives per run. Retries over the maximum stay queued for a later run.
+func retryArchives(ctx context.Context, logger logr.Logger, cfg *config, slackers []*ArchiveSlacker, store Store, pace *pacer, summary *summaryMessageData) (map[string]bool, int, error) {
+	retries, err := listArchiveRetries(ctx, store)
+	if err != nil {
+		return nil, 0, fmt.Errorf("can not list archive retries: %w", err)
+	}
+
+	retried := map[string]bool{}
+	archived := 0
+	for _, r := range retries {
+		if cfg.maxArchivesPerRun > 0 && archived >= cfg.maxArchivesPerRun {
+			logger.Info("reached the maximum archives per run, leaving archive retries for a later run")
+			break
+		}
+
+		// Triggered runs leave the channels they are not limited to for a later run
+		if cfg.channelFilter != nil && !cfg.channelFilter[r.ChannelID] && !cfg.channelFilter[r.ChannelName] {
+			continue
+		}
+
+		a := slackers[0]
+		if r.Shard < len(slackers) {
+			a = slackers[r.Shard]
+		}
+		logger := logger.WithValues("channel", r.ChannelName, "attempt", r.Attempts+1)
+
+		drop := func(msg string) {
+			logger.Info(msg)
+			if err := store.Delete(ctx, bucketArchiveRetries, r.ChannelID); err != nil {
+				logger.Error(err, "failed to remove archive retry")
+			}
+		}
+
+		c, err := a.client.GetConversationInfoContext(ctx, &slack.GetConversationInfoInput{ChannelID: r.ChannelID})
+		if err != nil {
+			if isTransientError(err) {
+				logger.Error(err, "failed to get channel to retry archiving, retrying next run")
+				continue
+			}
+			logger.Error(err, "failed to get channel to retry archiving")
+			drop("dropping archive retry")
+			continue
+		}
+		if c.IsArchived {
+			drop("channel was archived since, dropping archive retry")
+			continue
+		}
+		// Only channels the bot could not join are archived without it being a member
+		if !c.IsMember && a.admin != nil {
+			a.adminChannels[c.ID] = true
+		}
+
+		// Ask the exemption service and the group directory too, as either may exempt the channel since it failed
+		now := a.now()
+		e, err := a.evaluateChannel(ctx, *c, now)
+		if err == nil {
+			e, err = a.checkCandidateExemptions(ctx, *c, e, now)
+		}
+		if err != nil {
+			logger.Error(err, "could not determine if channel is still archivable, retrying next run")
+			continue
+		}
+		if e.decision != decisionArchive {
+			// The scan decides what to do with the channel now
+			drop("channel is no longer archivable, dropping archive retry")
+			continue
+		}
+
+		inactive := inactiveChannel{
+			channel:      *c,
+			daysInactive: e.daysInactive,
+			threshold:    e.threshold,
+			archiveDate:  e.archiveDate,
+			reason:       e.reason,
+			skipExport:   e.skipExport,
+		}
+		retried[c.ID] = true
+
+		allowed, exempt, err := slackers[0].isArchiveAllowed(ctx, inactive)
+		if err != nil {
+			logger.Error(err, "failed to check if channel may be archived, retrying next run")
+			a.result.addChannel(*c, decisionArchive, e.reason, e.daysInactive, err)
+			summary.Failed = append(summary.Failed, summaryChannel{Channel: *c, Reason: e.reason})
+			continue
+		}
+		if exempt != nil {
+			a.result.addChannel(*c, decisionExempt, "", 0, nil)
+			summary.Exempt = append(summary.Exempt, summaryChannel{Channel: *c, Exemption: exempt})
+			drop("channel was exempted, dropping archive retry")
+			continue
+		}
+		if !allowed {
+			a.result.addChannel(*c, decisionKeep, e.reason, e.daysInactive, nil)
+			drop("channel may no longer be archived, dropping archive retry")
+			continue
+		}
+
+		if err := pace.wait(ctx); err != nil {
+			return retried, archived, err
+		}
+		logger.Info("retrying archiving channel", "reason", e.reason)
+		err = a.autoarchiveChannel(ctx, inactive)
+		a.result.addChannel(*c, decisionArchive, e.reason, e.daysInactive, err)
+		if err == nil {
+			summary.Archived = append(summary.Archived, summaryChannel{Channel: *c, Reason: e.reason})
+			archived++
+			drop("archived channel on retry")
+			continue
+		}
+
+		logger.Error(err, "failed to archive channel on retry")
+		a.notifier.error(ctx, c, err)
+		summary.Failed = append(summary.Failed, summaryChannel{Channel: *c, Reason: e.reason})
+		if !isTransientError(err) || r.Attempts >= cfg.archiveRetryAttempts {
+			drop("giving up retrying archiving channel")
+			continue
+		}
+		if err := a.queueArchiveRetry(ctx, *c, r.Attempts+1, err); err != nil {
+			logger.Error(err, "failed to queue archive retry")
+		}
+	}
+
+	return retried, archived, nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// rollupTopPrefixes is how many of the prefixes with the most channel debt the rollup lists
+const rollupTopPrefixes = 5
+
+// prefixCount is how many channels have a name prefix
+type prefixCount struct {
+	Prefix string `json:"prefix"`
+	Count  int    `json:"count"`
+}
+
+// workspaceRollup is what a run did in one workspace of an Enterprise Grid organization, or in all of them
+type workspaceRollup struct {
+	TeamID   string `json:"team_id"`
+	Scanned  int    `json:"scanned"`
+	Kept     int    `json:"kept"`
+	Warned   int    `json:"warned"`
+	Archived int    `json:"archived"`
+	Exempt   int    `json:"exempt"`
+	Failed   int    `json:"failed"`
+	// Debt is the channels that were inactive for long enough to be warned or archived, and Archived the debt that
+	// was reclaimed
+	Debt int `json:"debt"`
+	// TopPrefixes are the name prefixes with the most channel debt, the most first
+	TopPrefixes []prefixCount `json:"top_prefixes"`
+
+	prefixes map[string]int
+}
+
+// reclaimedPercent is how much of the channel debt was archived
+func (w *workspaceRollup) reclaimedPercent() int {
+	if w.Debt == 0 {
+		return 0
+	}
+
+	return w.Archived * 100 / w.Debt
+}
+
+// add will count a channel's result
+func (w *workspaceRollup) add(c channelResult) {
+	w.Scanned++
+	switch {
+	case c.Error != "":
+		w.Failed++
+	case c.Decision == decisionKeep:
+		w.Kept++
+	case c.Decision == decisionExempt:
+		w.Exempt++
+	case c.Decision == decisionWarn && !c.ReportOnly:
+		w.Warned++
+	case c.Decision == decisionArchive && !c.ReportOnly:
+		w.Archived++
+	}
+
+	if c.Decision == decisionWarn || c.Decision == decisionArchive {
+		w.Debt++
+		w.prefixes[namePrefix(c.Name)]++
+	}
+}
+
+// orgRollup aggregates a run of an org-wide install over its workspaces, for the org's admins
+type orgRollup struct {
+	RunID      string            `json:"run_id"`
+	Workspaces []workspaceRollup `json:"workspaces"`
+	Total      workspaceRollup   `json:"total"`
+}
+
+// newOrgRollup will aggregate the channels of a run by workspace, in the order the workspaces were run in
+func newOrgRollup(r *runResult, teamIDs []string) *orgRollup {
+	rollups := map[string]*workspaceRollup{}
+	for _, teamID := range teamIDs {
+		rollups[teamID] = &workspaceRollup{TeamID: teamID, prefixes: map[string]int{}}
+	}
+	total := workspaceRollup{TeamID: "total", prefixes: map[string]int{}}
+
+	for _, c := range r.Channels {
+		if w := rollups[c.Workspace]; w != nil {
+			w.add(c)
+		}
+		total.add(c)
+	}
+
+	o := &orgRollup{RunID: r.RunID}
+	for _, teamID := range teamIDs {
+		w := rollups[teamID]
+		w.TopPrefixes = topPrefixes(w.prefixes, rollupTopPrefixes)
+		o.Workspaces = append(o.Workspaces, *w)
+	}
+	total.TopPrefixes = topPrefixes(total.prefixes, rollupTopPrefixes)
+	o.Total = total
+
+	return o
+}
+
+// topPrefixes will return the n prefixes with the most channels, the most first and ties by name
+func topPrefixes(counts map[string]int, n int) []prefixCount {
+	prefixes := make([]prefixCount, 0, len(counts))
+	for prefix, count := range counts {
+		prefixes = append(prefixes, prefixCount{Prefix: prefix, Count: count})
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if prefixes[i].Count != prefixes[j].Count {
+			return prefixes[i].Count > prefixes[j].Count
+		}
+		return prefixes[i].Prefix < prefixes[j].Prefix
+	})
+
+	return prefixes[:min(n, len(prefixes))]
+}
+
+// text will describe the rollup for a Slack message
+func (o *orgRollup) text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*auto-archiver org rollup* (run %s)\n", o.RunID)
+	for _, w := range append(slices.Clip(o.Workspaces), o.Total) {
+		fmt.Fprintf(&b, "• *%s*: %d scanned, %d archived, %d warned, %d exempt, %d failed, %d%% of %d channel debt reclaimed",
+			w.TeamID, w.Scanned, w.Archived, w.Warned, w.Exempt, w.Failed, w.reclaimedPercent(), w.Debt)
+		if len(w.TopPrefixes) > 0 {
+			fmt.Fprintf(&b, ", top prefixes %s", formatPrefixes(w.TopPrefixes, " "))
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// formatPrefixes will list prefixes with their counts, such as "proj:12 tmp:4"
+func formatPrefixes(prefixes []prefixCount, sep string) string {
+	parts := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		parts[i] = fmt.Sprintf("%s:%d", p.Prefix, p.Count)
+	}
+
+	return strings.Join(parts, sep)
+}
+
+// writeCSV will write a row for each workspace and one for the whole organization
+func (o *orgRollup) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	rows := [][]string{{"run_id", "workspace", "scanned", "kept", "warned", "archived", "exempt", "failed", "debt",
+		"reclaimed_percent", "top_prefixes"}}
+	for _, ws := range append(slices.Clip(o.Workspaces), o.Total) {
+		rows = append(rows, []string{o.RunID, ws.TeamID, strconv.Itoa(ws.Scanned), strconv.Itoa(ws.Kept),
+			strconv.Itoa(ws.Warned), strconv.Itoa(ws.Archived), strconv.Itoa(ws.Exempt), strconv.Itoa(ws.Failed),
+			strconv.Itoa(ws.Debt), strconv.Itoa(ws.reclaimedPercent()), formatPrefixes(ws.TopPrefixes, ";")})
+	}
+
+	return cw.WriteAll(rows)
+}
+
+// postOrgRollup will post the rollup to the org admin channel, with the rollup attached as a CSV file
+func postOrgRollup(ctx context.Context, client *slack.Client, channelID string, o *orgRollup) error {
+	var buf bytes.Buffer
+	if err := o.writeCSV(&buf); err != nil {
+		return err
+	}
+
+	_, err := client.UploadFileV2Context(ctx, slack.UploadFileV2Parameters{
+		Channel:        channelID,
+		Content:        buf.String(),
+		FileSize:       buf.Len(),
+		Filename:       fmt.Sprintf("auto-archiver-rollup-%s.csv", o.RunID),
+		Title:          "auto-archiver org rollup",
+		InitialComment: o.text(),
+	})
+	return err
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// socketEvent is a request received over Socket Mode, with what its handler needs to answer it
+type socketEvent struct {
+	// route is the slash command, callback ID, action ID or event type the request was routed by
+	route string
+	// userID is who sent the command or interaction, empty for events
+	userID string
+	// teamID is the workspace the command or event came from
+	teamID string
+
+	// Only the field of the request's kind is set
+	command  slack.SlashCommand
+	callback slack.InteractionCallback
+	// action is the first block action of a block actions interaction
+	action *slack.BlockAction
+	// event is the inner event of an Events API request, such as *slackevents.AppMentionEvent
+	event any
+
+	socket *socketmode.Client
+	req    *socketmode.Request
+	acked  bool
+}
+
+// ack will acknowledge the request, with payload as the response if one is given. Requests are only acknowledged
+// once, and the router acknowledges those their handler did not after it returns.
+func (e *socketEvent) ack(payload ...any) {
+	if e.acked || e.req == nil {
+		return
+	}
+	e.acked = true
+	e.socket.Ack(*e.req, payload...)
+}
+
+// socketHandler handles a routed Socket Mode request
+type socketHandler func(ctx context.Context, e *socketEvent)
+
+// socketMiddleware wraps a handler, such as to check the user may use it
+type socketMiddleware func(next socketHandler) socketHandler
+
+// socketRouter routes Socket Mode requests to handlers by slash command, by callback or action ID for
+// interactions, and by type for Events API events, so each command or shortcut is registered in one place
+type socketRouter struct {
+	socket *socketmode.Client
+	logger logr.Logger
+
+	commands     map[string]socketHandler
+	interactions map[string]socketHandler
+	events       map[string]socketHandler
+	// middleware wraps every handler, outside of the middleware of each route
+	middleware []socketMiddleware
+}
+
+func newSocketRouter(socket *socketmode.Client, logger logr.Logger) *socketRouter {
+	return &socketRouter{
+		socket:       socket,
+		logger:       logger,
+		commands:     map[string]socketHandler{},
+		interactions: map[string]socketHandler{},
+		events:       map[string]socketHandler{},
+	}
+}
+
+// use will add middleware wrapping every handler
+func (r *socketRouter) use(mw ...socketMiddleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// command will route a slash command to h
+func (r *socketRouter) command(name string, h socketHandler, mw ...socketMiddleware) {
+	r.commands[name] = chainMiddleware(h, mw)
+}
+
+// interaction will route shortcuts and modal submissions with a callback ID, or clicks of buttons with an action
+// ID, to h
+func (r *socketRouter) interaction(id string, h socketHandler, mw ...socketMiddleware) {
+	r.interactions[id] = chainMiddleware(h, mw)
+}
+
+// event will route Events API events of a type, such as app_mention, to h
+func (r *socketRouter) event(eventType slackevents.EventsAPIType, h socketHandler, mw ...socketMiddleware) {
+	r.events[string(eventType)] = chainMiddleware(h, mw)
+}
+
+// chainMiddleware will wrap h in middleware, the first being the outermost
+func chainMiddleware(h socketHandler, mw []socketMiddleware) socketHandler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+
+	return h
+}
+
+// route will pass a Socket Mode event to the handler of its route, acknowledging requests without one
+func (r *socketRouter) route(ctx context.Context, evt socketmode.Event) {
+	e := &socketEvent{socket: r.socket, req: evt.Request}
+	var h socketHandler
+	switch evt.Type {
+	case socketmode.EventTypeConnecting:
+		r.logger.V(1).Info("connecting to slack with socket mode")
+		return
+	case socketmode.EventTypeConnected:
+		r.logger.Info("connected to slack with socket mode")
+		return
+	case socketmode.EventTypeSlashCommand:
+		cmd, ok := evt.Data.(slack.SlashCommand)
+		if !ok {
+			return
+		}
+		e.command, e.route, e.userID, e.teamID = cmd, cmd.Command, cmd.UserID, cmd.TeamID
+		h = r.commands[e.route]
+	case socketmode.EventTypeInteractive:
+		callback, ok := evt.Data.(slack.InteractionCallback)
+		if !ok {
+			return
+		}
+		e.callback, e.userID = callback, callback.User.ID
+		switch callback.Type {
+		case slack.InteractionTypeMessageAction, slack.InteractionTypeShortcut:
+			e.route = callback.CallbackID
+		case slack.InteractionTypeViewSubmission:
+			e.route = callback.View.CallbackID
+		case slack.InteractionTypeBlockActions:
+			if len(callback.ActionCallback.BlockActions) > 0 {
+				e.action = callback.ActionCallback.BlockActions[0]
+				e.route = e.action.ActionID
+			}
+		}
+		if e.route != "" {
+			h = r.interactions[e.route]
+		}
+	case socketmode.EventTypeEventsAPI:
+		event, ok := evt.Data.(slackevents.EventsAPIEvent)
+		if !ok {
+			return
+		}
+		// Events only need acknowledging, replies are posted separately
+		e.ack()
+		if event.Type != slackevents.CallbackEvent {
+			return
+		}
+		e.event, e.route, e.teamID = event.InnerEvent.Data, event.InnerEvent.Type, event.TeamID
+		h = r.events[e.route]
+	default:
+		return
+	}
+
+	if h != nil {
+		chainMiddleware(h, r.middleware)(ctx, e)
+	}
+	e.ack()
+}
+
+// logSocketEvents is middleware that logs each routed request and how long handling it took
+func logSocketEvents(logger logr.Logger) socketMiddleware {
+	return func(next socketHandler) socketHandler {
+		return func(ctx context.Context, e *socketEvent) {
+			start := time.Now()
+			next(ctx, e)
+			logger.V(1).Info("handled socket mode request", "route", e.route, "user", e.userID, "duration", time.Since(start))
+		}
+	}
+}
+
+// ackFirst is middleware that acknowledges the request before handling it, for handlers that take longer than
+// the three seconds Slack waits and answer separately
+func ackFirst(next socketHandler) socketHandler {
+	return func(ctx context.Context, e *socketEvent) {
+		e.ack()
+		next(ctx, e)
+	}
+}
+
+// requireAuthorized is middleware that only lets users who may manage auto-archiver through, answering everyone
+// else with denied
+func (d *daemon) requireAuthorized(denied string, answer func(ctx context.Context, e *socketEvent, text string)) socketMiddleware {
+	return func(next socketHandler) socketHandler {
+		return func(ctx context.Context, e *socketEvent) {
+			authorized, err := d.isAuthorized(ctx, e.userID)
+			if err != nil {
+				d.logger.Error(err, "failed to check if user may manage auto-archiver", "user", e.userID, "route", e.route)
+				answer(ctx, e, "Something went wrong, please try again.")
+				return
+			}
+			if !authorized {
+				answer(ctx, e, denied)
+				return
+			}
+
+			next(ctx, e)
+		}
+	}
+}
+
+// answerEphemeral will answer the user of an interaction with a message only they see in its channel
+func (d *daemon) answerEphemeral(ctx context.Context, e *socketEvent, text string) {
+	if _, err := d.api.PostEphemeralContext(ctx, e.callback.Channel.ID, e.userID, slack.MsgOptionText(text, false)); err != nil {
+		d.logger.Error(err, "failed to answer interaction", "user", e.userID, "route", e.route)
+	}
+}
+
+// answerDirect will answer the user of an interaction with a direct message, for interactions outside of a channel
+// such as in the App Home
+func (d *daemon) answerDirect(ctx context.Context, e *socketEvent, text string) {
+	dm, _, _, err := d.api.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{e.userID}})
+	if err == nil {
+		_, _, err = d.api.PostMessageContext(ctx, dm.ID, slack.MsgOptionText(text, false))
+	}
+	if err != nil {
+		d.logger.Error(err, "failed to answer interaction", "user", e.userID, "route", e.route)
+	}
+}
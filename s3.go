@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3Target writes files to an S3 bucket, authenticating with the default AWS credentials
+type s3Target struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func newS3Target(ctx context.Context, bucket, prefix string) (*s3Target, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not load AWS configuration: %w", err)
+	}
+
+	return &s3Target{client: s3.NewFromConfig(awsCfg), bucket: bucket, prefix: strings.Trim(prefix, "/")}, nil
+}
+
+func (t *s3Target) Write(ctx context.Context, name string, data []byte, metadata map[string]string) (string, error) {
+	key := path.Join(t.prefix, name)
+	_, err := t.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(t.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType(name)),
+		Metadata:    metadata,
+	})
+	if err != nil {
+		return "", fmt.Errorf("can not upload s3://%s/%s: %w", t.bucket, key, err)
+	}
+
+	return fmt.Sprintf("s3://%s/%s", t.bucket, key), nil
+}
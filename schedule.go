@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// bucketScanSchedule holds when each kept channel next needs scanning, by channel ID
+const bucketScanSchedule = "scan_schedule"
+
+// scanSchedule is when a kept channel next needs scanning. New activity only moves the day a channel needs
+// attention later, so a channel last active at LastActivity can not need warning before NextScan.
+type scanSchedule struct {
+	ChannelID    string    `json:"channel_id"`
+	ChannelName  string    `json:"channel_name"`
+	LastActivity time.Time `json:"last_activity"`
+	// Threshold is the threshold NextScan was calculated with, the channel is scanned again if it is lowered
+	Threshold int       `json:"threshold"`
+	NextScan  time.Time `json:"next_scan"`
+}
+
+// adaptiveScan reports whether channels are only scanned once they could need attention
+func (a *ArchiveSlacker) adaptiveScan() bool {
+	return a.maxScanIntervalDays > 0 && a.store != nil && a.since.IsZero() && a.until.IsZero()
+}
+
+// scheduledEvaluation will return the evaluation of a channel whose next scan is not due yet, or nil if the
+// channel needs scanning
+func (a *ArchiveSlacker) scheduledEvaluation(ctx context.Context, c slack.Channel, now time.Time) (*channelEvaluation, error) {
+	var s scanSchedule
+	if err := getJSON(ctx, a.store, bucketScanSchedule, c.ID, &s); err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !now.Before(s.NextScan) || a.policy.threshold(c, a.channelThreshold(c)) < s.Threshold {
+		return nil, nil
+	}
+
+	return &channelEvaluation{
+		decision:     decisionKeep,
+		lastActivity: s.LastActivity,
+		daysInactive: int(now.Sub(s.LastActivity).Hours() / 24),
+		threshold:    s.Threshold,
+		archiveDate:  s.LastActivity.AddDate(0, 0, s.Threshold),
+		scheduled:    true,
+	}, nil
+}
+
+// scheduleNextScan will record when a kept channel next needs scanning: once it is within the warning period of
+// its archive date, or after the longest scan interval, so busy channels are scanned rarely and borderline channels
+// every run. The record expires when the scan is due, so those of channels archived since do not pile up. Dry runs
+// do not record it.
+func (a *ArchiveSlacker) scheduleNextScan(ctx context.Context, c slack.Channel, e channelEvaluation, now time.Time) error {
+	if e.scheduled || e.lastActivity.IsZero() || a.dryRun {
+		return nil
+	}
+
+	next := e.lastActivity.AddDate(0, 0, e.threshold-a.channelWarningDays(c))
+	next = minTime(next, now.AddDate(0, 0, a.maxScanIntervalDays))
+
+	return putJSON(ctx, a.store, bucketScanSchedule, c.ID, scanSchedule{
+		ChannelID:    c.ID,
+		ChannelName:  c.Name,
+		LastActivity: e.lastActivity,
+		Threshold:    e.threshold,
+		NextScan:     next,
+	}, max(next.Sub(now), time.Minute))
+}
+
+// minTime will return the earlier of two times
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+
+	return a
+}
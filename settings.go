@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// keyRuntimeSettings holds the settings changed from the App Home, in bucketMeta
+	keyRuntimeSettings = "runtime_settings"
+
+	// editSettingsActionID is the action ID of the App Home button that opens the settings modal
+	editSettingsActionID     = "edit_settings"
+	settingsModalCallbackID  = "settings_modal"
+	settingsThresholdBlockID = "threshold"
+	settingsDryRunBlockID    = "dry_run"
+	settingsMaxArchBlockID   = "max_archives"
+)
+
+// runtimeSettings are the settings authorized users change from the App Home. They are applied on top of the
+// environment and the setup wizard from the next run, without redeploying.
+type runtimeSettings struct {
+	ArchiveThreshold  int  `json:"archive_threshold"`
+	DryRun            bool `json:"dry_run"`
+	MaxArchivesPerRun int  `json:"max_archives_per_run"`
+
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// getRuntimeSettings will return the settings changed from the App Home, or nil if they never were
+func getRuntimeSettings(ctx context.Context, store Store) (*runtimeSettings, error) {
+	s := &runtimeSettings{}
+	if err := getJSON(ctx, store, bucketMeta, keyRuntimeSettings, s); err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// apply will return a copy of cfg with the settings changed from the App Home. They replace a dry run chosen in the
+// setup wizard, but a dry run from the command line, flagDryRun, is kept even when the App Home turned dry runs off.
+func (s *runtimeSettings) apply(cfg *config, flagDryRun bool) (*config, error) {
+	applied := *cfg
+	applied.archiveThreshold = s.ArchiveThreshold
+	applied.dryRun = flagDryRun || s.DryRun
+	applied.maxArchivesPerRun = s.MaxArchivesPerRun
+
+	if applied.maxArchivesPerRun < 0 {
+		return nil, fmt.Errorf("max archives per run can not be negative, got %d", applied.maxArchivesPerRun)
+	}
+	if err := applied.validateThreshold(); err != nil {
+		return nil, err
+	}
+
+	return &applied, nil
+}
+
+// applyStoredSettings will return a copy of cfg with the settings of the setup wizard and the App Home, and
+// whether runs are waiting for the setup wizard to be done
+func applyStoredSettings(ctx context.Context, cfg *config, store Store) (*config, bool, error) {
+	if store == nil {
+		return cfg, false, nil
+	}
+	flagDryRun := cfg.dryRun
+
+	if cfg.setupAdmin != "" {
+		settings, err := getSetupSettings(ctx, store)
+		if err != nil {
+			return nil, false, fmt.Errorf("can not get setup settings: %w", err)
+		}
+		if settings == nil {
+			return cfg, true, nil
+		}
+		if cfg, err = settings.apply(cfg); err != nil {
+			return nil, false, fmt.Errorf("can not apply setup settings: %w", err)
+		}
+	}
+
+	settings, err := getRuntimeSettings(ctx, store)
+	if err != nil {
+		return nil, false, fmt.Errorf("can not get runtime settings: %w", err)
+	}
+	if settings != nil {
+		if cfg, err = settings.apply(cfg, flagDryRun); err != nil {
+			return nil, false, fmt.Errorf("can not apply runtime settings: %w", err)
+		}
+	}
+
+	return cfg, false, nil
+}
+
+// publishHome will show the settings the next run uses in a user's App Home, with a button to change them for users
+// who may manage auto-archiver
+func (d *daemon) publishHome(ctx context.Context, userID string) error {
+	cfg, pending, err := applyStoredSettings(ctx, d.cfg, d.store)
+	if err != nil {
+		return err
+	}
+
+	maxArchives := "unlimited"
+	if cfg.maxArchivesPerRun > 0 {
+		maxArchives = strconv.Itoa(cfg.maxArchivesPerRun)
+	}
+	fields := []*slack.TextBlockObject{
+		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*Archive threshold*\n%d days", cfg.archiveThreshold), false, false),
+		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*Dry run*\n%s", yesNo(cfg.dryRun)), false, false),
+		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*Max archives per run*\n%s", maxArchives), false, false),
+	}
+
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, "auto-archiver settings", false, false)),
+		slack.NewSectionBlock(nil, fields, nil),
+	}
+	switch {
+	case pending:
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType,
+			"Nothing is archived until the setup wizard is done.", false, false)))
+	default:
+		settings, err := getRuntimeSettings(ctx, d.store)
+		if err != nil {
+			return err
+		}
+		if settings != nil {
+			blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType,
+				fmt.Sprintf("Last changed by <@%s> on %s.", settings.UpdatedBy, settings.UpdatedAt.Format(archiveDateLayout)), false, false)))
+		}
+	}
+
+	authorized, err := d.isAuthorized(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if authorized && !pending {
+		button := slack.NewButtonBlockElement(editSettingsActionID, "",
+			slack.NewTextBlockObject(slack.PlainTextType, "Change settings", false, false))
+		blocks = append(blocks, slack.NewActionBlock("", button))
+	}
+
+	_, err = d.api.PublishViewContext(ctx, userID, slack.HomeTabViewRequest{
+		Type:   slack.VTHomeTab,
+		Blocks: slack.Blocks{BlockSet: blocks},
+	}, "")
+	return err
+}
+
+// openSettingsModal will open the modal to change the settings, filled in with those the next run uses
+func (d *daemon) openSettingsModal(ctx context.Context, callback slack.InteractionCallback) {
+	cfg, _, err := applyStoredSettings(ctx, d.cfg, d.store)
+	if err != nil {
+		d.logger.Error(err, "failed to get settings", "user", callback.User.ID)
+		return
+	}
+
+	thresholdInput := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "Days without activity", false, false), settingsThresholdBlockID)
+	thresholdInput.InitialValue = strconv.Itoa(cfg.archiveThreshold)
+
+	maxArchivesInput := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "0 is unlimited", false, false), settingsMaxArchBlockID)
+	maxArchivesInput.InitialValue = strconv.Itoa(cfg.maxArchivesPerRun)
+
+	dryRunOption := slack.NewOptionBlockObject("dry_run",
+		slack.NewTextBlockObject(slack.PlainTextType, "Dry run, only report what would be archived", false, false), nil)
+	dryRunCheckbox := slack.NewCheckboxGroupsBlockElement(settingsDryRunBlockID, dryRunOption)
+	if cfg.dryRun {
+		dryRunCheckbox.InitialOptions = []*slack.OptionBlockObject{dryRunOption}
+	}
+	dryRunBlock := slack.NewInputBlock(settingsDryRunBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Mode", false, false), nil, dryRunCheckbox)
+	dryRunBlock.Optional = true
+
+	view := slack.ModalViewRequest{
+		Type:       slack.VTModal,
+		CallbackID: settingsModalCallbackID,
+		Title:      slack.NewTextBlockObject(slack.PlainTextType, "Change settings", false, false),
+		Submit:     slack.NewTextBlockObject(slack.PlainTextType, "Save", false, false),
+		Close:      slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewInputBlock(settingsThresholdBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Archive channels inactive for", false, false),
+				nil, thresholdInput),
+			dryRunBlock,
+			slack.NewInputBlock(settingsMaxArchBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Max archives per run", false, false),
+				nil, maxArchivesInput),
+		}},
+	}
+
+	if _, err := d.api.OpenViewContext(ctx, callback.TriggerID, view); err != nil {
+		d.logger.Error(err, "failed to open settings modal", "user", callback.User.ID)
+	}
+}
+
+// submitSettingsModal will store the settings from a submitted settings modal and refresh the user's App Home,
+// returning a response with errors to show in the modal if the settings are not valid or could not be stored
+func (d *daemon) submitSettingsModal(ctx context.Context, callback slack.InteractionCallback) *slack.ViewSubmissionResponse {
+	values := callback.View.State.Values
+	logger := d.logger.WithValues("user", callback.User.ID)
+
+	threshold, err := strconv.Atoi(strings.TrimSpace(values[settingsThresholdBlockID][settingsThresholdBlockID].Value))
+	if err != nil || threshold < 1 {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{settingsThresholdBlockID: "Enter a number of days."})
+	}
+	maxArchives, err := strconv.Atoi(strings.TrimSpace(values[settingsMaxArchBlockID][settingsMaxArchBlockID].Value))
+	if err != nil || maxArchives < 0 {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{settingsMaxArchBlockID: "Enter a number of channels, or 0 for unlimited."})
+	}
+
+	settings := &runtimeSettings{
+		ArchiveThreshold:  threshold,
+		DryRun:            len(values[settingsDryRunBlockID][settingsDryRunBlockID].SelectedOptions) > 0,
+		MaxArchivesPerRun: maxArchives,
+		UpdatedBy:         callback.User.ID,
+		UpdatedAt:         time.Now(),
+	}
+	// The settings are checked against the rest of the configuration they are applied to
+	cfg, _, err := applyStoredSettings(ctx, d.cfg, d.store)
+	if err != nil {
+		logger.Error(err, "failed to get settings")
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{settingsThresholdBlockID: "Something went wrong, please try again."})
+	}
+	if _, err := settings.apply(cfg, d.cfg.dryRun); err != nil {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{settingsThresholdBlockID: err.Error()})
+	}
+
+	if err := putJSON(ctx, d.store, bucketMeta, keyRuntimeSettings, settings, 0); err != nil {
+		logger.Error(err, "failed to store runtime settings")
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{settingsThresholdBlockID: "Something went wrong, please try again."})
+	}
+	logger.Info("changed settings", "archive_threshold", settings.ArchiveThreshold, "dry_run", settings.DryRun,
+		"max_archives_per_run", settings.MaxArchivesPerRun)
+
+	if err := d.publishHome(ctx, callback.User.ID); err != nil {
+		logger.Error(err, "failed to refresh app home")
+	}
+
+	return nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "Yes"
+	}
+	return "No"
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// keySetupSettings holds the settings chosen in the setup wizard, in bucketMeta
+	keySetupSettings = "setup_settings"
+	// keySetupPromptedAt is when the setup admin was sent the setup wizard, in bucketMeta
+	keySetupPromptedAt = "setup_prompted_at"
+
+	// setupActionID is the action ID of the button that opens the setup wizard
+	setupActionID         = "open_setup"
+	setupModalCallbackID  = "setup_modal"
+	setupThresholdBlockID = "threshold"
+	setupExcludeBlockID   = "exclude"
+	setupAdminBlockID     = "admin_channel"
+	setupDryRunBlockID    = "dry_run"
+
+	// defaultSetupThreshold is the archive threshold offered in the setup wizard when none is configured
+	defaultSetupThreshold = 90
+	// setupExcludeReason is the reason of the exemptions of channels excluded in the setup wizard
+	setupExcludeReason = "Excluded during setup"
+)
+
+// setupSettings are the settings chosen in the setup wizard. They take the place of the matching environment
+// variables on every run once stored.
+type setupSettings struct {
+	ArchiveThreshold int      `json:"archive_threshold"`
+	ExcludedChannels []string `json:"excluded_channels,omitempty"`
+	AdminChannel     string   `json:"admin_channel,omitempty"`
+	DryRun           bool     `json:"dry_run"`
+
+	ConfiguredBy string    `json:"configured_by"`
+	ConfiguredAt time.Time `json:"configured_at"`
+}
+
+// getSetupSettings will return the settings stored by the setup wizard, or nil if setup was not finished yet
+func getSetupSettings(ctx context.Context, store Store) (*setupSettings, error) {
+	s := &setupSettings{}
+	if err := getJSON(ctx, store, bucketMeta, keySetupSettings, s); err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// apply will return a copy of cfg with the settings of the setup wizard. A dry run from the command line is kept
+// even when the wizard turned dry runs off.
+func (s *setupSettings) apply(cfg *config) (*config, error) {
+	applied := *cfg
+	applied.archiveThreshold = s.ArchiveThreshold
+	applied.adminChannel = s.AdminChannel
+	applied.dryRun = cfg.dryRun || s.DryRun
+
+	if err := applied.validateThreshold(); err != nil {
+		return nil, err
+	}
+	if (applied.archiveApproval || applied.pinnedExemptions) && applied.adminChannel == "" {
+		return nil, errors.New("an admin channel is needed for archive approval and pinned exemptions")
+	}
+
+	return &applied, nil
+}
+
+// promptSetup will send the setup admin a direct message with a button opening the setup wizard, unless setup was
+// finished or they were already sent one
+func (d *daemon) promptSetup(ctx context.Context) error {
+	settings, err := getSetupSettings(ctx, d.store)
+	if err != nil {
+		return err
+	}
+	if settings != nil {
+		return nil
+	}
+
+	var promptedAt time.Time
+	if err := getJSON(ctx, d.store, bucketMeta, keySetupPromptedAt, &promptedAt); err == nil {
+		d.logger.Info("waiting for setup", "admin", d.cfg.setupAdmin, "prompted_at", promptedAt)
+		return nil
+	} else if !errors.Is(err, errNotFound) {
+		return err
+	}
+
+	dm, _, _, err := d.api.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{d.cfg.setupAdmin}})
+	if err != nil {
+		return err
+	}
+
+	text := "auto-archiver was installed in this workspace and needs setting up before it archives anything. " +
+		"Choose when channels count as inactive, which channels to leave alone and where to report to."
+	button := slack.NewButtonBlockElement(setupActionID, "",
+		slack.NewTextBlockObject(slack.PlainTextType, "Set up auto-archiver", false, false))
+	button.Style = slack.StylePrimary
+	_, _, err = d.api.PostMessageContext(ctx, dm.ID, slack.MsgOptionText(text, false), slack.MsgOptionBlocks(
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+		slack.NewActionBlock("", button),
+	))
+	if err != nil {
+		return err
+	}
+
+	d.logger.Info("sent setup wizard", "admin", d.cfg.setupAdmin)
+	return putJSON(ctx, d.store, bucketMeta, keySetupPromptedAt, time.Now(), 0)
+}
+
+// openSetupModal will open the setup wizard, filled in with the current settings
+func (d *daemon) openSetupModal(ctx context.Context, callback slack.InteractionCallback) {
+	current := setupSettings{ArchiveThreshold: d.cfg.archiveThreshold, AdminChannel: d.cfg.adminChannel, DryRun: true}
+	settings, err := getSetupSettings(ctx, d.store)
+	if err != nil {
+		d.logger.Error(err, "failed to get setup settings", "user", callback.User.ID)
+		return
+	}
+	if settings != nil {
+		current = *settings
+	}
+
+	thresholdInput := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "Days without activity", false, false), setupThresholdBlockID)
+	thresholdInput.InitialValue = strconv.Itoa(current.ArchiveThreshold)
+
+	excludeSelect := slack.NewOptionsMultiSelectBlockElement(slack.MultiOptTypeConversations,
+		slack.NewTextBlockObject(slack.PlainTextType, "Select channels", false, false), setupExcludeBlockID)
+	excludeSelect.InitialConversations = current.ExcludedChannels
+
+	adminSelect := slack.NewOptionsSelectBlockElement(slack.OptTypeConversations,
+		slack.NewTextBlockObject(slack.PlainTextType, "Select a channel", false, false), setupAdminBlockID)
+	adminSelect.InitialConversation = current.AdminChannel
+	adminSelect.Filter = &slack.SelectBlockElementFilter{Include: []string{"public", "private"}}
+
+	dryRunOption := slack.NewOptionBlockObject("dry_run",
+		slack.NewTextBlockObject(slack.PlainTextType, "Dry run, only report what would be archived", false, false), nil)
+	dryRunCheckbox := slack.NewCheckboxGroupsBlockElement(setupDryRunBlockID, dryRunOption)
+	if current.DryRun {
+		dryRunCheckbox.InitialOptions = []*slack.OptionBlockObject{dryRunOption}
+	}
+
+	optional := func(block *slack.InputBlock) *slack.InputBlock {
+		block.Optional = true
+		return block
+	}
+	view := slack.ModalViewRequest{
+		Type:       slack.VTModal,
+		CallbackID: setupModalCallbackID,
+		Title:      slack.NewTextBlockObject(slack.PlainTextType, "Set up auto-archiver", false, false),
+		Submit:     slack.NewTextBlockObject(slack.PlainTextType, "Save", false, false),
+		Close:      slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewInputBlock(setupThresholdBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Archive channels inactive for", false, false),
+				slack.NewTextBlockObject(slack.PlainTextType, "Days without messages before a channel is archived", false, false), thresholdInput),
+			optional(slack.NewInputBlock(setupExcludeBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Never archive", false, false),
+				slack.NewTextBlockObject(slack.PlainTextType, "These channels are exempt permanently", false, false), excludeSelect)),
+			optional(slack.NewInputBlock(setupAdminBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Admin channel", false, false),
+				slack.NewTextBlockObject(slack.PlainTextType, "Where a summary of each run is posted", false, false), adminSelect)),
+			optional(slack.NewInputBlock(setupDryRunBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Mode", false, false), nil, dryRunCheckbox)),
+		}},
+	}
+
+	if _, err := d.api.OpenViewContext(ctx, callback.TriggerID, view); err != nil {
+		d.logger.Error(err, "failed to open setup modal", "user", callback.User.ID)
+	}
+}
+
+// submitSetupModal will store the settings from a submitted setup wizard and exempt the excluded channels,
+// returning a response with errors to show in the modal if the settings are not valid or could not be stored
+func (d *daemon) submitSetupModal(ctx context.Context, callback slack.InteractionCallback) *slack.ViewSubmissionResponse {
+	values := callback.View.State.Values
+	logger := d.logger.WithValues("user", callback.User.ID)
+
+	threshold, err := strconv.Atoi(strings.TrimSpace(values[setupThresholdBlockID][setupThresholdBlockID].Value))
+	if err != nil || threshold < 1 {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{setupThresholdBlockID: "Enter a number of days."})
+	}
+
+	settings := &setupSettings{
+		ArchiveThreshold: threshold,
+		ExcludedChannels: values[setupExcludeBlockID][setupExcludeBlockID].SelectedConversations,
+		AdminChannel:     values[setupAdminBlockID][setupAdminBlockID].SelectedConversation,
+		DryRun:           len(values[setupDryRunBlockID][setupDryRunBlockID].SelectedOptions) > 0,
+		ConfiguredBy:     callback.User.ID,
+		ConfiguredAt:     time.Now(),
+	}
+	if _, err := settings.apply(d.cfg); err != nil {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{setupThresholdBlockID: err.Error()})
+	}
+
+	previous, err := getSetupSettings(ctx, d.store)
+	if err != nil {
+		logger.Error(err, "failed to get setup settings")
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{setupThresholdBlockID: "Something went wrong, please try again."})
+	}
+	for _, channelID := range settings.ExcludedChannels {
+		if previous != nil && slices.Contains(previous.ExcludedChannels, channelID) {
+			continue
+		}
+		if _, err := d.exemptChannel(ctx, channelID, callback.User.ID, 0, setupExcludeReason); err != nil {
+			logger.Error(err, "failed to exempt excluded channel", "channel", channelID)
+			return slack.NewErrorsViewSubmissionResponse(map[string]string{setupExcludeBlockID: "Something went wrong, please try again."})
+		}
+	}
+
+	if err := putJSON(ctx, d.store, bucketMeta, keySetupSettings, settings, 0); err != nil {
+		logger.Error(err, "failed to store setup settings")
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{setupThresholdBlockID: "Something went wrong, please try again."})
+	}
+	logger.Info("stored setup settings", "archive_threshold", settings.ArchiveThreshold, "excluded", len(settings.ExcludedChannels),
+		"admin_channel", settings.AdminChannel, "dry_run", settings.DryRun)
+
+	mode := "archive"
+	if settings.DryRun {
+		mode = "report what it would archive in"
+	}
+	confirmation := fmt.Sprintf("auto-archiver is set up and runs now. It will %s channels inactive for %d days, "+
+		"and %d channels are exempt.", mode, settings.ArchiveThreshold, len(settings.ExcludedChannels))
+	if dm, _, _, err := d.api.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{callback.User.ID}}); err == nil {
+		if _, _, err := d.api.PostMessageContext(ctx, dm.ID, slack.MsgOptionText(confirmation, false)); err != nil {
+			logger.V(1).Info("could not confirm setup", "error", err.Error())
+		}
+	}
+
+	return nil
+}
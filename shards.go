@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/slack-go/slack"
+)
+
+// botShard is one bot token of the pool channels are spread over, each app install having its own rate limits
+type botShard struct {
+	token  string
+	client *slack.Client
+}
+
+// newBotShards will create a client for the bot token and each extra bot token, the bot token's shard first
+func newBotShards(cfg *config, options ...slack.Option) []botShard {
+	options = append(options, slack.OptionHTTPClient(newSlackHTTPClient(cfg)))
+
+	shards := []botShard{}
+	for _, token := range append([]string{cfg.botToken}, cfg.extraBotTokens...) {
+		shards = append(shards, botShard{token: token, client: slack.New(token, options...)})
+	}
+
+	return shards
+}
+
+// newShardSlacker will create the ArchiveSlacker that acts on a shard's channels with the shard's token
+func newShardSlacker(logger logr.Logger, shard botShard, cfg *config, exportTarget Exporter, store Store, result *runResult) *ArchiveSlacker {
+	a := NewArchiveSlacker(logger, shard.client, cfg, exportTarget, store, result)
+	a.raw = newRawSlackClient(cfg, shard.token)
+
+	return a
+}
+
+// shardIndex will return the shard that joins a public channel, which stays the same between runs
+func shardIndex(channelID string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(channelID))
+
+	return int(h.Sum32() % uint32(shards))
+}
+
+// getShardChannels will get the channels each shard handles. A channel is handled by the first shard that is
+// already a member of it, and public channels no shard is a member of yet are spread over the shards by ID.
+func getShardChannels(ctx context.Context, slackers []*ArchiveSlacker) ([][]slack.Channel, error) {
+	visible := make([][]slack.Channel, len(slackers))
+	errs := make([]error, len(slackers))
+
+	// Each shard lists the channels itself, as membership differs between the bots
+	var wg sync.WaitGroup
+	for i, a := range slackers {
+		wg.Add(1)
+		go func(i int, a *ArchiveSlacker) {
+			defer wg.Done()
+			visible[i], errs[i] = a.getUnarchivedChannels(ctx)
+		}(i, a)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	memberShard := map[string]int{}
+	for i := len(visible) - 1; i >= 0; i-- {
+		for _, c := range visible[i] {
+			if c.IsMember {
+				memberShard[c.ID] = i
+			}
+		}
+	}
+
+	assigned := make([][]slack.Channel, len(slackers))
+	for i, channels := range visible {
+		for _, c := range channels {
+			shard, ok := memberShard[c.ID]
+			if !ok {
+				shard = shardIndex(c.ID, len(slackers))
+			}
+			if shard == i {
+				assigned[i] = append(assigned[i], c)
+			}
+		}
+	}
+
+	return assigned, nil
+}
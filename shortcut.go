@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// exemptShortcutCallbackID is the callback ID of the message and global "Exempt this channel" shortcuts
+	exemptShortcutCallbackID = "exempt_channel"
+	exemptModalCallbackID    = "exempt_channel_modal"
+
+	exemptChannelBlockID  = "channel"
+	exemptDurationBlockID = "duration"
+	exemptReasonBlockID   = "reason"
+
+	exemptPermanent = "permanent"
+)
+
+// exemptDurations are the durations offered in the exemption modal, in days
+var exemptDurations = []int{30, 90, 180, 365}
+
+// openExemptModal will open the modal to exempt a channel, preselecting the channel the shortcut was used in
+func (d *daemon) openExemptModal(ctx context.Context, callback slack.InteractionCallback) {
+	channelSelect := slack.NewOptionsSelectBlockElement(slack.OptTypeConversations,
+		slack.NewTextBlockObject(slack.PlainTextType, "Select a channel", false, false), exemptChannelBlockID)
+	channelSelect.InitialConversation = callback.Channel.ID
+	channelSelect.DefaultToCurrentConversation = true
+	channelSelect.Filter = &slack.SelectBlockElementFilter{Include: []string{"public", "private"}}
+
+	durationOptions := []*slack.OptionBlockObject{}
+	for _, days := range exemptDurations {
+		durationOptions = append(durationOptions, slack.NewOptionBlockObject(fmt.Sprintf("%dd", days),
+			slack.NewTextBlockObject(slack.PlainTextType, fmt.Sprintf("%d days", days), false, false), nil))
+	}
+	durationOptions = append(durationOptions, slack.NewOptionBlockObject(exemptPermanent,
+		slack.NewTextBlockObject(slack.PlainTextType, "Permanently", false, false), nil))
+
+	durationSelect := slack.NewOptionsSelectBlockElement(slack.OptTypeStatic,
+		slack.NewTextBlockObject(slack.PlainTextType, "Select a duration", false, false), exemptDurationBlockID, durationOptions...)
+	durationSelect.InitialOption = durationOptions[0]
+
+	reasonInput := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "Why should this channel be kept?", false, false), exemptReasonBlockID)
+
+	view := slack.ModalViewRequest{
+		Type:       slack.VTModal,
+		CallbackID: exemptModalCallbackID,
+		Title:      slack.NewTextBlockObject(slack.PlainTextType, "Exempt channel", false, false),
+		Submit:     slack.NewTextBlockObject(slack.PlainTextType, "Exempt", false, false),
+		Close:      slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewInputBlock(exemptChannelBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Channel", false, false), nil, channelSelect),
+			slack.NewInputBlock(exemptDurationBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Exempt for", false, false), nil, durationSelect),
+			slack.NewInputBlock(exemptReasonBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Reason", false, false), nil, reasonInput),
+		}},
+	}
+
+	if _, err := d.api.OpenViewContext(ctx, callback.TriggerID, view); err != nil {
+		d.logger.Error(err, "failed to open exemption modal", "user", callback.User.ID)
+	}
+}
+
+// submitExemptModal will store the exemption from a modal submitted by an authorized user, returning a response
+// with errors to show in the modal if the exemption could not be stored
+func (d *daemon) submitExemptModal(ctx context.Context, callback slack.InteractionCallback) *slack.ViewSubmissionResponse {
+	values := callback.View.State.Values
+	channelID := values[exemptChannelBlockID][exemptChannelBlockID].SelectedConversation
+	duration := values[exemptDurationBlockID][exemptDurationBlockID].SelectedOption.Value
+	reason := strings.TrimSpace(values[exemptReasonBlockID][exemptReasonBlockID].Value)
+
+	logger := d.logger.WithValues("channel", channelID, "user", callback.User.ID)
+
+	days := 0
+	if duration != exemptPermanent {
+		if _, err := fmt.Sscanf(duration, "%dd", &days); err != nil {
+			return slack.NewErrorsViewSubmissionResponse(map[string]string{exemptDurationBlockID: "Select a duration."})
+		}
+	}
+
+	e, err := d.exemptChannel(ctx, channelID, callback.User.ID, days, reason)
+	if err != nil {
+		logger.Error(err, "failed to store exemption")
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{exemptReasonBlockID: "Something went wrong, please try again."})
+	}
+
+	logger.Info("exempted channel", "until", e.Until, "reason", e.Reason)
+
+	confirmation := "This channel is now exempt from auto-archiving permanently."
+	if !e.Until.IsZero() {
+		confirmation = fmt.Sprintf("This channel is now exempt from auto-archiving until %s.", e.Until.Format(archiveDateLayout))
+	}
+	if _, err := d.api.PostEphemeralContext(ctx, channelID, callback.User.ID, slack.MsgOptionText(confirmation, false)); err != nil {
+		logger.V(1).Info("could not confirm exemption", "error", err.Error())
+	}
+
+	return nil
+}
+
+// exemptChannel will store an exemption of a channel for a number of days, or permanently if days is 0
+func (d *daemon) exemptChannel(ctx context.Context, channelID, userID string, days int, reason string) (exemption, error) {
+	now := time.Now()
+	e := exemption{
+		ChannelID:  channelID,
+		Reason:     reason,
+		ExemptedBy: userID,
+		CreatedAt:  now,
+	}
+	if days > 0 {
+		e.Until = now.AddDate(0, 0, days)
+	}
+
+	if info, err := d.api.GetConversationInfoContext(ctx, &slack.GetConversationInfoInput{ChannelID: channelID}); err == nil {
+		e.ChannelName = info.Name
+	}
+
+	return e, putExemption(ctx, d.store, e)
+}
+
+// isAuthorized will report whether a user may manage auto-archiver, which workspace admins and owners,
+// the configured authorized users and the setup admin can
+func (d *daemon) isAuthorized(ctx context.Context, userID string) (bool, error) {
+	if userID == d.cfg.setupAdmin {
+		return true, nil
+	}
+	for _, id := range d.cfg.authorizedUsers {
+		if id == userID {
+			return true, nil
+		}
+	}
+
+	user, err := d.api.GetUserInfoContext(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return user.IsAdmin || user.IsOwner || user.IsPrimaryOwner, nil
+}
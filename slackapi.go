@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// rawSlackClient calls Slack API methods, or reads response fields, that the slack SDK does not support yet
+type rawSlackClient struct {
+	httpClient *http.Client
+	apiURL     string
+	token      string
+}
+
+func newRawSlackClient(cfg *config, token string) *rawSlackClient {
+	return &rawSlackClient{
+		httpClient: newSlackHTTPClient(cfg),
+		apiURL:     cfg.slackAPIURL,
+		token:      token,
+	}
+}
+
+// newSlackHTTPClient will return the HTTP client for Slack API calls, so a single hung request can not stall a run
+func newSlackHTTPClient(cfg *config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: cfg.httpTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = cfg.httpTimeout
+	transport.ResponseHeaderTimeout = cfg.httpTimeout
+	transport.Proxy = proxyFunc(cfg)
+	transport.TLSClientConfig = tlsConfig(cfg)
+
+	var next http.RoundTripper = transport
+	if cfg.readOnly {
+		next = &readOnlyTransport{next: transport, apiURL: cfg.slackAPIURL}
+	}
+
+	if cfg.apiCalls != nil {
+		return &http.Client{Transport: &countingTransport{next: next, counter: cfg.apiCalls}, Timeout: cfg.apiCallTimeout}
+	}
+
+	return &http.Client{Transport: next, Timeout: cfg.apiCallTimeout}
+}
+
+// call will POST values to a Slack API method and decode the response into out, returning the
+// Slack error when the response is not ok
+func (r *rawSlackClient) call(ctx context.Context, method string, values url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.apiURL+method, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+r.token)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return &slack.RateLimitedError{RetryAfter: time.Duration(retryAfter) * time.Second}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", method, resp.Status)
+	}
+
+	var slackResp slack.SlackResponse
+	if err := json.Unmarshal(body, &slackResp); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if err := slackResp.Err(); err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+// archiveConversation will archive a channel, for archiving with a user token where only admins may archive channels.
+// It needs the channels:write scope, and groups:write for private channels.
+func (r *rawSlackClient) archiveConversation(ctx context.Context, channelID string) error {
+	return r.call(ctx, "conversations.archive", url.Values{"channel": {channelID}}, nil)
+}
+
+// getCanvasLastEdited will return when the canvas of a channel was last edited,
+// or the zero time if the channel has no canvas
+func (r *rawSlackClient) getCanvasLastEdited(ctx context.Context, channelID string) (time.Time, error) {
+	var info struct {
+		Channel struct {
+			Properties struct {
+				Canvas struct {
+					FileID string `json:"file_id"`
+				} `json:"canvas"`
+			} `json:"properties"`
+		} `json:"channel"`
+	}
+	if err := r.call(ctx, "conversations.info", url.Values{"channel": {channelID}}, &info); err != nil {
+		return time.Time{}, err
+	}
+
+	fileID := info.Channel.Properties.Canvas.FileID
+	if fileID == "" {
+		return time.Time{}, nil
+	}
+
+	var file struct {
+		File struct {
+			Updated int64 `json:"updated"`
+		} `json:"file"`
+	}
+	if err := r.call(ctx, "files.info", url.Values{"file": {fileID}}, &file); err != nil {
+		return time.Time{}, err
+	}
+
+	if file.File.Updated == 0 {
+		return time.Time{}, nil
+	}
+
+	return time.Unix(file.File.Updated, 0), nil
+}
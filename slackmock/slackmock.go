@@ -0,0 +1,437 @@
+// Package slackmock is a fake Slack Web API for end-to-end testing auto-archiver without a real workspace.
+//
+// A Server serves fixture channels, messages and users, records the channels joined and archived and the
+// messages posted, and can answer any method as rate limited. Point auto-archiver at it by setting
+// AUTO_ARCHIVER_SLACK_API_URL to the server's URL.
+package slackmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// defaultLimit is the page size of list methods when the request does not set one
+const defaultLimit = 100
+
+// Fixtures are the contents of the fake workspace
+type Fixtures struct {
+	// TeamID and BotUserID identify the workspace and bot in auth.test, they default to T0000000000 and U0000000000
+	TeamID    string
+	BotUserID string
+	Channels  []slack.Channel
+	// Messages are the top level messages of each channel by channel ID, in any order
+	Messages map[string][]slack.Message
+	Users    []slack.User
+	// Members are the user IDs of each channel's members by channel ID
+	Members map[string][]string
+}
+
+// PostedMessage is a message posted with chat.postMessage
+type PostedMessage struct {
+	Channel  string
+	Text     string
+	ThreadTS string
+}
+
+// Server is a fake Slack Web API serving Fixtures
+type Server struct {
+	// URL is the base URL of the API, ending in a slash
+	URL string
+
+	server *httptest.Server
+
+	mu        sync.Mutex
+	teamID    string
+	botUserID string
+	channels  []*slack.Channel
+	messages  map[string][]slack.Message
+	users     map[string]slack.User
+	members   map[string][]string
+	// rateLimits are how many more calls of each method are answered as rate limited, and for how long
+	rateLimits map[string]rateLimit
+	calls      map[string]int
+	joined     []string
+	archived   []string
+	posted     []PostedMessage
+	nextTS     int64
+}
+
+// rateLimit is the rate limiting configured for a method
+type rateLimit struct {
+	remaining  int
+	retryAfter time.Duration
+}
+
+// New will start a Server serving fixtures. Close it when done.
+func New(fixtures Fixtures) *Server {
+	s := &Server{
+		teamID:     fixtures.TeamID,
+		botUserID:  fixtures.BotUserID,
+		messages:   map[string][]slack.Message{},
+		users:      map[string]slack.User{},
+		members:    map[string][]string{},
+		rateLimits: map[string]rateLimit{},
+		calls:      map[string]int{},
+		nextTS:     time.Now().Unix(),
+	}
+	if s.teamID == "" {
+		s.teamID = "T0000000000"
+	}
+	if s.botUserID == "" {
+		s.botUserID = "U0000000000"
+	}
+
+	for i := range fixtures.Channels {
+		c := fixtures.Channels[i]
+		s.channels = append(s.channels, &c)
+	}
+
+	// History is returned newest first
+	for id, messages := range fixtures.Messages {
+		sorted := append([]slack.Message{}, messages...)
+		sort.Slice(sorted, func(i, j int) bool { return timestamp(sorted[i].Timestamp) > timestamp(sorted[j].Timestamp) })
+		s.messages[id] = sorted
+	}
+
+	for _, u := range fixtures.Users {
+		s.users[u.ID] = u
+	}
+
+	for id, members := range fixtures.Members {
+		s.members[id] = append([]string{}, members...)
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL + "/"
+
+	return s
+}
+
+// Close will shut the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// RateLimit will answer the next times calls of method with HTTP 429 and a Retry-After of retryAfter
+func (s *Server) RateLimit(method string, times int, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rateLimits[method] = rateLimit{remaining: times, retryAfter: retryAfter}
+}
+
+// Calls will return how many times method was called, including rate limited calls
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls[method]
+}
+
+// Joined will return the IDs of the channels joined, in order
+func (s *Server) Joined() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string{}, s.joined...)
+}
+
+// Archived will return the IDs of the channels archived, in order
+func (s *Server) Archived() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string{}, s.archived...)
+}
+
+// Posted will return the messages posted, in order. Posted messages are not added to channel history,
+// so they do not change how channels are evaluated.
+func (s *Server) Posted() []PostedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]PostedMessage{}, s.posted...)
+}
+
+// serveHTTP will answer a Web API call, the method being the last element of the path
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[method]++
+
+	if limit := s.rateLimits[method]; limit.remaining > 0 {
+		limit.remaining--
+		s.rateLimits[method] = limit
+		w.Header().Set("Retry-After", strconv.Itoa(int(limit.retryAfter.Seconds())))
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	var resp any
+	switch method {
+	case "auth.test":
+		resp = map[string]any{"ok": true, "url": s.URL, "team": "slackmock", "user": "auto-archiver",
+			"team_id": s.teamID, "user_id": s.botUserID}
+	case "conversations.list":
+		resp = s.conversationsList(r)
+	case "conversations.info":
+		resp = s.withChannel(r, func(c *slack.Channel) any {
+			return map[string]any{"ok": true, "channel": c}
+		})
+	case "conversations.history":
+		resp = s.conversationsHistory(r)
+	case "conversations.members":
+		resp = s.withChannel(r, func(c *slack.Channel) any {
+			members := s.members[c.ID]
+			page, next, err := paginate(r, len(members))
+			if err != nil {
+				return errorResponse("invalid_cursor")
+			}
+			return map[string]any{
+				"ok":                true,
+				"members":           members[page[0]:page[1]],
+				"response_metadata": map[string]string{"next_cursor": next},
+			}
+		})
+	case "conversations.join":
+		resp = s.withChannel(r, func(c *slack.Channel) any {
+			if c.IsArchived {
+				return errorResponse("is_archived")
+			}
+			if !c.IsMember {
+				c.IsMember = true
+				s.joined = append(s.joined, c.ID)
+			}
+			return map[string]any{"ok": true, "channel": c}
+		})
+	case "conversations.leave":
+		resp = s.withChannel(r, func(c *slack.Channel) any {
+			if !c.IsMember {
+				return map[string]any{"ok": true, "not_in_channel": true}
+			}
+			c.IsMember = false
+			return map[string]any{"ok": true}
+		})
+	case "conversations.archive":
+		resp = s.withChannel(r, func(c *slack.Channel) any {
+			if c.IsArchived {
+				return errorResponse("already_archived")
+			}
+			if !c.IsMember {
+				return errorResponse("not_in_channel")
+			}
+			c.IsArchived = true
+			s.archived = append(s.archived, c.ID)
+			return map[string]any{"ok": true}
+		})
+	case "conversations.open":
+		// Direct messages are opened as the user's ID, which chat.postMessage records them under
+		user, _, _ := strings.Cut(r.Form.Get("users"), ",")
+		if _, ok := s.users[user]; !ok {
+			resp = errorResponse("user_not_found")
+			break
+		}
+		resp = map[string]any{"ok": true, "channel": map[string]any{"id": user}}
+	case "chat.postMessage":
+		resp = s.chatPostMessage(r)
+	case "users.info":
+		u, ok := s.users[r.Form.Get("user")]
+		if !ok {
+			resp = errorResponse("user_not_found")
+			break
+		}
+		resp = map[string]any{"ok": true, "user": u}
+	default:
+		resp = errorResponse("unknown_method")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// conversationsList will page through the channels matching the types and exclude_archived arguments
+func (s *Server) conversationsList(r *http.Request) any {
+	types := r.Form.Get("types")
+	if types == "" {
+		types = "public_channel"
+	}
+	excludeArchived, _ := strconv.ParseBool(r.Form.Get("exclude_archived"))
+
+	// Like Slack's, the cursor is the next channel rather than an offset, so archiving channels while paging
+	// through them does not skip any
+	start := 0
+	if cursor := r.Form.Get("cursor"); cursor != "" {
+		start = -1
+		for i, c := range s.channels {
+			if c.ID == cursor {
+				start = i
+			}
+		}
+		if start < 0 {
+			return errorResponse("invalid_cursor")
+		}
+	}
+	limit, err := strconv.Atoi(r.Form.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultLimit
+	}
+
+	channels := []*slack.Channel{}
+	next := ""
+	for _, c := range s.channels[start:] {
+		if excludeArchived && c.IsArchived {
+			continue
+		}
+		switch {
+		case c.IsMpIM:
+			if !strings.Contains(types, "mpim") || !c.IsMember {
+				continue
+			}
+		case c.IsPrivate:
+			if !strings.Contains(types, "private_channel") || !c.IsMember {
+				continue
+			}
+		case !strings.Contains(types, "public_channel"):
+			continue
+		}
+		if len(channels) == limit {
+			next = c.ID
+			break
+		}
+		channels = append(channels, c)
+	}
+
+	return map[string]any{
+		"ok":                true,
+		"channels":          channels,
+		"response_metadata": map[string]string{"next_cursor": next},
+	}
+}
+
+// conversationsHistory will page through a channel's messages between oldest and latest, newest first
+func (s *Server) conversationsHistory(r *http.Request) any {
+	return s.withChannel(r, func(c *slack.Channel) any {
+		if !c.IsMember {
+			return errorResponse("not_in_channel")
+		}
+
+		oldest := timestamp(r.Form.Get("oldest"))
+		latest := timestamp(r.Form.Get("latest"))
+		inclusive := r.Form.Get("inclusive") == "1" || r.Form.Get("inclusive") == "true"
+
+		messages := []slack.Message{}
+		for _, m := range s.messages[c.ID] {
+			ts := timestamp(m.Timestamp)
+			if latest > 0 && (ts > latest || (ts == latest && !inclusive)) {
+				continue
+			}
+			if ts < oldest || (ts == oldest && !inclusive) {
+				continue
+			}
+			messages = append(messages, m)
+		}
+
+		page, next, err := paginate(r, len(messages))
+		if err != nil {
+			return errorResponse("invalid_cursor")
+		}
+
+		return map[string]any{
+			"ok":                true,
+			"messages":          messages[page[0]:page[1]],
+			"has_more":          next != "",
+			"response_metadata": map[string]string{"next_cursor": next},
+		}
+	})
+}
+
+// chatPostMessage will record a message posted to a channel or, by user ID, as a direct message
+func (s *Server) chatPostMessage(r *http.Request) any {
+	channel := r.Form.Get("channel")
+	if s.channel(channel) == nil {
+		if _, ok := s.users[channel]; !ok {
+			return errorResponse("channel_not_found")
+		}
+	}
+
+	s.nextTS++
+	ts := fmt.Sprintf("%d.000100", s.nextTS)
+	s.posted = append(s.posted, PostedMessage{Channel: channel, Text: r.Form.Get("text"), ThreadTS: r.Form.Get("thread_ts")})
+
+	return map[string]any{"ok": true, "channel": channel, "ts": ts}
+}
+
+// withChannel will call f with the channel of the request, or answer channel_not_found
+func (s *Server) withChannel(r *http.Request, f func(c *slack.Channel) any) any {
+	c := s.channel(r.Form.Get("channel"))
+	if c == nil {
+		return errorResponse("channel_not_found")
+	}
+
+	return f(c)
+}
+
+// channel will return the channel with id, or nil if there is none
+func (s *Server) channel(id string) *slack.Channel {
+	for _, c := range s.channels {
+		if c.ID == id {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// paginate will return the bounds of the page of n items requested by the cursor and limit arguments,
+// and the cursor of the next page, which is empty on the last page. Cursors are offsets.
+func paginate(r *http.Request, n int) ([2]int, string, error) {
+	start := 0
+	if cursor := r.Form.Get("cursor"); cursor != "" {
+		var err error
+		start, err = strconv.Atoi(cursor)
+		if err != nil || start < 0 || start > n {
+			return [2]int{}, "", fmt.Errorf("invalid cursor %q", cursor)
+		}
+	}
+
+	limit, err := strconv.Atoi(r.Form.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultLimit
+	}
+
+	end := start + limit
+	if end >= n {
+		return [2]int{start, n}, "", nil
+	}
+
+	return [2]int{start, end}, strconv.Itoa(end), nil
+}
+
+// errorResponse is the response of a failed call
+func errorResponse(err string) map[string]any {
+	return map[string]any{"ok": false, "error": err}
+}
+
+// timestamp will parse a Slack timestamp for ordering, treating invalid timestamps as 0
+func timestamp(ts string) float64 {
+	t, _ := strconv.ParseFloat(ts, 64)
+	return t
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errNotFound is returned by a Store when a key does not exist or has expired
+var errNotFound = errors.New("not found")
+
+// Store persists auto-archiver state between runs as JSON values grouped into buckets
+type Store interface {
+	// Get will return the value of key in bucket, or errNotFound
+	Get(ctx context.Context, bucket, key string) ([]byte, error)
+	// Put will set the value of key in bucket, expiring it after ttl unless ttl is 0
+	Put(ctx context.Context, bucket, key string, value []byte, ttl time.Duration) error
+	// Delete will remove key from bucket, deleting a missing key is not an error
+	Delete(ctx context.Context, bucket, key string) error
+	// List will return every unexpired value in bucket by key
+	List(ctx context.Context, bucket string) (map[string][]byte, error)
+	Close() error
+}
+
+// bucketExemptions holds an exemption per channel ID
+const bucketExemptions = "exemptions"
+
+// stateGracePeriod is how long state is kept after it stops mattering, so runs that are late, paused or evaluate
+// channels with a longer threshold still find it
+const stateGracePeriod = 30 * 24 * time.Hour
+
+// stateBuckets are all the buckets auto-archiver keeps state in
+var stateBuckets = []string{
+	bucketExemptions, bucketActivity, bucketMeta, bucketEscalations, bucketArchiveRetries, bucketApprovals,
+	bucketArchived, bucketScanSchedule, bucketWarnings, bucketExemptionService, bucketNudges,
+}
+
+// newStore will open the configured state store, or return nil if no store is configured. Read-only runs open it
+// without creating or migrating anything.
+func newStore(ctx context.Context, cfg *config) (Store, error) {
+	switch {
+	case cfg.stateFile != "":
+		return openFileStore(cfg.stateFile)
+	case cfg.stateBoltFile != "":
+		return openBoltStore(cfg.stateBoltFile, cfg.readOnly)
+	case cfg.statePostgresURL != "":
+		return openPostgresStore(ctx, cfg.statePostgresURL, cfg.readOnly)
+	case cfg.stateRedisURL != "":
+		return openRedisStore(ctx, cfg.stateRedisURL, cfg.stateRedisPrefix)
+	case cfg.stateDynamoDBTable != "":
+		return openDynamoDBStore(ctx, cfg.stateDynamoDBTable)
+	case cfg.stateObjectURL != "":
+		return openObjectStore(ctx, cfg.stateObjectURL)
+	default:
+		return nil, nil
+	}
+}
+
+// exemption keeps a channel from being warned or archived
+type exemption struct {
+	ChannelID   string `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	// Until is when the exemption ends, the zero time means it is permanent
+	Until      time.Time `json:"until,omitempty"`
+	Reason     string    `json:"reason"`
+	ExemptedBy string    `json:"exempted_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	// RemindedAt is when the user who made the exemption was reminded that it is ending, the zero time if they
+	// have not been
+	RemindedAt time.Time `json:"reminded_at,omitempty"`
+}
+
+// activeAt will report whether the exemption applies at t
+func (e exemption) activeAt(t time.Time) bool {
+	return e.Until.IsZero() || t.Before(e.Until)
+}
+
+// getExemption will return the exemption of a channel, or nil if it has none
+func getExemption(ctx context.Context, store Store, channelID string) (*exemption, error) {
+	var e exemption
+	if err := getJSON(ctx, store, bucketExemptions, channelID, &e); err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// putExemption will save an exemption, replacing any existing exemption of the channel. Timed exemptions expire a
+// grace period after they end, so ended exemptions can still be reported for a while, permanent ones never do.
+func putExemption(ctx context.Context, store Store, e exemption) error {
+	ttl := time.Duration(0)
+	if !e.Until.IsZero() {
+		ttl = max(time.Until(e.Until), 0) + stateGracePeriod
+	}
+
+	return putJSON(ctx, store, bucketExemptions, e.ChannelID, e, ttl)
+}
+
+// listExemptions will return every stored exemption
+func listExemptions(ctx context.Context, store Store) ([]exemption, error) {
+	values, err := store.List(ctx, bucketExemptions)
+	if err != nil {
+		return nil, err
+	}
+
+	exemptions := []exemption{}
+	for key, value := range values {
+		var e exemption
+		if err := json.Unmarshal(value, &e); err != nil {
+			return nil, fmt.Errorf("can not decode %s/%s: %w", bucketExemptions, key, err)
+		}
+		exemptions = append(exemptions, e)
+	}
+
+	return exemptions, nil
+}
+
+// getJSON will decode the value of key in bucket into v
+func getJSON(ctx context.Context, store Store, bucket, key string, v any) error {
+	value, err := store.Get(ctx, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(value, v); err != nil {
+		return fmt.Errorf("can not decode %s/%s: %w", bucket, key, err)
+	}
+
+	return nil
+}
+
+// putJSON will encode v as the value of key in bucket
+func putJSON(ctx context.Context, store Store, bucket, key string, v any, ttl time.Duration) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return store.Put(ctx, bucket, key, value, ttl)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltStore keeps state in an embedded bbolt database file. Unlike fileStore it only writes the keys that change,
+// but it is still only safe for a single auto-archiver process at a time as the file is locked while open.
+type boltStore struct {
+	db *bolt.DB
+}
+
+// openBoltStore will open a bolt state file, creating it unless the store is read-only. Read-only stores share the
+// file with other readers and fail if it does not exist.
+func openBoltStore(path string, readOnly bool) (*boltStore, error) {
+	// Waiting for the lock briefly means an overlapping run fails instead of hanging
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 10 * time.Second, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("can not open bolt state file %s: %w", path, err)
+	}
+
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) Get(_ context.Context, bucket, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return errNotFound
+		}
+
+		e, err := decodeBoltEntry(b.Get([]byte(key)))
+		if err != nil {
+			return fmt.Errorf("can not decode %s/%s: %w", bucket, key, err)
+		}
+		if e == nil || e.expired(time.Now()) {
+			return errNotFound
+		}
+
+		value = e.Value
+		return nil
+	})
+
+	return value, err
+}
+
+func (s *boltStore) Put(_ context.Context, bucket, key string, value []byte, ttl time.Duration) error {
+	e := fileEntry{Value: value}
+	if ttl > 0 {
+		e.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(key), data)
+	})
+}
+
+func (s *boltStore) Delete(_ context.Context, bucket, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+
+		return b.Delete([]byte(key))
+	})
+}
+
+// List will also delete the expired values of the bucket, as bbolt has no expiry of its own
+func (s *boltStore) List(_ context.Context, bucket string) (map[string][]byte, error) {
+	values := map[string][]byte{}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+
+		now := time.Now()
+		expired := [][]byte{}
+		err := b.ForEach(func(k, v []byte) error {
+			e, err := decodeBoltEntry(v)
+			if err != nil {
+				return fmt.Errorf("can not decode %s/%s: %w", bucket, k, err)
+			}
+			if e.expired(now) {
+				expired = append(expired, k)
+				return nil
+			}
+
+			values[string(k)] = e.Value
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return values, err
+}
+
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+// decodeBoltEntry will decode an entry stored by boltStore, or return nil if data is nil
+func decodeBoltEntry(data []byte) (*fileEntry, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	var e fileEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// stateExportVersion is the version of the state export format
+const stateExportVersion = 1
+
+// stateExport is every value of every bucket of a state store, in a format any store can import
+type stateExport struct {
+	Version int                                   `json:"version"`
+	Buckets map[string]map[string]json.RawMessage `json:"buckets"`
+}
+
+// runStateCommand will run "auto-archiver state export|import" against the configured state store and return the
+// exit code. Exporting from one store and importing into another migrates state between backends.
+func runStateCommand(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	usage := func() {
+		fmt.Fprintln(stderr, "usage: auto-archiver state export [--file <path>]")
+		fmt.Fprintln(stderr, "       auto-archiver state import [--file <path>]")
+	}
+
+	if len(args) == 0 || (args[0] != "export" && args[0] != "import") {
+		usage()
+		return exitUsage
+	}
+
+	flags := flag.NewFlagSet("state "+args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	file := flags.String("file", "", "file to write the export to or read the import from instead of stdout or stdin")
+	configFile, profile := addProfileFlags(flags)
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+
+	cfg, err := loadProfileConfig(*configFile, *profile)
+	if err != nil {
+		fmt.Fprintf(stderr, "can not load configuration: %v\n", err)
+		return exitConfig
+	}
+
+	store, err := newStore(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "can not open state store: %v\n", err)
+		return exitConfig
+	}
+	if store == nil {
+		fmt.Fprintln(stderr, "no state store is configured")
+		return exitConfig
+	}
+	defer store.Close()
+
+	switch args[0] {
+	case "export":
+		w := stdout
+		if *file != "" {
+			f, err := os.Create(*file)
+			if err != nil {
+				fmt.Fprintf(stderr, "can not create export: %v\n", err)
+				return exitRunFailed
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if err := exportState(ctx, store, w); err != nil {
+			fmt.Fprintf(stderr, "can not export state: %v\n", err)
+			return exitRunFailed
+		}
+	case "import":
+		r := stdin
+		if *file != "" {
+			f, err := os.Open(*file)
+			if err != nil {
+				fmt.Fprintf(stderr, "can not open import: %v\n", err)
+				return exitRunFailed
+			}
+			defer f.Close()
+			r = f
+		}
+
+		n, err := importState(ctx, store, r)
+		if err != nil {
+			fmt.Fprintf(stderr, "can not import state: %v\n", err)
+			return exitRunFailed
+		}
+		fmt.Fprintf(stderr, "imported %d values\n", n)
+	}
+
+	return exitOK
+}
+
+// exportState will write every value in the store as a stateExport. Expiry times are not exported,
+// so values that expire are imported without expiring.
+func exportState(ctx context.Context, store Store, w io.Writer) error {
+	export := stateExport{
+		Version: stateExportVersion,
+		Buckets: map[string]map[string]json.RawMessage{},
+	}
+
+	for _, bucket := range stateBuckets {
+		values, err := store.List(ctx, bucket)
+		if err != nil {
+			return fmt.Errorf("can not list %s: %w", bucket, err)
+		}
+
+		export.Buckets[bucket] = map[string]json.RawMessage{}
+		for key, value := range values {
+			export.Buckets[bucket][key] = value
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(export)
+}
+
+// importState will put every value of a stateExport into the store, replacing existing values with the same key,
+// and return how many values were imported
+func importState(ctx context.Context, store Store, r io.Reader) (int, error) {
+	var export stateExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return 0, fmt.Errorf("can not decode export: %w", err)
+	}
+	if export.Version != stateExportVersion {
+		return 0, fmt.Errorf("unsupported export version %d", export.Version)
+	}
+
+	n := 0
+	for bucket, values := range export.Buckets {
+		for key, value := range values {
+			if err := store.Put(ctx, bucket, key, value, 0); err != nil {
+				return n, fmt.Errorf("can not put %s/%s: %w", bucket, key, err)
+			}
+			n++
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// dynamoDBStore keeps all state in a single DynamoDB table:
+//
+//	bucket      partition key (S)  the Store bucket, such as "exemptions"
+//	key         sort key (S)       the key within the bucket, such as a channel ID
+//	value       S                  the JSON value
+//	expires_at  N                  Unix seconds the value expires at, set as the table's TTL attribute
+//
+// DynamoDB deletes expired items lazily, so expired items are also filtered out when read.
+type dynamoDBStore struct {
+	client *dynamodb.Client
+	table  string
+}
+
+// openDynamoDBStore will connect to DynamoDB with the default AWS credential chain, such as a Lambda's role
+func openDynamoDBStore(ctx context.Context, table string) (*dynamoDBStore, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not load AWS configuration: %w", err)
+	}
+
+	s := &dynamoDBStore{client: dynamodb.NewFromConfig(awsCfg), table: table}
+
+	// Describing the table catches a wrong table name or missing permissions at start up
+	if _, err := s.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)}); err != nil {
+		return nil, fmt.Errorf("can not describe DynamoDB table %s: %w", table, err)
+	}
+
+	return s, nil
+}
+
+func (s *dynamoDBStore) Get(ctx context.Context, bucket, key string) ([]byte, error) {
+	out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            dynamoDBKey(bucket, key),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := dynamoDBValue(out.Item, time.Now())
+	if !ok {
+		return nil, errNotFound
+	}
+
+	return value, nil
+}
+
+func (s *dynamoDBStore) Put(ctx context.Context, bucket, key string, value []byte, ttl time.Duration) error {
+	item := dynamoDBKey(bucket, key)
+	item["value"] = &types.AttributeValueMemberS{Value: string(value)}
+	if ttl > 0 {
+		item["expires_at"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)}
+	}
+
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+	})
+	return err
+}
+
+func (s *dynamoDBStore) Delete(ctx context.Context, bucket, key string) error {
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key:       dynamoDBKey(bucket, key),
+	})
+	return err
+}
+
+func (s *dynamoDBStore) List(ctx context.Context, bucket string) (map[string][]byte, error) {
+	paginator := dynamodb.NewQueryPaginator(s.client, &dynamodb.QueryInput{
+		TableName:                 aws.String(s.table),
+		KeyConditionExpression:    aws.String("#bucket = :bucket"),
+		ExpressionAttributeNames:  map[string]string{"#bucket": "bucket"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":bucket": &types.AttributeValueMemberS{Value: bucket}},
+		ConsistentRead:            aws.Bool(true),
+	})
+
+	now := time.Now()
+	values := map[string][]byte{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			key, ok := item["key"].(*types.AttributeValueMemberS)
+			if !ok {
+				continue
+			}
+			if value, ok := dynamoDBValue(item, now); ok {
+				values[key.Value] = value
+			}
+		}
+	}
+
+	return values, nil
+}
+
+func (s *dynamoDBStore) Close() error {
+	return nil
+}
+
+// dynamoDBKey will return the primary key of key in bucket
+func dynamoDBKey(bucket, key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"bucket": &types.AttributeValueMemberS{Value: bucket},
+		"key":    &types.AttributeValueMemberS{Value: key},
+	}
+}
+
+// dynamoDBValue will return the value of an item, reporting false if there is no item or it expired at now
+func dynamoDBValue(item map[string]types.AttributeValue, now time.Time) ([]byte, bool) {
+	value, ok := item["value"].(*types.AttributeValueMemberS)
+	if !ok {
+		return nil, false
+	}
+
+	if expiresAt, ok := item["expires_at"].(*types.AttributeValueMemberN); ok {
+		unix, err := strconv.ParseInt(expiresAt.Value, 10, 64)
+		if err == nil && !now.Before(time.Unix(unix, 0)) {
+			return nil, false
+		}
+	}
+
+	return []byte(value.Value), true
+}
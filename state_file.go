@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileEntry is a single value in a fileStore
+type fileEntry struct {
+	Value     json.RawMessage `json:"value"`
+	ExpiresAt time.Time       `json:"expires_at,omitempty"`
+}
+
+// expired will report whether the entry has expired at t
+func (e fileEntry) expired(t time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !t.Before(e.ExpiresAt)
+}
+
+// fileStore keeps all state in a single JSON file, which is rewritten on every change.
+// It needs no infrastructure, but is only safe for a single auto-archiver process at a time.
+type fileStore struct {
+	path string
+
+	mu      sync.Mutex
+	buckets map[string]map[string]fileEntry
+}
+
+func openFileStore(path string) (*fileStore, error) {
+	s := &fileStore{
+		path:    path,
+		buckets: map[string]map[string]fileEntry{},
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &s.buckets); err != nil {
+		return nil, fmt.Errorf("can not decode state file %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+func (s *fileStore) Get(_ context.Context, bucket, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.buckets[bucket][key]
+	if !ok || e.expired(time.Now()) {
+		return nil, errNotFound
+	}
+
+	return e.Value, nil
+}
+
+func (s *fileStore) Put(_ context.Context, bucket, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := fileEntry{Value: value}
+	if ttl > 0 {
+		e.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = map[string]fileEntry{}
+	}
+	s.buckets[bucket][key] = e
+
+	return s.save()
+}
+
+func (s *fileStore) Delete(_ context.Context, bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.buckets[bucket][key]; !ok {
+		return nil
+	}
+	delete(s.buckets[bucket], key)
+
+	return s.save()
+}
+
+func (s *fileStore) List(_ context.Context, bucket string) (map[string][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	values := map[string][]byte{}
+	for key, e := range s.buckets[bucket] {
+		if !e.expired(now) {
+			values[key] = e.Value
+		}
+	}
+
+	return values, nil
+}
+
+func (s *fileStore) Close() error {
+	return nil
+}
+
+// save will write the state file, replacing it atomically so a crash never leaves it half written.
+// Expired entries are dropped. Must be called with mu held.
+func (s *fileStore) save() error {
+	now := time.Now()
+	for _, entries := range s.buckets {
+		for key, e := range entries {
+			if e.expired(now) {
+				delete(entries, key)
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(s.buckets, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
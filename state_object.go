@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// objectStoreRefresh is how long state read from the object is trusted before it is read again,
+	// so changes made by other replicas are seen without reading the object for every value
+	objectStoreRefresh = time.Minute
+	// objectStoreAttempts is how many times a change is retried when another replica changed the object first
+	objectStoreAttempts = 5
+)
+
+// errStateConflict is returned by a stateObject when the object changed since it was read
+var errStateConflict = errors.New("state object was changed by someone else")
+
+// stateObject is a versioned object in a bucket that holds the whole state
+type stateObject interface {
+	// read will return the object's data and version, or nil data and an empty version if it does not exist
+	read(ctx context.Context) ([]byte, string, error)
+	// write will replace the object if its version is still version, an empty version meaning it must not exist,
+	// and return the new version. It returns errStateConflict if the object changed.
+	write(ctx context.Context, data []byte, version string) (string, error)
+	location() string
+}
+
+// objectStore keeps all state in a single JSON object in S3 or GCS, like fileStore does in a file. Changes are
+// written with optimistic locking, so several replicas can share the state without overwriting each other.
+type objectStore struct {
+	object stateObject
+
+	mu       sync.Mutex
+	buckets  map[string]map[string]fileEntry
+	version  string
+	loadedAt time.Time
+}
+
+// openObjectStore will open the state object at a gs://bucket/name or s3://bucket/key URL
+func openObjectStore(ctx context.Context, rawURL string) (*objectStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse state object URL: %w", err)
+	}
+	name := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || name == "" {
+		return nil, fmt.Errorf("state object URL must be gs://bucket/name or s3://bucket/key, got %s", rawURL)
+	}
+
+	var object stateObject
+	switch u.Scheme {
+	case "gs":
+		object, err = newGCSStateObject(ctx, u.Host, name)
+	case "s3":
+		object, err = newS3StateObject(ctx, u.Host, name)
+	default:
+		return nil, fmt.Errorf("state object URL must be gs://bucket/name or s3://bucket/key, got %s", rawURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &objectStore{object: object}
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// load will read the state object. Must be called with mu held, or before the store is shared.
+func (s *objectStore) load(ctx context.Context) error {
+	data, version, err := s.object.read(ctx)
+	if err != nil {
+		return fmt.Errorf("can not read %s: %w", s.object.location(), err)
+	}
+
+	buckets := map[string]map[string]fileEntry{}
+	if data != nil {
+		if err := json.Unmarshal(data, &buckets); err != nil {
+			return fmt.Errorf("can not decode %s: %w", s.object.location(), err)
+		}
+	}
+
+	s.buckets = buckets
+	s.version = version
+	s.loadedAt = time.Now()
+	return nil
+}
+
+// refresh will read the state object again if it was read too long ago. Must be called with mu held.
+func (s *objectStore) refresh(ctx context.Context) error {
+	if time.Since(s.loadedAt) < objectStoreRefresh {
+		return nil
+	}
+
+	return s.load(ctx)
+}
+
+// update will apply change to the state and write it, reading the object and applying change again
+// when another replica changed it first. The change is applied to a copy of the state, which only replaces the
+// state once it was written, so a change that could not be written is never seen.
+func (s *objectStore) update(ctx context.Context, change func(buckets map[string]map[string]fileEntry)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for attempt := 0; attempt < objectStoreAttempts; attempt++ {
+		buckets := copyBuckets(s.buckets)
+		change(buckets)
+
+		now := time.Now()
+		for _, entries := range buckets {
+			for key, e := range entries {
+				if e.expired(now) {
+					delete(entries, key)
+				}
+			}
+		}
+
+		data, err := json.MarshalIndent(buckets, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		version, err := s.object.write(ctx, data, s.version)
+		if errors.Is(err, errStateConflict) {
+			if err := s.load(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("can not write %s: %w", s.object.location(), err)
+		}
+
+		s.buckets = buckets
+		s.version = version
+		return nil
+	}
+
+	return fmt.Errorf("can not write %s: %w", s.object.location(), errStateConflict)
+}
+
+// copyBuckets will return a copy of the state whose buckets can be changed without changing the original
+func copyBuckets(buckets map[string]map[string]fileEntry) map[string]map[string]fileEntry {
+	copied := make(map[string]map[string]fileEntry, len(buckets))
+	for bucket, entries := range buckets {
+		copied[bucket] = maps.Clone(entries)
+	}
+
+	return copied
+}
+
+func (s *objectStore) Get(ctx context.Context, bucket, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	e, ok := s.buckets[bucket][key]
+	if !ok || e.expired(time.Now()) {
+		return nil, errNotFound
+	}
+
+	return e.Value, nil
+}
+
+func (s *objectStore) Put(ctx context.Context, bucket, key string, value []byte, ttl time.Duration) error {
+	e := fileEntry{Value: value}
+	if ttl > 0 {
+		e.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	return s.update(ctx, func(buckets map[string]map[string]fileEntry) {
+		if buckets[bucket] == nil {
+			buckets[bucket] = map[string]fileEntry{}
+		}
+		buckets[bucket][key] = e
+	})
+}
+
+func (s *objectStore) Delete(ctx context.Context, bucket, key string) error {
+	return s.update(ctx, func(buckets map[string]map[string]fileEntry) {
+		delete(buckets[bucket], key)
+	})
+}
+
+func (s *objectStore) List(ctx context.Context, bucket string) (map[string][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	values := map[string][]byte{}
+	for key, e := range s.buckets[bucket] {
+		if !e.expired(now) {
+			values[key] = e.Value
+		}
+	}
+
+	return values, nil
+}
+
+func (s *objectStore) Close() error {
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	gcsObjectURL      = "https://storage.googleapis.com/storage/v1/b/%s/o/%s?alt=media"
+	gcsMediaUploadURL = "https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=media&name=%s&ifGenerationMatch=%s"
+)
+
+// gcsStateObject is a state object in Google Cloud Storage, versioned by its generation
+type gcsStateObject struct {
+	client *http.Client
+	bucket string
+	name   string
+}
+
+func newGCSStateObject(ctx context.Context, bucket, name string) (*gcsStateObject, error) {
+	client, err := google.DefaultClient(ctx, gcsScope)
+	if err != nil {
+		return nil, fmt.Errorf("can not find application default credentials: %w", err)
+	}
+
+	return &gcsStateObject{client: client, bucket: bucket, name: name}, nil
+}
+
+func (o *gcsStateObject) read(ctx context.Context) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf(gcsObjectURL, url.PathEscape(o.bucket), url.PathEscape(o.name)), nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, "", fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, resp.Header.Get("X-Goog-Generation"), nil
+}
+
+func (o *gcsStateObject) write(ctx context.Context, data []byte, version string) (string, error) {
+	// Generation 0 only matches when the object does not exist yet
+	if version == "" {
+		version = "0"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf(gcsMediaUploadURL, url.PathEscape(o.bucket), url.QueryEscape(o.name), version), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return "", errStateConflict
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+
+	var object struct {
+		Generation string `json:"generation"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&object); err != nil {
+		return "", err
+	}
+
+	return object.Generation, nil
+}
+
+func (o *gcsStateObject) location() string {
+	return fmt.Sprintf("gs://%s/%s", o.bucket, o.name)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// s3StateObject is a state object in S3, versioned by its ETag
+type s3StateObject struct {
+	client *s3.Client
+	bucket string
+	key    string
+}
+
+func newS3StateObject(ctx context.Context, bucket, key string) (*s3StateObject, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not load AWS configuration: %w", err)
+	}
+
+	return &s3StateObject{client: s3.NewFromConfig(awsCfg), bucket: bucket, key: key}, nil
+}
+
+func (o *s3StateObject) read(ctx context.Context) ([]byte, string, error) {
+	out, err := o.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(o.bucket),
+		Key:    aws.String(o.key),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, aws.ToString(out.ETag), nil
+}
+
+func (o *s3StateObject) write(ctx context.Context, data []byte, version string) (string, error) {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(o.bucket),
+		Key:         aws.String(o.key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}
+	if version == "" {
+		input.IfNoneMatch = aws.String("*")
+	} else {
+		input.IfMatch = aws.String(version)
+	}
+
+	out, err := o.client.PutObject(ctx, input)
+	if err != nil {
+		// A conditional write racing another one can also fail with 409 Conflict
+		var respErr *smithyhttp.ResponseError
+		if errors.As(err, &respErr) &&
+			(respErr.HTTPStatusCode() == http.StatusPreconditionFailed || respErr.HTTPStatusCode() == http.StatusConflict) {
+			return "", errStateConflict
+		}
+		return "", err
+	}
+
+	return aws.ToString(out.ETag), nil
+}
+
+func (o *s3StateObject) location() string {
+	return fmt.Sprintf("s3://%s/%s", o.bucket, o.key)
+}
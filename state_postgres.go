@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	// Registers the pgx database/sql driver
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// postgresMigrations are applied in file name order, each exactly once
+//
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+// postgresMigrationLock is the advisory lock that keeps concurrent replicas from migrating at the same time
+const postgresMigrationLock = 0x617263686976 // "archiv"
+
+// postgresStore keeps state in PostgreSQL, so replicas can share it and it can be queried with SQL
+type postgresStore struct {
+	db *sql.DB
+}
+
+// openPostgresStore will connect to PostgreSQL and migrate the schema. Read-only stores only check that the schema
+// was migrated, so read-only runs never change the database.
+func openPostgresStore(ctx context.Context, url string, readOnly bool) (*postgresStore, error) {
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can not connect to postgres: %w", err)
+	}
+
+	s := &postgresStore{db: db}
+	if readOnly {
+		if err := s.checkMigrated(ctx); err != nil {
+			db.Close()
+			return nil, err
+		}
+		return s, nil
+	}
+	if err := s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can not migrate postgres: %w", err)
+	}
+
+	return s, nil
+}
+
+// migrate will apply the migrations that have not been applied yet
+func (s *postgresStore) migrate(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", postgresMigrationLock); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS auto_archiver_migrations (
+		version    text        PRIMARY KEY,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`); err != nil {
+		return err
+	}
+
+	names, err := postgresMigrationNames()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		version := strings.TrimSuffix(path.Base(name), ".sql")
+
+		var applied bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM auto_archiver_migrations WHERE version = $1)",
+			version).Scan(&applied); err != nil {
+			return err
+		}
+		if applied {
+			continue
+		}
+
+		migration, err := postgresMigrations.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, string(migration)); err != nil {
+			return fmt.Errorf("can not apply %s: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO auto_archiver_migrations (version) VALUES ($1)", version); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// checkMigrated will return an error unless every migration was applied
+func (s *postgresStore) checkMigrated(ctx context.Context) error {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, "SELECT to_regclass('auto_archiver_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("postgres schema is missing, run auto-archiver once without --read-only to create it")
+	}
+
+	names, err := postgresMigrationNames()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		version := strings.TrimSuffix(path.Base(name), ".sql")
+
+		var applied bool
+		if err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM auto_archiver_migrations WHERE version = $1)",
+			version).Scan(&applied); err != nil {
+			return err
+		}
+		if !applied {
+			return fmt.Errorf("postgres schema is missing migration %s, run auto-archiver once without --read-only to apply it", version)
+		}
+	}
+
+	return nil
+}
+
+// postgresMigrationNames will return the file names of the migrations in the order they are applied in
+func postgresMigrationNames() ([]string, error) {
+	names, err := fs.Glob(postgresMigrations, "migrations/postgres/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (s *postgresStore) Get(ctx context.Context, bucket, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM auto_archiver_state
+		WHERE bucket = $1 AND key = $2 AND (expires_at IS NULL OR expires_at > now())`, bucket, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errNotFound
+	}
+
+	return value, err
+}
+
+func (s *postgresStore) Put(ctx context.Context, bucket, key string, value []byte, ttl time.Duration) error {
+	var expiresAt *time.Time
+	if ttl > 0 {
+		t := time.Now().Add(ttl)
+		expiresAt = &t
+	}
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO auto_archiver_state (bucket, key, value, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (bucket, key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at, updated_at = now()`,
+		bucket, key, string(value), expiresAt)
+	return err
+}
+
+func (s *postgresStore) Delete(ctx context.Context, bucket, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM auto_archiver_state WHERE bucket = $1 AND key = $2", bucket, key)
+	return err
+}
+
+// List will also delete the expired values of the bucket
+func (s *postgresStore) List(ctx context.Context, bucket string) (map[string][]byte, error) {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM auto_archiver_state WHERE bucket = $1 AND expires_at <= now()", bucket); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT key, value FROM auto_archiver_state WHERE bucket = $1", bucket)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := map[string][]byte{}
+	for rows.Next() {
+		var key string
+		var value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+
+	return values, rows.Err()
+}
+
+func (s *postgresStore) Close() error {
+	return s.db.Close()
+}
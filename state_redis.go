@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisStore keeps each value in its own Redis key named <prefix>:<bucket>:<key>, so values expire
+// through Redis key TTLs and replicas can share state
+type redisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func openRedisStore(ctx context.Context, url, prefix string) (*redisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("can not connect to redis: %w", err)
+	}
+
+	return &redisStore{client: client, prefix: prefix}, nil
+}
+
+// key will return the Redis key of key in bucket
+func (s *redisStore) key(bucket, key string) string {
+	return s.prefix + ":" + bucket + ":" + key
+}
+
+func (s *redisStore) Get(ctx context.Context, bucket, key string) ([]byte, error) {
+	value, err := s.client.Get(ctx, s.key(bucket, key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, errNotFound
+	}
+
+	return value, err
+}
+
+func (s *redisStore) Put(ctx context.Context, bucket, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.key(bucket, key), value, ttl).Err()
+}
+
+func (s *redisStore) Delete(ctx context.Context, bucket, key string) error {
+	return s.client.Del(ctx, s.key(bucket, key)).Err()
+}
+
+func (s *redisStore) List(ctx context.Context, bucket string) (map[string][]byte, error) {
+	bucketPrefix := s.key(bucket, "")
+
+	keys := []string{}
+	iter := s.client.Scan(ctx, 0, bucketPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	values := map[string][]byte{}
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	results, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, result := range results {
+		// Keys that expired between the scan and the get are nil
+		value, ok := result.(string)
+		if !ok {
+			continue
+		}
+		values[strings.TrimPrefix(keys[i], bucketPrefix)] = []byte(value)
+	}
+
+	return values, nil
+}
+
+func (s *redisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// statusCommand is the slash command that reports the standing of the channel it is used in
+const statusCommand = "/archiver-status"
+
+// handleStatusCommand will reply ephemerally with the standing of the channel the command was used in. Evaluating
+// the channel reads its history and can take longer than the three seconds Slack waits for the acknowledgement,
+// so the command is acknowledged first and answered through its response URL, without holding up other events.
+func (d *daemon) handleStatusCommand(ctx context.Context, e *socketEvent) {
+	e.ack(map[string]any{
+		"response_type": slack.ResponseTypeEphemeral,
+		"text":          "Checking this channel…",
+	})
+
+	command := e.command
+	go func() {
+		text, err := d.channelStatus(ctx, command.ChannelID, command.TeamID)
+		if err != nil {
+			d.logger.Error(err, "failed to get channel status", "channel", command.ChannelID, "user", command.UserID)
+			text = "Something went wrong getting the status of this channel, please try again."
+		}
+
+		reply := &slack.WebhookMessage{ResponseType: slack.ResponseTypeEphemeral, Text: text, ReplaceOriginal: true}
+		if err := slack.PostWebhookContext(ctx, command.ResponseURL, reply); err != nil {
+			d.logger.Error(err, "failed to answer status command", "channel", command.ChannelID, "user", command.UserID)
+		}
+	}()
+}
+
+// channelStatus will describe whether a channel of a workspace is at risk of being archived, evaluating it the same
+// way a run would
+func (d *daemon) channelStatus(ctx context.Context, channelID, teamID string) (string, error) {
+	c, err := d.api.GetConversationInfoContext(ctx, &slack.GetConversationInfoInput{ChannelID: channelID})
+	if err != nil {
+		return "", fmt.Errorf("could not get channel: %w", err)
+	}
+	if !c.IsMember {
+		return "auto-archiver is not a member of this channel, so it can not check its activity.", nil
+	}
+
+	// The threshold may have been changed from Slack since the daemon started
+	cfg, _, err := applyStoredSettings(ctx, d.cfg, d.store)
+	if err != nil {
+		return "", err
+	}
+	if cfg, err = d.applyPolicy(ctx, cfg, teamID); err != nil {
+		return "", err
+	}
+
+	a := NewArchiveSlacker(d.logger, d.api, cfg, nil, d.store, newRunResult(time.Now()))
+	now := time.Now()
+	e, err := a.evaluateChannel(ctx, *c, now)
+	if err == nil {
+		e, err = a.checkCandidateExemptions(ctx, *c, e, now)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{}
+	switch e.decision {
+	case decisionExempt:
+		lines = append(lines, "This channel is exempt from auto-archiving "+describeExemption(*e.exemption)+".")
+		return strings.Join(lines, "\n"), nil
+	case decisionArchive:
+		if e.lastActivity.IsZero() {
+			lines = append(lines, fmt.Sprintf("There has been no qualifying activity in the last %d days.", e.threshold))
+		} else {
+			lines = append(lines, fmt.Sprintf("The last qualifying activity was %d days ago.", e.daysInactive))
+		}
+		lines = append(lines, fmt.Sprintf("This channel will be archived on the next run: %s.", e.reason.Description()))
+	default:
+		lines = append(lines,
+			fmt.Sprintf("The last qualifying activity was %d days ago.", e.daysInactive),
+			fmt.Sprintf("This channel is archived after %d days without activity.", e.threshold),
+			fmt.Sprintf("Without new activity this channel will be archived on %s.", e.archiveDate.Format(archiveDateLayout)),
+		)
+		if e.decision == decisionWarn {
+			lines = append(lines, "This channel is in the warning period.")
+		}
+	}
+
+	// An expired exemption is worth mentioning, as it explains why a channel is no longer kept
+	if d.store != nil {
+		exempt, err := getExemption(ctx, d.store, c.ID)
+		if err != nil {
+			return "", fmt.Errorf("could not get channel exemption: %w", err)
+		}
+		if exempt != nil && !exempt.activeAt(now) {
+			lines = append(lines, fmt.Sprintf("Its exemption ended on %s.", exempt.Until.Format(archiveDateLayout)))
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// applyPolicy will return a copy of cfg with the policy of a workspace applied as a run would apply it, or cfg
+// without a policy repository
+func (d *daemon) applyPolicy(ctx context.Context, cfg *config, teamID string) (*config, error) {
+	if cfg.policyRepo == "" {
+		return cfg, nil
+	}
+
+	policy, err := d.policy.get(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("can not load policy: %w", err)
+	}
+
+	// Org-wide installs apply the policy of the workspace the channel is in
+	if len(cfg.workspaces) > 0 {
+		applied := *cfg
+		applied.teamID = teamID
+		return policy.forWorkspace(teamID).apply(&applied)
+	}
+
+	return policy.apply(cfg)
+}
+
+// describeExemption will describe how long an exemption lasts and why it was granted
+func describeExemption(e exemption) string {
+	description := "permanently"
+	if !e.Until.IsZero() {
+		description = "until " + e.Until.Format(archiveDateLayout)
+	}
+	if e.Reason != "" {
+		description += fmt.Sprintf(" (%s)", e.Reason)
+	}
+
+	return description
+}
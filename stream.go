@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+
+	"github.com/slack-go/slack"
+)
+
+// channelBatches are the channels of a run, handed out a batch at a time. With a page size each batch is a page of
+// channels, so a run only holds one page of channels at a time, otherwise the only batch is every channel.
+type channelBatches struct {
+	slackers []*ArchiveSlacker
+	pageSize int
+	cursor   string
+	done     bool
+}
+
+func newChannelBatches(slackers []*ArchiveSlacker, pageSize int) *channelBatches {
+	return &channelBatches{slackers: slackers, pageSize: pageSize}
+}
+
+// next will return the channels each shard handles in the next batch, or nil once every batch was returned
+func (b *channelBatches) next(ctx context.Context) ([][]slack.Channel, error) {
+	if b.done {
+		return nil, nil
+	}
+
+	if b.pageSize == 0 {
+		b.done = true
+		return getShardChannels(ctx, b.slackers)
+	}
+
+	// Paging is only supported with a single shard, so the page is all its channels
+	a := b.slackers[0]
+	a.logger.V(1).Info("getting page of channels", "cursor", b.cursor)
+	channels, cursor, err := a.client.GetConversationsContext(ctx, &slack.GetConversationsParameters{
+		ExcludeArchived: true,
+		Types:           channelTypes(a.privateChannels),
+		Limit:           b.pageSize,
+		Cursor:          b.cursor,
+		TeamID:          a.teamID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.cursor = cursor
+	b.done = cursor == ""
+
+	return [][]slack.Channel{channels}, nil
+}
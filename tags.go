@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	tagCommand = "/archiver-tag"
+	// tagsEventType is the message metadata event type of the pinned message holding a channel's tags
+	tagsEventType = "auto_archiver_tags"
+	// tagsExemptedBy is who exemptions from exempt-until tags are recorded as exempted by
+	tagsExemptedBy = "channel-tags"
+)
+
+// topicTagsPattern matches the "archiver: key=value ..." tags in a channel topic
+var topicTagsPattern = regexp.MustCompile(`(?i)\barchiver:\s*((?:[a-z-]+=\S*\s*)+)`)
+
+// tagOwnerPattern matches an owner tag: a user or user group as Slack sends mentions of them, or their ID
+var tagOwnerPattern = regexp.MustCompile(`^<(?:@|!subteam\^)([A-Z0-9]+)(?:\|[^>]*)?>$|^([UWS][A-Z0-9]+)$`)
+
+// channelTags are the machine-readable settings of a channel, kept as the metadata of a message pinned in it or, where
+// that is not available, in its topic
+type channelTags struct {
+	// TTL is the channel's archive threshold in days, replacing the configured and policy thresholds
+	TTL int `json:"ttl_days,omitempty"`
+	// Owner is the user, or the user group starting with S, notified about the channel instead of its creator
+	Owner string `json:"owner,omitempty"`
+	// ExemptUntil is the date the channel is exempt until, as yyyy-mm-dd
+	ExemptUntil string `json:"exempt_until,omitempty"`
+}
+
+// set will set the tags of key=value arguments, an empty value removing the tag
+func (t *channelTags) set(args []string) error {
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("tags must look like key=value, got %q", arg)
+		}
+
+		switch strings.ToLower(key) {
+		case "ttl":
+			if value == "" {
+				t.TTL = 0
+				continue
+			}
+			days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+			if err != nil || days < 1 {
+				return fmt.Errorf("ttl must be a number of days, got %q", value)
+			}
+			t.TTL = days
+		case "owner":
+			if value == "" {
+				t.Owner = ""
+				continue
+			}
+			match := tagOwnerPattern.FindStringSubmatch(value)
+			if match == nil {
+				return fmt.Errorf("owner must mention a user or user group, got %q", value)
+			}
+			t.Owner = match[1] + match[2]
+		case "exempt-until":
+			if value != "" {
+				if _, err := time.Parse(time.DateOnly, value); err != nil {
+					return fmt.Errorf("exempt-until must be a date like 2025-06-30, got %q", value)
+				}
+			}
+			t.ExemptUntil = value
+		default:
+			return fmt.Errorf("unknown tag %q, tags are ttl, owner and exempt-until", key)
+		}
+	}
+
+	return nil
+}
+
+// exemption will return the exemption of a channel tagged exempt until a date after now, or nil if it has none
+func (t *channelTags) exemption(c slack.Channel, now time.Time) *exemption {
+	if t == nil || t.ExemptUntil == "" {
+		return nil
+	}
+
+	until, err := time.Parse(time.DateOnly, t.ExemptUntil)
+	if err != nil {
+		return nil
+	}
+	e := &exemption{ChannelID: c.ID, ChannelName: c.Name, Until: until, Reason: "tagged exempt", ExemptedBy: tagsExemptedBy}
+	if !e.activeAt(now) {
+		return nil
+	}
+
+	return e
+}
+
+// String will describe the tags, such as "ttl=30 owner=<@U0123456789>"
+func (t *channelTags) String() string {
+	parts := []string{}
+	if t.TTL > 0 {
+		parts = append(parts, fmt.Sprintf("ttl=%d", t.TTL))
+	}
+	if t.Owner != "" {
+		if strings.HasPrefix(t.Owner, "S") {
+			parts = append(parts, fmt.Sprintf("owner=<!subteam^%s>", t.Owner))
+		} else {
+			parts = append(parts, fmt.Sprintf("owner=<@%s>", t.Owner))
+		}
+	}
+	if t.ExemptUntil != "" {
+		parts = append(parts, "exempt-until="+t.ExemptUntil)
+	}
+	if len(parts) == 0 {
+		return "no tags"
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// parseTopicTags will read the tags of an "archiver: key=value ..." part of a channel topic, nil if it has none
+func parseTopicTags(topic string) (*channelTags, error) {
+	match := topicTagsPattern.FindStringSubmatch(topic)
+	if match == nil {
+		return nil, nil
+	}
+
+	t := &channelTags{}
+	if err := t.set(strings.Fields(match[1])); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// findTagsMessage will return the newest pinned message holding a channel's tags and its tags, or nil if none is
+// pinned. Pins do not always include message metadata, so pinned bot messages without it are read again with it.
+func findTagsMessage(ctx context.Context, client *slack.Client, channelID string) (*slack.Message, *channelTags, error) {
+	items, _, err := client.ListPinsContext(ctx, channelID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var newest *slack.Message
+	for _, item := range items {
+		m := item.Message
+		if m == nil || m.BotID == "" {
+			continue
+		}
+
+		if m.Metadata.EventType == "" {
+			history, err := client.GetConversationHistoryContext(ctx, &slack.GetConversationHistoryParameters{
+				ChannelID:          channelID,
+				Latest:             m.Timestamp,
+				Inclusive:          true,
+				Limit:              1,
+				IncludeAllMetadata: true,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(history.Messages) == 0 {
+				continue
+			}
+			m = &history.Messages[0]
+		}
+
+		if m.Metadata.EventType == tagsEventType && (newest == nil || m.Timestamp > newest.Timestamp) {
+			newest = m
+		}
+	}
+	if newest == nil {
+		return nil, nil, nil
+	}
+
+	payload, err := json.Marshal(newest.Metadata.EventPayload)
+	if err != nil {
+		return nil, nil, err
+	}
+	t := &channelTags{}
+	if err := json.Unmarshal(payload, t); err != nil {
+		return nil, nil, fmt.Errorf("can not decode channel tags: %w", err)
+	}
+
+	return newest, t, nil
+}
+
+// tagsUnavailable will report whether a channel's pins can not be read, so its tags are read from its topic instead
+func tagsUnavailable(err error) bool {
+	var slackErr slack.SlackErrorResponse
+	if !errors.As(err, &slackErr) {
+		return false
+	}
+
+	switch slackErr.Err {
+	case "not_in_channel", "channel_not_found", "missing_scope", "not_allowed_token_type":
+		return true
+	}
+	return false
+}
+
+// channelTags will return the tags of a channel, from its pinned tags message or otherwise its topic, or nil if tags
+// are disabled or it has none. Topic tags that can not be parsed are logged and ignored, so one typo does not fail
+// the channel.
+func (a *ArchiveSlacker) channelTags(ctx context.Context, c slack.Channel) (*channelTags, error) {
+	if !a.tagsEnabled {
+		return nil, nil
+	}
+	if t, ok := a.tags[c.ID]; ok {
+		return t, nil
+	}
+
+	_, t, err := findTagsMessage(ctx, a.client, c.ID)
+	if err != nil && !tagsUnavailable(err) {
+		return nil, err
+	}
+	if t == nil {
+		t, err = parseTopicTags(c.Topic.Value)
+		if err != nil {
+			a.logger.Error(err, "ignoring channel topic tags", "channel", c.Name, "topic", c.Topic.Value)
+		}
+	}
+
+	a.tags[c.ID] = t
+	return t, nil
+}
+
+func (d *daemon) handleTagCommand(ctx context.Context, e *socketEvent) {
+	text, err := d.tagChannel(ctx, e.command.ChannelID, e.command.Text)
+	if err != nil {
+		d.logger.Error(err, "failed to tag channel", "channel", e.command.ChannelID, "user", e.userID)
+		text = "Something went wrong tagging this channel, please try again."
+	}
+
+	e.ack(map[string]any{
+		"response_type": slack.ResponseTypeEphemeral,
+		"text":          text,
+	})
+}
+
+// tagChannel will set the tags of key=value arguments on a channel's pinned tags message, pinning one if the
+// channel has none, and describe the channel's tags. Without arguments the tags are only described.
+func (d *daemon) tagChannel(ctx context.Context, channelID, args string) (string, error) {
+	message, tags, err := findTagsMessage(ctx, d.api, channelID)
+	if tagsUnavailable(err) {
+		return "auto-archiver can not read the pins of this channel, add it to the channel to tag it.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if tags == nil {
+		tags = &channelTags{}
+	}
+
+	if strings.TrimSpace(args) == "" {
+		if message == nil {
+			return "This channel has no tags. Set them with `" + tagCommand + " ttl=30 owner=@someone exempt-until=2025-06-30`.", nil
+		}
+		return "This channel's tags are " + tags.String() + ".", nil
+	}
+
+	if err := tags.set(strings.Fields(args)); err != nil {
+		return err.Error() + ".", nil
+	}
+
+	payload := map[string]any{}
+	raw, err := json.Marshal(tags)
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return "", err
+	}
+	options := []slack.MsgOption{
+		slack.MsgOptionText(fmt.Sprintf("auto-archiver tags for this channel: %s. Change them with `%s`.", tags, tagCommand), false),
+		slack.MsgOptionMetadata(slack.SlackMetadata{EventType: tagsEventType, EventPayload: payload}),
+	}
+
+	if message != nil {
+		if _, _, _, err := d.api.UpdateMessageContext(ctx, channelID, message.Timestamp, options...); err != nil {
+			return "", err
+		}
+	} else {
+		_, ts, err := d.api.PostMessageContext(ctx, channelID, options...)
+		if err != nil {
+			return "", err
+		}
+		if err := d.api.AddPinContext(ctx, channelID, slack.NewRefToMessage(channelID, ts)); err != nil {
+			return "", err
+		}
+	}
+
+	return "Tagged this channel with " + tags.String() + ".", nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// triggerPath is where runs are triggered over HTTP
+const triggerPath = "/trigger"
+
+// serveTrigger will accept authenticated requests to start a run until ctx is done, so external schedulers,
+// ChatOps and CI can start runs on demand
+func (d *daemon) serveTrigger(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(triggerPath, func(w http.ResponseWriter, r *http.Request) {
+		d.handleTrigger(ctx, w, r)
+	})
+
+	server := &http.Server{
+		Addr:              d.cfg.triggerAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	d.logger.Info("accepting run triggers", "addr", d.cfg.triggerAddr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// handleTrigger will start a run in the background, unless one is already running. The dry_run parameter
+// makes it a dry run and channel parameters, by ID or name, limit it to those channels.
+func (d *daemon) handleTrigger(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeTriggerResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+d.cfg.triggerToken)) != 1 {
+		writeTriggerResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeTriggerResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Each triggered run gets its own copy of the config, so its parameters do not leak into scheduled runs
+	cfg := *d.cfg
+	if v := r.Form.Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			writeTriggerResponse(w, http.StatusBadRequest, "can not parse dry_run into a bool")
+			return
+		}
+		cfg.dryRun = cfg.dryRun || dryRun
+	}
+	if channels := r.Form["channel"]; len(channels) > 0 {
+		cfg.channelFilter = map[string]bool{}
+		for _, c := range channels {
+			cfg.channelFilter[c] = true
+		}
+	}
+
+	if !d.running.TryLock() {
+		writeTriggerResponse(w, http.StatusConflict, "a run is already in progress")
+		return
+	}
+
+	d.logger.Info("run triggered", "dry_run", cfg.dryRun, "channels", r.Form["channel"])
+	go func() {
+		defer d.running.Unlock()
+		d.runOnce(ctx, &cfg)
+	}()
+
+	writeTriggerResponse(w, http.StatusAccepted, "run started")
+}
+
+// writeTriggerResponse will reply to a trigger request with a JSON status
+func writeTriggerResponse(w http.ResponseWriter, code int, status string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// unarchiveExemptedBy is who exemptions of channels unarchived after auto-archiver archived them are recorded as
+// exempted by
+const unarchiveExemptedBy = "unarchive"
+
+// exemptUnarchivedChannels will exempt the channels that were unarchived after auto-archiver archived them for
+// days, since someone evidently still needs them. The archive record of each is removed, so it is only exempted once.
+func (a *ArchiveSlacker) exemptUnarchivedChannels(ctx context.Context, channels []slack.Channel, days int, now time.Time) error {
+	values, err := a.store.List(ctx, bucketArchived)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range channels {
+		value, ok := values[c.ID]
+		if !ok {
+			continue
+		}
+		var archived archivedChannel
+		if err := json.Unmarshal(value, &archived); err != nil {
+			return fmt.Errorf("can not decode %s/%s: %w", bucketArchived, c.ID, err)
+		}
+
+		logger := a.logger.WithValues("channel", c.Name)
+		until := now.AddDate(0, 0, days)
+		existing, err := getExemption(ctx, a.store, c.ID)
+		if err != nil {
+			return err
+		}
+		// A longer exemption someone already made is kept
+		if existing != nil && existing.activeAt(now) && (existing.Until.IsZero() || existing.Until.After(until)) {
+			logger.Info("channel was unarchived after it was archived and is already exempt", "archived_at", archived.ArchivedAt)
+		} else {
+			logger.Info("channel was unarchived after it was archived, exempting it", "archived_at", archived.ArchivedAt, "until", until)
+			err := putExemption(ctx, a.store, exemption{
+				ChannelID:   c.ID,
+				ChannelName: c.Name,
+				Until:       until,
+				Reason:      fmt.Sprintf("the channel was unarchived after auto-archiver archived it on %s", archived.ArchivedAt.Format(archiveDateLayout)),
+				ExemptedBy:  unarchiveExemptedBy,
+				CreatedAt:   now,
+			})
+			if err != nil {
+				return fmt.Errorf("can not exempt unarchived channel %s: %w", c.Name, err)
+			}
+		}
+
+		if err := a.store.Delete(ctx, bucketArchived, c.ID); err != nil {
+			return fmt.Errorf("can not remove archive record of %s: %w", c.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// bucketWarnings holds when each channel was last warned, by channel ID
+const bucketWarnings = "warnings"
+
+// Who warnings mention
+const (
+	mentionNone    = "none"
+	mentionHere    = "here"
+	mentionChannel = "channel"
+)
+
+// What is done with the warnings posted in a channel that became active again
+const (
+	staleWarningsKeep   = "keep"
+	staleWarningsDelete = "delete"
+	staleWarningsUpdate = "update"
+)
+
+// warnedChannel is a channel auto-archiver warned, with the last activity its whole history was read for then
+type warnedChannel struct {
+	ChannelID    string    `json:"channel_id"`
+	ChannelName  string    `json:"channel_name"`
+	LastActivity time.Time `json:"last_activity"`
+	WarnedAt     time.Time `json:"warned_at"`
+	// MessageTS are the timestamps of the warnings posted in the channel, oldest first
+	MessageTS []string `json:"message_ts,omitempty"`
+}
+
+// recordWarning will remember when a channel was warned, its last activity then and the warning posted, ts, so the
+// next run only reads the history posted since and the warnings can be cleaned up if the channel survives. ts is
+// empty when members were nudged instead of the channel being warned. It is kept until a grace period after the
+// channel is archived, as long as the history read then and the warnings posted can matter.
+func (a *ArchiveSlacker) recordWarning(ctx context.Context, c inactiveChannel, now time.Time, ts string) error {
+	var previous warnedChannel
+	if err := getJSON(ctx, a.store, bucketWarnings, c.channel.ID, &previous); err != nil && !errors.Is(err, errNotFound) {
+		return err
+	}
+	messageTS := previous.MessageTS
+	if ts != "" && (len(messageTS) == 0 || messageTS[len(messageTS)-1] != ts) {
+		messageTS = append(messageTS, ts)
+	}
+
+	return putJSON(ctx, a.store, bucketWarnings, c.channel.ID, warnedChannel{
+		ChannelID:    c.channel.ID,
+		ChannelName:  c.channel.Name,
+		LastActivity: c.lastActivity,
+		WarnedAt:     now,
+		MessageTS:    messageTS,
+	}, max(c.archiveDate.Sub(now), 0)+stateGracePeriod)
+}
+
+// getWarnedChannel will return when a channel was last warned if the history read then is still within the window
+// from windowStart, or nil if its history has to be read in full. Reactions can be added to old messages at any
+// time, so channels are read in full when reactions count as activity, as they are for bounded windows.
+func (a *ArchiveSlacker) getWarnedChannel(ctx context.Context, c slack.Channel, windowStart time.Time) (*warnedChannel, error) {
+	if a.store == nil || a.reactionWeight > 0 || !a.since.IsZero() || !a.until.IsZero() {
+		return nil, nil
+	}
+
+	var w warnedChannel
+	if err := getJSON(ctx, a.store, bucketWarnings, c.ID, &w); err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if w.LastActivity.IsZero() || w.WarnedAt.Before(windowStart) {
+		return nil, nil
+	}
+
+	return &w, nil
+}
+
+// updateWarning will replace the text of the last warning posted in a channel for countdown warnings, returning its
+// timestamp, or an empty timestamp if a new warning has to be posted because there is none or it was deleted
+func (a *ArchiveSlacker) updateWarning(ctx context.Context, c slack.Channel, text string) (string, error) {
+	if !a.countdownWarnings || a.store == nil {
+		return "", nil
+	}
+
+	var w warnedChannel
+	if err := getJSON(ctx, a.store, bucketWarnings, c.ID, &w); err != nil {
+		if errors.Is(err, errNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	if len(w.MessageTS) == 0 {
+		return "", nil
+	}
+
+	ts := w.MessageTS[len(w.MessageTS)-1]
+	if _, _, _, err := a.client.UpdateMessageContext(ctx, c.ID, ts, slack.MsgOptionText(text, false)); err != nil {
+		var slackErr slack.SlackErrorResponse
+		if errors.As(err, &slackErr) && slackErr.Err == "message_not_found" {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return ts, nil
+}
+
+// cleanUpWarnings will delete the warnings posted in a channel that survived, or update them to say it is no longer
+// being archived, so the channel is not left with stale notices. The channel's warning record is removed once every
+// warning was cleaned up, so its history is read in full again.
+func (a *ArchiveSlacker) cleanUpWarnings(ctx context.Context, c slack.Channel, e channelEvaluation) error {
+	if a.store == nil || a.dryRun || a.staleWarnings == staleWarningsKeep {
+		return nil
+	}
+
+	var w warnedChannel
+	if err := getJSON(ctx, a.store, bucketWarnings, c.ID, &w); err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil
+		}
+		return err
+	}
+	if len(w.MessageTS) == 0 {
+		return nil
+	}
+
+	text, err := render(a.templates.warningResolved, a.templates.channelData(c, e.daysInactive, e.threshold, e.archiveDate))
+	if err != nil {
+		return err
+	}
+	for _, ts := range w.MessageTS {
+		if a.staleWarnings == staleWarningsDelete {
+			_, _, err = a.client.DeleteMessageContext(ctx, c.ID, ts)
+		} else {
+			_, _, _, err = a.client.UpdateMessageContext(ctx, c.ID, ts, slack.MsgOptionText(text, false))
+		}
+		// Warnings someone already deleted are as good as cleaned up
+		var slackErr slack.SlackErrorResponse
+		if err != nil && !(errors.As(err, &slackErr) && slackErr.Err == "message_not_found") {
+			return fmt.Errorf("could not clean up warning %s: %w", ts, err)
+		}
+	}
+
+	a.logger.V(1).Info("cleaned up warnings of channel that survived", "channel", c.Name, "warnings", len(w.MessageTS),
+		"action", a.staleWarnings)
+	return a.store.Delete(ctx, bucketWarnings, c.ID)
+}
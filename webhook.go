@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Decisions a decision webhook can return for an archive candidate
+const (
+	webhookAllow = "allow"
+	// webhookDeny exempts the channel, so it is not asked about again while the exemption lasts
+	webhookDeny = "deny"
+	// webhookDefer skips the channel this run so it is asked about again on the next run
+	webhookDefer = "defer"
+)
+
+// webhookExemptedBy is who channels the decision webhook denies archiving are recorded as exempted by
+const webhookExemptedBy = "decision-webhook"
+
+// decisionWebhook asks an external HTTP endpoint whether each archive candidate may be archived,
+// so organizations can plug in their own logic without forking auto-archiver
+type decisionWebhook struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+func newDecisionWebhook(url, token string, timeout time.Duration) *decisionWebhook {
+	return &decisionWebhook{
+		client: &http.Client{Timeout: timeout},
+		url:    url,
+		token:  token,
+	}
+}
+
+// webhookRequest is the body POSTed to the decision webhook for each candidate
+type webhookRequest struct {
+	Channel      slack.Channel `json:"channel"`
+	Reason       archiveReason `json:"reason"`
+	DaysInactive int           `json:"days_inactive"`
+	Threshold    int           `json:"threshold"`
+	RunID        string        `json:"run_id"`
+}
+
+// webhookResponse is the body the decision webhook replies with
+type webhookResponse struct {
+	Decision string `json:"decision"`
+	Reason   string `json:"reason"`
+	// Until is when a denial ends, the zero time denying the channel permanently
+	Until time.Time `json:"until"`
+}
+
+// decide will POST a candidate to the webhook and return its decision
+func (w *decisionWebhook) decide(ctx context.Context, body webhookRequest) (webhookResponse, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return webhookResponse{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(data))
+	if err != nil {
+		return webhookResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return webhookResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return webhookResponse{}, fmt.Errorf("decision webhook returned %s: %s", resp.Status, msg)
+	}
+
+	var decision webhookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return webhookResponse{}, fmt.Errorf("can not decode decision webhook response: %w", err)
+	}
+
+	switch decision.Decision {
+	case webhookAllow, webhookDeny, webhookDefer:
+		return decision, nil
+	default:
+		return webhookResponse{}, fmt.Errorf("decision webhook returned unknown decision %q", decision.Decision)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+
+	"github.com/slack-go/slack"
+)
+
+// weighsAuthors reports whether messages count differently depending on who posted them
+func (a *ArchiveSlacker) weighsAuthors() bool {
+	return a.guestWeight != 1 || a.externalWeight != 1
+}
+
+// messageWeight will return how much a message of activity counts towards one message by its author. Messages of
+// guests and of users from other organizations in shared channels can count for less, or not at all.
+func (a *ArchiveSlacker) messageWeight(ctx context.Context, m slack.Message) (float64, error) {
+	if !a.weighsAuthors() || m.User == "" || a.activityAuthors.always[m.User] {
+		return 1, nil
+	}
+	// Backtests only know the users in the export
+	if _, ok := a.users[m.User]; !ok && a.client == nil {
+		return 1, nil
+	}
+
+	user, err := a.getUser(ctx, m.User)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case a.isExternal(user):
+		return a.externalWeight, nil
+	case user.IsRestricted || user.IsUltraRestricted:
+		return a.guestWeight, nil
+	default:
+		return 1, nil
+	}
+}
+
+// isExternal will report whether a user belongs to another organization. Users of other workspaces of the same
+// Enterprise Grid organization are not external.
+func (a *ArchiveSlacker) isExternal(user *slack.User) bool {
+	if user.IsStranger {
+		return true
+	}
+	if a.workspace == nil || user.TeamID == "" || user.TeamID == a.workspace.TeamID {
+		return false
+	}
+
+	return a.workspace.EnterpriseID == "" || user.Enterprise.EnterpriseID != a.workspace.EnterpriseID
+}
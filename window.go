@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// archiveWindow is the time of day channels may be archived in, such as 01:00-05:00, so archiving never happens
+// during business hours
+type archiveWindow struct {
+	// start and end are the times since midnight the window starts and ends at, end being before start when the
+	// window spans midnight
+	start    time.Duration
+	end      time.Duration
+	location *time.Location
+}
+
+// parseArchiveWindow will parse a window like 01:00-05:00 in location
+func parseArchiveWindow(s string, location *time.Location) (*archiveWindow, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("archive window must look like 01:00-05:00, got %q", s)
+	}
+
+	start, err := parseTimeOfDay(strings.TrimSpace(from))
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTimeOfDay(strings.TrimSpace(to))
+	if err != nil {
+		return nil, err
+	}
+	if start == end {
+		return nil, fmt.Errorf("archive window %q is empty", s)
+	}
+
+	return &archiveWindow{start: start, end: end, location: location}, nil
+}
+
+// parseTimeOfDay will parse a time of day like 01:00 into the time since midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("can not parse %q as a time of day like 01:00", s)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// contains will report whether t is within the window, which a nil window always is
+func (w *archiveWindow) contains(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+
+	t = t.In(w.location)
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.start < w.end {
+		return sinceMidnight >= w.start && sinceMidnight < w.end
+	}
+
+	return sinceMidnight >= w.start || sinceMidnight < w.end
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/go-logr/logr"
+	"github.com/slack-go/slack"
+)
+
+// runWorkspaces will run in each workspace of an org-wide install in turn, each with the org's policy and the
+// overrides of the workspace. A failed workspace does not stop the others.
+func runWorkspaces(ctx context.Context, logger logr.Logger, cfg *config, policy *channelPolicy, shards []botShard,
+	auths []*slack.AuthTestResponse, botUsers map[string]bool, store Store, result *runResult) error {
+	var errs []error
+	for _, teamID := range cfg.workspaces {
+		logger := logger.WithValues("workspace", teamID)
+
+		applied := *cfg
+		applied.teamID = teamID
+		workspaceCfg := &applied
+		if policy != nil {
+			var err error
+			if workspaceCfg, err = policy.forWorkspace(teamID).apply(workspaceCfg); err != nil {
+				errs = append(errs, fmt.Errorf("can not apply policy from %s to workspace %s: %w", policy.commit, teamID, err))
+				continue
+			}
+		}
+
+		logger.Info("running in workspace", "archive_threshold", workspaceCfg.archiveThreshold)
+		result.teamID = teamID
+		if err := runWorkspace(ctx, logger, workspaceCfg, shards, auths, botUsers, store, result); err != nil {
+			logger.Error(err, "run in workspace failed")
+			errs = append(errs, fmt.Errorf("workspace %s: %w", teamID, err))
+		}
+	}
+	result.teamID = ""
+
+	if cfg.orgAdminChannel != "" {
+		rollup := newOrgRollup(result, cfg.workspaces)
+		logger.Info("org rollup", "archived", rollup.Total.Archived, "debt", rollup.Total.Debt,
+			"reclaimed_percent", rollup.Total.reclaimedPercent())
+		// Dry runs tell nobody what they would have done
+		if !cfg.dryRun {
+			if err := postOrgRollup(ctx, shards[0].client, cfg.orgAdminChannel, rollup); err != nil {
+				logger.Error(err, "failed to post org rollup", "channel", cfg.orgAdminChannel)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// forWorkspace will return the policy of a workspace, the org's policy with the workspace's overrides layered on
+// top. Settings the workspace sets replace the org's, and its rules and exemptions come before the org's so they
+// win when both match a channel equally.
+func (p *channelPolicy) forWorkspace(teamID string) *channelPolicy {
+	w := p.Workspaces[teamID]
+	if w == nil {
+		return p
+	}
+
+	merged := *p
+	merged.Workspaces = nil
+	if w.ArchiveThreshold != nil {
+		merged.ArchiveThreshold = w.ArchiveThreshold
+	}
+	if w.WarningDays != nil {
+		merged.WarningDays = w.WarningDays
+	}
+	if w.MinActivityMessages != nil {
+		merged.MinActivityMessages = w.MinActivityMessages
+	}
+	if w.ArchivePrivate != nil {
+		merged.ArchivePrivate = w.ArchivePrivate
+	}
+	if w.AdminChannel != "" {
+		merged.AdminChannel = w.AdminChannel
+	}
+	merged.ActivityUsers = append(slices.Clip(p.ActivityUsers), w.ActivityUsers...)
+	merged.IgnoredUsers = append(slices.Clip(p.IgnoredUsers), w.IgnoredUsers...)
+	merged.ExemptGroups = append(slices.Clip(p.ExemptGroups), w.ExemptGroups...)
+	merged.Rules = append(slices.Clip(w.Rules), p.Rules...)
+	merged.Exemptions = append(slices.Clip(w.Exemptions), p.Exemptions...)
+
+	return &merged
+}